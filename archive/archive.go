@@ -0,0 +1,216 @@
+// Package archive streams objects as a ZIP or tar.gz archive straight from the backend
+// to a writer, one object at a time, without buffering them on disk.
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"kluisz-object-storage/config"
+	"kluisz-object-storage/models"
+	"kluisz-object-storage/storage"
+)
+
+// Formats
+const (
+	Zip   = "zip"
+	TarGz = "tar.gz"
+)
+
+// ManifestName -- the last entry of every archive, listing what failed
+const ManifestName = "_manifest.json"
+
+// Format -- the canonical name of format, "" for zip; ok is false for unknown formats
+func Format(format string) (string, bool) {
+	switch strings.ToLower(format) {
+	case "", "zip":
+		return Zip, true
+	case "tar.gz", "tgz":
+		return TarGz, true
+	}
+	return "", false
+}
+
+// ContentType -- the media type of archives in format
+func ContentType(format string) string {
+	if format == TarGz {
+		return "application/gzip"
+	}
+	return "application/zip"
+}
+
+// Entry -- an object and its path in the archive
+type Entry struct {
+	Key  string
+	Name string
+}
+
+// Name -- the archive path of key: without strip, cleaned so it cannot point outside
+// the directory it is extracted into; "" for folder markers, which are left out
+func Name(key, strip string) string {
+	if strings.HasSuffix(key, "/") {
+		return ""
+	}
+	name := path.Clean("/" + strings.TrimPrefix(key, strip))
+	return strings.TrimPrefix(name, "/")
+}
+
+// writer -- one archive format
+type writer interface {
+	add(name string, size int64, modTime time.Time, r io.Reader) (int64, error)
+	close() error
+}
+
+// Write -- writes the entries of bucket to w as an archive in format, followed by the
+// manifest. Objects that cannot be read, or would take the archive past maxBytes, are
+// left out and listed in the manifest; an object failing halfway is padded or cut
+// short and listed too. The error is that of w, e.g. the client went away.
+func Write(ctx context.Context, w io.Writer, format, bucket, prefix string, entries []Entry, maxBytes int64) (models.ArchiveManifest, error) {
+	var aw writer
+	if format == TarGz {
+		gz := gzip.NewWriter(w)
+		aw = &tarWriter{gz: gz, tw: tar.NewWriter(gz)}
+	} else {
+		aw = &zipWriter{zw: zip.NewWriter(w)}
+	}
+	manifest := models.ArchiveManifest{Bucket: bucket, Prefix: prefix, Failed: []models.ArchiveFailure{}}
+	fail := func(key string, err error) {
+		manifest.Failed = append(manifest.Failed, models.ArchiveFailure{Key: key, Error: err.Error()})
+	}
+
+	for _, e := range entries {
+		if err := ctx.Err(); err != nil {
+			return manifest, err
+		}
+		written, skipped, err := add(ctx, aw, bucket, e, maxBytes-manifest.Bytes)
+		manifest.Bytes += written
+		if skipped != nil {
+			fail(e.Key, skipped)
+			continue
+		}
+		if err != nil {
+			return manifest, err
+		}
+		manifest.Files++
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return manifest, err
+	}
+	if _, err := aw.add(ManifestName, int64(len(data)), time.Now(), strings.NewReader(string(data))); err != nil {
+		return manifest, err
+	}
+	return manifest, aw.close()
+}
+
+// add -- one object; skipped is why it is missing or incomplete, err a write error
+func add(ctx context.Context, aw writer, bucket string, e Entry, room int64) (written int64, skipped, err error) {
+	ctx, cancel := context.WithTimeout(ctx, config.Get().Timeouts.Get)
+	defer cancel()
+	r, info, err := storage.Read(ctx, bucket, e.Key, minio.GetObjectOptions{})
+	if err != nil {
+		return 0, err, nil
+	}
+	defer r.Close()
+	if info.Size > room {
+		return 0, errors.New("left out, the archive would exceed archive.maxBytes"), nil
+	}
+	written, err = aw.add(e.Name, info.Size, info.LastModified, r)
+	if err == nil && written < info.Size {
+		return written, fmt.Errorf("incomplete, %d of %d bytes", written, info.Size), nil
+	}
+	var rerr readError
+	if errors.As(err, &rerr) {
+		return written, fmt.Errorf("incomplete, %d of %d bytes: %w", written, info.Size, rerr.err), nil
+	}
+	return written, nil, err
+}
+
+// readError -- reading the object failed, as opposed to writing the archive
+type readError struct{ err error }
+
+func (e readError) Error() string { return e.err.Error() }
+
+// copyEntry -- copies r to w, telling read from write errors apart
+func copyEntry(w io.Writer, r io.Reader) (int64, error) {
+	var n int64
+	buf := make([]byte, 32<<10)
+	for {
+		nr, rerr := r.Read(buf)
+		if nr > 0 {
+			nw, werr := w.Write(buf[:nr])
+			n += int64(nw)
+			if werr != nil {
+				return n, werr
+			}
+		}
+		if rerr == io.EOF {
+			return n, nil
+		}
+		if rerr != nil {
+			return n, readError{rerr}
+		}
+	}
+}
+
+type zipWriter struct {
+	zw *zip.Writer
+}
+
+func (z *zipWriter) add(name string, size int64, modTime time.Time, r io.Reader) (int64, error) {
+	w, err := z.zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modTime})
+	if err != nil {
+		return 0, err
+	}
+	return copyEntry(w, r)
+}
+
+func (z *zipWriter) close() error {
+	return z.zw.Close()
+}
+
+type tarWriter struct {
+	gz *gzip.Writer
+	tw *tar.Writer
+}
+
+// add -- tar headers carry the size, so an object ending early is padded with zeros
+// to keep the archive readable
+func (t *tarWriter) add(name string, size int64, modTime time.Time, r io.Reader) (int64, error) {
+	hdr := &tar.Header{Name: name, Mode: 0o644, Size: size, ModTime: modTime, Typeflag: tar.TypeReg, Format: tar.FormatPAX}
+	if err := t.tw.WriteHeader(hdr); err != nil {
+		return 0, err
+	}
+	n, err := copyEntry(t.tw, io.LimitReader(r, size))
+	if errors.As(err, new(readError)) || (err == nil && n < size) {
+		if _, perr := io.CopyN(t.tw, zeros{}, size-n); perr != nil {
+			return n, perr
+		}
+	}
+	return n, err
+}
+
+func (t *tarWriter) close() error {
+	if err := t.tw.Close(); err != nil {
+		return err
+	}
+	return t.gz.Close()
+}
+
+type zeros struct{}
+
+func (zeros) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
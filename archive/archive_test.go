@@ -0,0 +1,111 @@
+package archive
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+func TestName(t *testing.T) {
+	tests := []struct {
+		key, strip string
+		want       string
+	}{
+		{"photos/2024/a.jpg", "", "photos/2024/a.jpg"},
+		{"photos/2024/a.jpg", "photos/", "2024/a.jpg"},
+		{"photos/2024/", "", ""},
+		{"../../etc/passwd", "", "etc/passwd"},
+		{"photos/../../../x", "photos/", "x"},
+		{"/abs/key", "", "abs/key"},
+		{"a//b/./c", "", "a/b/c"},
+		{"other/a.jpg", "photos/", "other/a.jpg"},
+	}
+	for _, tt := range tests {
+		if got := Name(tt.key, tt.strip); got != tt.want {
+			t.Errorf("Name(%q, %q) = %q, want %q", tt.key, tt.strip, got, tt.want)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	for in, want := range map[string]string{"": Zip, "ZIP": Zip, "tgz": TarGz, "tar.gz": TarGz} {
+		if got, ok := Format(in); !ok || got != want {
+			t.Errorf("Format(%q) = %q, %v; want %q", in, got, ok, want)
+		}
+	}
+	if _, ok := Format("rar"); ok {
+		t.Error("rar accepted")
+	}
+}
+
+// shortReader -- data, then err instead of io.EOF
+func shortReader(data string, err error) io.Reader {
+	return io.MultiReader(strings.NewReader(data), iotest.ErrReader(err))
+}
+
+func TestTarPadding(t *testing.T) {
+	readErr := errors.New("connection reset")
+	tests := []struct {
+		name    string
+		size    int64
+		r       io.Reader
+		written int64
+		content string
+		readErr bool
+	}{
+		{"complete", 5, strings.NewReader("hello"), 5, "hello", false},
+		{"ends early", 5, strings.NewReader("he"), 2, "he\x00\x00\x00", false},
+		{"read fails", 5, shortReader("hel", readErr), 3, "hel\x00\x00", true},
+		{"longer than its size", 5, strings.NewReader("hello world"), 5, "hello", false},
+		{"empty", 0, strings.NewReader(""), 0, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			gz := gzip.NewWriter(&buf)
+			tw := &tarWriter{gz: gz, tw: tar.NewWriter(gz)}
+			n, err := tw.add("obj", tt.size, time.Now(), tt.r)
+			if n != tt.written {
+				t.Errorf("wrote %d bytes, want %d", n, tt.written)
+			}
+			if got := errors.As(err, new(readError)); got != tt.readErr || (err != nil && !got) {
+				t.Fatalf("error %v, want a read error %v", err, tt.readErr)
+			}
+			// the next entry must still be readable
+			if _, err := tw.add("next", 4, time.Now(), strings.NewReader("next")); err != nil {
+				t.Fatal(err)
+			}
+			if err := tw.close(); err != nil {
+				t.Fatal(err)
+			}
+
+			zr, err := gzip.NewReader(&buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tr := tar.NewReader(zr)
+			for _, want := range []struct{ name, content string }{{"obj", tt.content}, {"next", "next"}} {
+				hdr, err := tr.Next()
+				if err != nil {
+					t.Fatal(err)
+				}
+				data, err := io.ReadAll(tr)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if hdr.Name != want.name || string(data) != want.content {
+					t.Errorf("entry %s: %q, want %s: %q", hdr.Name, data, want.name, want.content)
+				}
+			}
+			if _, err := tr.Next(); err != io.EOF {
+				t.Errorf("after the entries: %v, want io.EOF", err)
+			}
+		})
+	}
+}
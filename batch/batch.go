@@ -0,0 +1,404 @@
+// Package batch runs one operation -- copy, delete, tag, metadata or restore -- on every
+// object listed in a manifest, S3 Batch Operations style, as a background job that
+// leaves a CSV report of each object's outcome in a bucket.
+package batch
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/tags"
+	"kluisz-object-storage/config"
+	"kluisz-object-storage/envelope"
+	"kluisz-object-storage/events"
+	"kluisz-object-storage/jobs"
+	"kluisz-object-storage/models"
+	"kluisz-object-storage/storage"
+)
+
+// Operations
+const (
+	Copy     = "copy"
+	Delete   = "delete"
+	Tag      = "tag"
+	Metadata = "metadata"
+	Restore  = "restore"
+)
+
+// RegisterJobs -- makes the "batch" job available, with a models.BatchRequest as
+// parameters and a models.BatchResult as result
+func RegisterJobs() {
+	jobs.Register("batch", jobs.Kind{
+		Check: func(params json.RawMessage) error {
+			var req models.BatchRequest
+			if err := json.Unmarshal(params, &req); err != nil {
+				return err
+			}
+			return Check(req)
+		},
+		Run: run,
+	})
+}
+
+// Check -- the request is complete for its operation
+func Check(req models.BatchRequest) error {
+	m, op := req.Manifest, req.Operation
+	if m.Bucket == "" || m.Key == "" {
+		return errors.New("manifest bucket and key are required")
+	}
+	if _, err := format(m); err != nil {
+		return err
+	}
+	if req.Report.Bucket == "" {
+		return errors.New("report bucket is required")
+	}
+	if max := config.Get().Jobs.MaxConcurrency; req.Concurrency > max {
+		return fmt.Errorf("concurrency can be at most %d, jobs.maxConcurrency", max)
+	}
+	switch op.Type {
+	case Copy:
+		if op.DestinationBucket == "" {
+			return errors.New("copy needs a destinationBucket")
+		}
+	case Tag:
+		if _, err := tags.NewTags(op.Tags, true); err != nil {
+			return fmt.Errorf("tags: %w", err)
+		}
+	case Metadata:
+		for k := range op.Metadata {
+			if envelope.Reserved(k) {
+				return fmt.Errorf("metadata %q is reserved for envelope encryption", k)
+			}
+		}
+	case Delete, Restore:
+	default:
+		return fmt.Errorf("operation %q must be copy, delete, tag, metadata or restore", op.Type)
+	}
+	return nil
+}
+
+func format(m models.BatchManifest) (string, error) {
+	switch f := m.Format; f {
+	case "csv", "jsonl":
+		return f, nil
+	case "":
+		switch strings.ToLower(path.Ext(m.Key)) {
+		case ".jsonl", ".json", ".ndjson":
+			return "jsonl", nil
+		}
+		return "csv", nil
+	default:
+		return "", fmt.Errorf("manifest format %q must be csv or jsonl", f)
+	}
+}
+
+// item -- one manifest line
+type item struct {
+	line      int
+	Bucket    string `json:"bucket"`
+	Key       string `json:"key"`
+	VersionID string `json:"versionId"`
+}
+
+func run(ctx context.Context, job models.Job, p *jobs.Progress) (any, error) {
+	var req models.BatchRequest
+	if err := json.Unmarshal(job.Params, &req); err != nil {
+		return nil, err
+	}
+	op, err := newOperation(req.Operation)
+	if err != nil {
+		return nil, err
+	}
+
+	// the report can have as many lines as the manifest, it is written to disk and
+	// uploaded at the end
+	tmp, err := os.CreateTemp("", "batch-report-*.csv")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+	report := &reporter{w: csv.NewWriter(tmp), failedOnly: req.Report.FailedOnly}
+	report.w.Write([]string{"bucket", "key", "versionId", "status", "error"})
+
+	concurrency := req.Concurrency
+	if concurrency <= 0 {
+		concurrency = config.Get().Jobs.Concurrency
+	}
+	// the limit may have been lowered since the job was submitted
+	concurrency = min(concurrency, config.Get().Jobs.MaxConcurrency)
+	items := make(chan item)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for it := range items {
+				err := op.apply(ctx, it)
+				report.add(it, err)
+				if err != nil {
+					p.Fail(it.Bucket+"/"+it.Key, err)
+				} else {
+					p.Done(0)
+				}
+			}
+		}()
+	}
+	readErr := read(ctx, req.Manifest, func(it item, err error) {
+		p.AddTotal(1)
+		if err != nil {
+			err = fmt.Errorf("manifest line %d: %w", it.line, err)
+			report.add(it, err)
+			p.Fail(fmt.Sprintf("line %d", it.line), err)
+			return
+		}
+		select {
+		case items <- it:
+		case <-ctx.Done():
+		}
+	})
+	close(items)
+	wg.Wait()
+
+	// the report is also left for cancelled and failed jobs, so the work done is known
+	result := models.BatchResult{
+		ReportBucket: req.Report.Bucket,
+		ReportKey:    req.Report.Prefix + "batch-" + job.ID + ".csv",
+		Succeeded:    report.succeeded,
+		Failed:       report.failed,
+	}
+	if err := report.upload(tmp, result.ReportBucket, result.ReportKey); err != nil {
+		result.ReportError = err.Error()
+	}
+	if readErr == nil {
+		readErr = ctx.Err()
+	}
+	return result, readErr
+}
+
+// read -- calls fn for every manifest line, see parse
+func read(ctx context.Context, m models.BatchManifest, fn func(item, error)) error {
+	f, _ := format(m)
+	r, _, err := storage.Read(ctx, m.Bucket, m.Key, minio.GetObjectOptions{})
+	if err != nil {
+		return fmt.Errorf("manifest: %w", err)
+	}
+	defer r.Close()
+	return parse(ctx, r, f, fn)
+}
+
+// parse -- calls fn for every line of a csv or jsonl manifest, with the reason for
+// lines that are not bucket,key[,versionId]; stops when ctx is done
+func parse(ctx context.Context, r io.Reader, f string, fn func(item, error)) error {
+	if f == "jsonl" {
+		lines := bufio.NewReader(r)
+		for n := 1; ctx.Err() == nil; n++ {
+			line, err := lines.ReadBytes('\n')
+			if len(bytes.TrimSpace(line)) > 0 {
+				it := item{}
+				perr := json.Unmarshal(line, &it)
+				it.line = n
+				if perr == nil && (it.Bucket == "" || it.Key == "") {
+					perr = errors.New("bucket and key are required")
+				}
+				fn(it, perr)
+			}
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("manifest: %w", err)
+			}
+		}
+		return ctx.Err()
+	}
+
+	lines := csv.NewReader(r)
+	lines.FieldsPerRecord = -1
+	lines.ReuseRecord = true
+	for n := 1; ctx.Err() == nil; n++ {
+		rec, err := lines.Read()
+		if err == io.EOF {
+			return nil
+		}
+		var perr *csv.ParseError
+		if errors.As(err, &perr) {
+			fn(item{line: n}, err)
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("manifest: %w", err)
+		}
+		// an optional header line
+		if n == 1 && strings.EqualFold(strings.TrimSpace(rec[0]), "bucket") {
+			continue
+		}
+		it := item{line: n, Bucket: strings.TrimSpace(rec[0])}
+		if len(rec) > 1 {
+			it.Key = rec[1]
+		}
+		if len(rec) > 2 {
+			it.VersionID = strings.TrimSpace(rec[2])
+		}
+		switch {
+		case len(rec) > 3:
+			fn(it, errors.New("more than bucket,key,versionId"))
+		case it.Bucket == "" || it.Key == "":
+			fn(it, errors.New("bucket and key are required"))
+		default:
+			fn(it, nil)
+		}
+	}
+	return ctx.Err()
+}
+
+type reporter struct {
+	mu         sync.Mutex
+	w          *csv.Writer
+	failedOnly bool
+	succeeded  int64
+	failed     int64
+}
+
+func (r *reporter) add(it item, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	status, reason := "succeeded", ""
+	if err != nil {
+		status, reason = "failed", err.Error()
+		r.failed++
+	} else {
+		r.succeeded++
+		if r.failedOnly {
+			return
+		}
+	}
+	r.w.Write([]string{it.Bucket, it.Key, it.VersionID, status, reason})
+}
+
+func (r *reporter) upload(f *os.File, bucket, key string) error {
+	r.w.Flush()
+	if err := r.w.Error(); err != nil {
+		return err
+	}
+	size, err := f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	// not bound to the job, a cancelled job still gets its report
+	ctx, cancel := context.WithTimeout(context.Background(), config.Get().Timeouts.Put)
+	defer cancel()
+	return storage.Upload(ctx, bucket, key, f, size, minio.PutObjectOptions{ContentType: "text/csv"})
+}
+
+// operation -- the configured operation, applied per object
+type operation struct {
+	models.BatchOperation
+	tags *tags.Tags
+}
+
+func newOperation(op models.BatchOperation) (*operation, error) {
+	o := &operation{BatchOperation: op}
+	if op.Type == Tag {
+		t, err := tags.NewTags(op.Tags, true)
+		if err != nil {
+			return nil, err
+		}
+		o.tags = t
+	}
+	return o, nil
+}
+
+func (o *operation) apply(ctx context.Context, it item) error {
+	timeouts := config.Get().Timeouts
+	timeout := timeouts.Put
+	if o.Type == Delete || o.Type == Tag {
+		timeout = timeouts.Other
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	switch o.Type {
+	case Copy:
+		key := o.DestinationPrefix + it.Key
+		if err := storage.Copy(ctx, it.Bucket, it.Key, it.VersionID, o.DestinationBucket, key); err != nil {
+			return err
+		}
+		publish(events.ObjectCreated, o.DestinationBucket, key, "")
+		return nil
+	case Restore:
+		if it.VersionID == "" {
+			return errors.New("restore needs a versionId")
+		}
+		// copying the version over the key makes it the current version again
+		if err := storage.Copy(ctx, it.Bucket, it.Key, it.VersionID, it.Bucket, it.Key); err != nil {
+			return err
+		}
+		publish(events.ObjectCreated, it.Bucket, it.Key, "")
+		return nil
+	}
+
+	client, err := storage.ClientFor(it.Bucket)
+	if err != nil {
+		return err
+	}
+	switch o.Type {
+	case Delete:
+		err = client.RemoveObject(ctx, it.Bucket, it.Key, minio.RemoveObjectOptions{VersionID: it.VersionID})
+		if err == nil {
+			publish(events.ObjectRemoved, it.Bucket, it.Key, it.VersionID)
+		}
+		return err
+	case Tag:
+		return client.PutObjectTagging(ctx, it.Bucket, it.Key, o.tags, minio.PutObjectTaggingOptions{VersionID: it.VersionID})
+	default:
+		return o.replaceMetadata(ctx, client, it)
+	}
+}
+
+// replaceMetadata -- copies the object onto itself with new metadata; the envelope
+// metadata of gateway encrypted objects is kept or they could not be read any more
+func (o *operation) replaceMetadata(ctx context.Context, client *minio.Client, it item) error {
+	info, err := client.StatObject(ctx, it.Bucket, it.Key, minio.StatObjectOptions{VersionID: it.VersionID})
+	if err != nil {
+		return err
+	}
+	meta := make(map[string]string, len(o.Metadata))
+	for k, v := range o.Metadata {
+		if !envelope.Reserved(k) {
+			meta[k] = v
+		}
+	}
+	for k, v := range envelope.Metadata(info.UserMetadata) {
+		meta[k] = v
+	}
+	contentType := o.ContentType
+	if contentType == "" {
+		contentType = info.ContentType
+	}
+	_, err = client.CopyObject(ctx,
+		minio.CopyDestOptions{Bucket: it.Bucket, Object: it.Key, ReplaceMetadata: true, UserMetadata: meta, ContentType: contentType},
+		minio.CopySrcOptions{Bucket: it.Bucket, Object: it.Key, VersionID: it.VersionID})
+	if err == nil {
+		publish(events.ObjectCreated, it.Bucket, it.Key, "")
+	}
+	return err
+}
+
+func publish(typ events.Type, bucket, key, versionID string) {
+	events.Publish(events.Event{Type: typ, Bucket: bucket, Key: key, VersionID: versionID, Source: events.SourceJob})
+}
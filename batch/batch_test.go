@@ -0,0 +1,107 @@
+package batch
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"kluisz-object-storage/models"
+)
+
+// line -- what parse reported for one manifest line
+type line struct {
+	item
+	err string
+}
+
+func parseAll(t *testing.T, format, manifest string) []line {
+	t.Helper()
+	var out []line
+	err := parse(context.Background(), strings.NewReader(manifest), format, func(it item, err error) {
+		l := line{item: it}
+		if err != nil {
+			l.err = err.Error()
+		}
+		out = append(out, l)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		format   string
+		manifest string
+		want     []line
+	}{
+		{"csv", "csv", "photos,a.jpg\nphotos, b.jpg ,v1\n",
+			[]line{
+				{item: item{line: 1, Bucket: "photos", Key: "a.jpg"}},
+				// keys are taken as they are, spaces included
+				{item: item{line: 2, Bucket: "photos", Key: " b.jpg ", VersionID: "v1"}},
+			}},
+		{"csv header", "csv", "Bucket,Key,VersionId\nphotos,a.jpg\n",
+			[]line{{item: item{line: 2, Bucket: "photos", Key: "a.jpg"}}}},
+		{"csv header only on the first line", "csv", "photos,a.jpg\nbucket,key\n",
+			[]line{
+				{item: item{line: 1, Bucket: "photos", Key: "a.jpg"}},
+				{item: item{line: 2, Bucket: "bucket", Key: "key"}},
+			}},
+		{"csv quoted key", "csv", "photos,\"2024/a,b.jpg\"\n",
+			[]line{{item: item{line: 1, Bucket: "photos", Key: "2024/a,b.jpg"}}}},
+		{"csv bad lines", "csv", "photos\nphotos,a,v1,extra\n,a.jpg\nphotos,\"open\n",
+			[]line{
+				{item: item{line: 1, Bucket: "photos"}, err: "bucket and key are required"},
+				{item: item{line: 2, Bucket: "photos", Key: "a", VersionID: "v1"}, err: "more than bucket,key,versionId"},
+				{item: item{line: 3, Key: "a.jpg"}, err: "bucket and key are required"},
+				{item: item{line: 4}, err: "extraneous or missing \" in quoted-field"},
+			}},
+		{"jsonl", "jsonl", "{\"bucket\":\"photos\",\"key\":\"a.jpg\",\"versionId\":\"v1\"}\n\n{\"bucket\":\"photos\",\"key\":\"b.jpg\"}",
+			[]line{
+				{item: item{line: 1, Bucket: "photos", Key: "a.jpg", VersionID: "v1"}},
+				{item: item{line: 3, Bucket: "photos", Key: "b.jpg"}},
+			}},
+		{"jsonl bad lines", "jsonl", "{\"bucket\":\"photos\"}\nnot json\n",
+			[]line{
+				{item: item{line: 1, Bucket: "photos"}, err: "bucket and key are required"},
+				{item: item{line: 2}, err: "invalid character 'o' in literal null (expecting 'u')"},
+			}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseAll(t, tt.format, tt.manifest)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d lines %+v, want %d", len(got), got, len(tt.want))
+			}
+			for i := range got {
+				if g, w := got[i], tt.want[i]; g.item != w.item || !strings.Contains(g.err, w.err) || (w.err == "") != (g.err == "") {
+					t.Errorf("line %d: got %+v, want %+v", i, g, w)
+				}
+			}
+		})
+	}
+}
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		format, key string
+		want        string
+		wantErr     bool
+	}{
+		{"", "manifest.csv", "csv", false},
+		{"", "manifest", "csv", false},
+		{"", "manifest.JSONL", "jsonl", false},
+		{"", "manifest.ndjson", "jsonl", false},
+		{"jsonl", "manifest.csv", "jsonl", false},
+		{"xml", "manifest.xml", "", true},
+	}
+	for _, tt := range tests {
+		got, err := format(models.BatchManifest{Bucket: "b", Key: tt.key, Format: tt.format})
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("format(%q, %q) = %q, %v", tt.format, tt.key, got, err)
+		}
+	}
+}
@@ -0,0 +1,233 @@
+// Package bulk holds the job types working on many objects at once: copying or
+// deleting everything under a prefix, and deleting a bucket with all its contents.
+package bulk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/minio/minio-go/v7"
+	"kluisz-object-storage/config"
+	"kluisz-object-storage/cors"
+	"kluisz-object-storage/events"
+	"kluisz-object-storage/jobs"
+	"kluisz-object-storage/lifecycle"
+	"kluisz-object-storage/models"
+	"kluisz-object-storage/storage"
+)
+
+// RegisterJobs -- makes the copy, delete and delete-bucket jobs available
+func RegisterJobs() {
+	jobs.Register("copy", jobs.Kind{Check: checkCopy, Run: runCopy})
+	jobs.Register("delete", jobs.Kind{Check: checkDelete, Run: runDelete})
+	jobs.Register("delete-bucket", jobs.Kind{Check: checkDeleteBucket, Run: runDeleteBucket})
+}
+
+func checkCopy(params json.RawMessage) error {
+	var p models.CopyJobParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return err
+	}
+	switch {
+	case p.SourceBucket == "" || p.DestinationBucket == "":
+		return errors.New("sourceBucket and destinationBucket are required")
+	case p.SourceBucket == p.DestinationBucket && strings.HasPrefix(p.DestinationPrefix, p.Prefix):
+		// the copies would be listed and copied again
+		return errors.New("destinationPrefix is inside the copied prefix")
+	}
+	return nil
+}
+
+func runCopy(ctx context.Context, job models.Job, p *jobs.Progress) (any, error) {
+	var params models.CopyJobParams
+	if err := json.Unmarshal(job.Params, &params); err != nil {
+		return nil, err
+	}
+	client, err := storage.ClientFor(params.SourceBucket)
+	if err != nil {
+		return nil, err
+	}
+	list := client.ListObjects(ctx, params.SourceBucket, minio.ListObjectsOptions{Prefix: params.Prefix, Recursive: true})
+	return nil, forEach(ctx, list, p, func(obj minio.ObjectInfo) {
+		key := params.DestinationPrefix + strings.TrimPrefix(obj.Key, params.Prefix)
+		ctx, cancel := context.WithTimeout(ctx, config.Get().Timeouts.Put)
+		defer cancel()
+		if err := storage.Copy(ctx, params.SourceBucket, obj.Key, "", params.DestinationBucket, key); err != nil {
+			p.Fail(obj.Key, err)
+			return
+		}
+		p.Done(obj.Size)
+		events.Publish(events.Event{Type: events.ObjectCreated, Bucket: params.DestinationBucket, Key: key, Size: obj.Size, Source: events.SourceJob})
+	})
+}
+
+func checkDelete(params json.RawMessage) error {
+	var p models.DeleteJobParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return err
+	}
+	if p.Bucket == "" {
+		return errors.New("bucket is required")
+	}
+	return nil
+}
+
+func runDelete(ctx context.Context, job models.Job, p *jobs.Progress) (any, error) {
+	var params models.DeleteJobParams
+	if err := json.Unmarshal(job.Params, &params); err != nil {
+		return nil, err
+	}
+	return nil, remove(ctx, params.Bucket, params.Prefix, false, p)
+}
+
+func checkDeleteBucket(params json.RawMessage) error {
+	var p models.DeleteBucketJobParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return err
+	}
+	if p.Bucket == "" {
+		return errors.New("bucket is required")
+	}
+	return nil
+}
+
+func runDeleteBucket(ctx context.Context, job models.Job, p *jobs.Progress) (any, error) {
+	var params models.DeleteBucketJobParams
+	if err := json.Unmarshal(job.Params, &params); err != nil {
+		return nil, err
+	}
+	bucket := params.Bucket
+	client, err := storage.ClientFor(bucket)
+	if err != nil {
+		return nil, err
+	}
+	// old versions keep a bucket from being deleted, once versioning was ever enabled
+	versioning, err := client.GetBucketVersioning(ctx, bucket)
+	if err != nil && minio.ToErrorResponse(err).Code != "NotImplemented" {
+		return nil, err
+	}
+	if err := remove(ctx, bucket, "", versioning.Status != "", p); err != nil {
+		return nil, err
+	}
+	for upload := range client.ListIncompleteUploads(ctx, bucket, "", true) {
+		// an upload completed or aborted while being listed is gone already
+		if minio.ToErrorResponse(upload.Err).Code == "NoSuchUpload" {
+			continue
+		}
+		if upload.Err != nil {
+			return nil, upload.Err
+		}
+		if err := client.RemoveIncompleteUpload(ctx, bucket, upload.Key); err != nil {
+			p.Fail(upload.Key, err)
+		}
+	}
+	if failed := p.Failed(); failed > 0 {
+		return nil, fmt.Errorf("%d objects could not be deleted, the bucket is kept", failed)
+	}
+
+	rctx, cancel := context.WithTimeout(ctx, config.Get().Timeouts.Other)
+	defer cancel()
+	// the bucket is empty now, so dropping its policy first exposes nothing when the
+	// removal fails, and a backend that keeps policies by name cannot make a future
+	// bucket with this name public
+	if err := client.SetBucketPolicy(rctx, bucket, ""); err != nil {
+		if code := minio.ToErrorResponse(err).Code; code != "NotImplemented" && code != "NoSuchBucketPolicy" {
+			return nil, err
+		}
+	}
+	// a bucket removed meanwhile still needs its rules dropped
+	if err := client.RemoveBucket(rctx, bucket); err != nil && minio.ToErrorResponse(err).Code != "NoSuchBucket" {
+		return nil, err
+	}
+	// rules of a removed bucket must not apply to a future bucket with the same name,
+	// replication rules and webhooks are dropped on the BucketRemoved event
+	if err := lifecycle.Rules.Delete(bucket); err != nil {
+		return nil, err
+	}
+	if err := cors.Rules.Delete(bucket); err != nil {
+		return nil, err
+	}
+	events.Publish(events.Event{Type: events.BucketRemoved, Bucket: bucket, Source: events.SourceJob})
+	return nil, nil
+}
+
+// remove -- deletes every object under prefix, with versions all their versions, in
+// batches through multi-object delete
+func remove(ctx context.Context, bucket, prefix string, versions bool, p *jobs.Progress) error {
+	client, err := storage.ClientFor(bucket)
+	if err != nil {
+		return err
+	}
+	// the delete results carry no size, so the listed sizes are kept until then
+	var mu sync.Mutex
+	sizes := make(map[string]int64)
+	id := func(key, version string) string { return key + "\x00" + version }
+
+	objects := make(chan minio.ObjectInfo)
+	var listErr error
+	go func() {
+		defer close(objects)
+		for obj := range client.ListObjects(ctx, bucket, minio.ListObjectsOptions{Prefix: prefix, Recursive: true, WithVersions: versions}) {
+			if obj.Err != nil {
+				listErr = obj.Err
+				return
+			}
+			p.AddTotal(1)
+			mu.Lock()
+			sizes[id(obj.Key, obj.VersionID)] = obj.Size
+			mu.Unlock()
+			select {
+			case objects <- obj:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	for res := range client.RemoveObjectsWithResult(ctx, bucket, objects, minio.RemoveObjectsOptions{}) {
+		mu.Lock()
+		size := sizes[id(res.ObjectName, res.ObjectVersionID)]
+		delete(sizes, id(res.ObjectName, res.ObjectVersionID))
+		mu.Unlock()
+		if res.Err != nil {
+			p.Fail(res.ObjectName, res.Err)
+			continue
+		}
+		p.Done(size)
+		events.Publish(events.Event{Type: events.ObjectRemoved, Bucket: bucket, Key: res.ObjectName, VersionID: res.ObjectVersionID, Source: events.SourceJob})
+	}
+	if listErr != nil {
+		return listErr
+	}
+	return ctx.Err()
+}
+
+// forEach -- calls fn for every listed object, jobs.concurrency at a time, and waits
+// for them; stops at a listing error or when ctx is done
+func forEach(ctx context.Context, list <-chan minio.ObjectInfo, p *jobs.Progress, fn func(minio.ObjectInfo)) error {
+	slots := make(chan struct{}, config.Get().Jobs.Concurrency)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for obj := range list {
+		if obj.Err != nil {
+			return obj.Err
+		}
+		p.AddTotal(1)
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			fn(obj)
+		}()
+	}
+	return ctx.Err()
+}
@@ -0,0 +1,42 @@
+package config
+
+import (
+	"path"
+	"sort"
+)
+
+// DefaultBackend -- the backend configured under s3; it holds every bucket that no
+// route sends elsewhere
+const DefaultBackend = "default"
+
+// Backend -- the named backend's settings
+func (c *Config) Backend(name string) (S3Config, bool) {
+	if name == DefaultBackend {
+		return c.S3, true
+	}
+	b, ok := c.Backends[name]
+	return b, ok
+}
+
+// BackendNames -- the default backend, then the others by name
+func (c *Config) BackendNames() []string {
+	names := make([]string, 0, len(c.Backends))
+	for name := range c.Backends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return append([]string{DefaultBackend}, names...)
+}
+
+// Route -- the backend holding bucket, see RoutingConfig
+func (c *Config) Route(bucket string) string {
+	if name, ok := c.Routing.Buckets[bucket]; ok {
+		return name
+	}
+	for _, r := range c.Routing.Patterns {
+		if ok, _ := path.Match(r.Pattern, bucket); ok {
+			return r.Backend
+		}
+	}
+	return DefaultBackend
+}
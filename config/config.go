@@ -1,33 +1,479 @@
 package config
 
 import (
+	"errors"
+	"flag"
+	"fmt"
 	"log"
 	"os"
-
-	"gopkg.in/yaml.v2"
+	"time"
 )
 
+// ServerConfig -- the HTTP listener. On SIGTERM or SIGINT readiness fails first, for
+// drainDelay, then new connections are refused and in-flight requests get
+// shutdownTimeout to finish before they are cut off.
+type ServerConfig struct {
+	Address         string        `yaml:"address"`
+	ShutdownTimeout time.Duration `yaml:"shutdownTimeout"`
+	DrainDelay      time.Duration `yaml:"drainDelay"`
+}
+
+// TimeoutsConfig -- upper bounds for backend calls by kind; put and get cover the whole
+// transfer so they must allow for the largest objects at the slowest expected rate
+type TimeoutsConfig struct {
+	List  time.Duration `yaml:"list"`
+	Stat  time.Duration `yaml:"stat"`
+	Put   time.Duration `yaml:"put"`
+	Get   time.Duration `yaml:"get"`
+	Other time.Duration `yaml:"other"`
+}
+
+// ResilienceConfig -- retries of transient backend failures, 0 turns them off, and
+// the circuit breaker that fast-fails calls once the backend looks down
+type ResilienceConfig struct {
+	Retries          int           `yaml:"retries"`
+	RetryBackoff     time.Duration `yaml:"retryBackoff"`
+	RetryMaxBackoff  time.Duration `yaml:"retryMaxBackoff"`
+	BreakerThreshold int           `yaml:"breakerThreshold"`
+	BreakerCooldown  time.Duration `yaml:"breakerCooldown"`
+}
+
+// S3Config -- the backend; AccessKey and SecretKey may be secret references, see
+// SecretsConfig
 type S3Config struct {
 	Endpoint  string `yaml:"endpoint"`
 	AccessKey string `yaml:"accessKey"`
-	SecretKey string `yaml:"secretKey"`
+	SecretKey string `yaml:"secretKey" secret:"true"`
 	Region    string `yaml:"region"`
 	UseSSL    bool   `yaml:"useSSL"`
 }
 
-type Config struct {
-	S3 S3Config `yaml:"s3"`
+// RoutingConfig -- which backend holds a bucket: its entry in buckets, else the
+// first pattern (path.Match syntax, like "archive-*") that matches, else the s3 backend
+type RoutingConfig struct {
+	Buckets  map[string]string `yaml:"buckets"`
+	Patterns []RoutePattern    `yaml:"patterns"`
+}
+
+type RoutePattern struct {
+	Pattern string `yaml:"pattern"`
+	Backend string `yaml:"backend"`
+}
+
+// LifecycleConfig -- where bucket lifecycle rules are enforced
+// mode "native" sends rules to the backend, "gateway" stores and enforces them here,
+// "auto" tries the backend first and falls back to the gateway when it is not supported
+type LifecycleConfig struct {
+	Mode     string        `yaml:"mode"`
+	Interval time.Duration `yaml:"interval"`
+}
+
+// EnvelopeConfig -- gateway-side encryption of object payloads
+// the keyfile is also needed to read objects uploaded while encryption was enabled
+type EnvelopeConfig struct {
+	Enabled   bool   `yaml:"enabled"`
+	KeyFile   string `yaml:"keyFile"`
+	ChunkSize int    `yaml:"chunkSize"`
+}
+
+// CORSConfig -- browser access to the gateway routes, no allowed origins disables CORS
+// buckets with their own CORS rules use those on their object routes instead
+type CORSConfig struct {
+	AllowedOrigins   []string      `yaml:"allowedOrigins"`
+	AllowedMethods   []string      `yaml:"allowedMethods"`
+	AllowedHeaders   []string      `yaml:"allowedHeaders"`
+	ExposeHeaders    []string      `yaml:"exposeHeaders"`
+	MaxAge           time.Duration `yaml:"maxAge"`
+	AllowCredentials bool          `yaml:"allowCredentials"`
+}
+
+// WebhookConfig -- delivery of bucket events to webhook subscribers
+type WebhookConfig struct {
+	Workers     int           `yaml:"workers"`
+	Timeout     time.Duration `yaml:"timeout"`
+	MaxAttempts int           `yaml:"maxAttempts"`
+	Backoff     time.Duration `yaml:"backoff"`
+	MaxBackoff  time.Duration `yaml:"maxBackoff"`
+}
+
+// StreamConfig -- live event streams for dashboards, GET /events/:bucket
+// backend notifications are only followed while a bucket has clients connected
+type StreamConfig struct {
+	Buffer               int           `yaml:"buffer"`
+	KeepAlive            time.Duration `yaml:"keepAlive"`
+	BackendNotifications bool          `yaml:"backendNotifications"`
+	Retry                time.Duration `yaml:"retry"`
+}
+
+// SinkConfig -- a message queue that receives bucket events, each sink has its own
+// on-disk retry queue so events are delivered at least once
+// topic is the NATS subject, Kafka topic or AMQP routing key, with {bucket} and {type}
+// replaced per event; empty filters match everything
+type SinkConfig struct {
+	Name        string        `yaml:"name"`
+	Type        string        `yaml:"type"`
+	URL         string        `yaml:"url" secret:"url"`
+	Topic       string        `yaml:"topic"`
+	Exchange    string        `yaml:"exchange"`
+	JetStream   bool          `yaml:"jetStream"`
+	Buckets     []string      `yaml:"buckets"`
+	Prefix      string        `yaml:"prefix"`
+	Suffix      string        `yaml:"suffix"`
+	Events      []string      `yaml:"events"`
+	Workers     int           `yaml:"workers"`
+	MaxAttempts int           `yaml:"maxAttempts"`
+	Backoff     time.Duration `yaml:"backoff"`
+	MaxBackoff  time.Duration `yaml:"maxBackoff"`
+}
+
+type EventsConfig struct {
+	Webhooks WebhookConfig `yaml:"webhooks"`
+	Stream   StreamConfig  `yaml:"stream"`
+	Sinks    []SinkConfig  `yaml:"sinks"`
+}
+
+// ReplicationConfig -- copying objects to other backends by replication rule, through
+// an on-disk queue so changes survive restarts and failed copies are retried
+type ReplicationConfig struct {
+	Workers     int           `yaml:"workers"`
+	MaxAttempts int           `yaml:"maxAttempts"`
+	Backoff     time.Duration `yaml:"backoff"`
+	MaxBackoff  time.Duration `yaml:"maxBackoff"`
+}
+
+// TracingConfig -- OpenTelemetry spans for requests and backend calls
+// exporter "otlp" sends OTLP over HTTP to endpoint, "stdout" and "file" write JSON spans
+type TracingConfig struct {
+	Enabled     bool    `yaml:"enabled"`
+	Exporter    string  `yaml:"exporter"`
+	Endpoint    string  `yaml:"endpoint"`
+	Insecure    bool    `yaml:"insecure"`
+	File        string  `yaml:"file"`
+	ServiceName string  `yaml:"serviceName"`
+	SampleRatio float64 `yaml:"sampleRatio"`
 }
 
-var Cfg Config
+// HealthConfig -- readiness checks; the backend checks list buckets within timeout,
+// the disk checks fail below minFreeBytes in the log and data directories. Only the
+// requiredBackends fail readiness, the others are reported as warnings.
+type HealthConfig struct {
+	Timeout          time.Duration `yaml:"timeout"`
+	MinFreeBytes     uint64        `yaml:"minFreeBytes"`
+	RequiredBackends []string      `yaml:"requiredBackends"`
+}
+
+// LoggingConfig -- the JSON request log, rotated by size
+type LoggingConfig struct {
+	File       string `yaml:"file"`
+	Level      string `yaml:"level"`
+	MaxSizeMB  int    `yaml:"maxSizeMB"`
+	MaxBackups int    `yaml:"maxBackups"`
+	MaxAgeDays int    `yaml:"maxAgeDays"`
+	Compress   bool   `yaml:"compress"`
+	LogBodies  bool   `yaml:"logBodies"`
+}
+
+// LimitsConfig -- request size limits; maxUploadBytes 0 means no limit, larger uploads
+// are answered 413. multipartMemory is how much of a form is buffered in memory before
+// spilling to temporary files.
+type LimitsConfig struct {
+	MaxUploadBytes  int64 `yaml:"maxUploadBytes"`
+	MultipartMemory int64 `yaml:"multipartMemory"`
+}
+
+// VaultConfig -- the Vault compatible store behind vault:// secret references; token
+// is usually a reference itself, like env://VAULT_TOKEN
+type VaultConfig struct {
+	Address   string        `yaml:"address"`
+	Token     string        `yaml:"token" secret:"true"`
+	Namespace string        `yaml:"namespace"`
+	Timeout   time.Duration `yaml:"timeout"`
+}
+
+// SecretsConfig -- how secret references (file://, env://, vault://) in backend keys
+// and sink URLs are resolved; fetched values are re-read after refresh
+type SecretsConfig struct {
+	Refresh time.Duration `yaml:"refresh"`
+	Vault   VaultConfig   `yaml:"vault"`
+}
+
+// ArchiveConfig -- limits of the archive downloads; sizes are those stored, which for
+// envelope encrypted objects is slightly more than their content
+type ArchiveConfig struct {
+	MaxObjects int   `yaml:"maxObjects"`
+	MaxBytes   int64 `yaml:"maxBytes"`
+}
+
+// JobsConfig -- background jobs: workers run that many jobs at once, each working on
+// up to concurrency objects at a time, or as many as the job asks for up to
+// maxConcurrency; finished jobs are kept for retention
+type JobsConfig struct {
+	Workers        int           `yaml:"workers"`
+	Concurrency    int           `yaml:"concurrency"`
+	MaxConcurrency int           `yaml:"maxConcurrency"`
+	Retention      time.Duration `yaml:"retention"`
+}
+
+// SyncConfig -- sync jobs; local directories can only be synced through the API when
+// they are under one of allowedDirs, the sync subcommand may read any directory
+type SyncConfig struct {
+	Workers     int      `yaml:"workers"`
+	AllowedDirs []string `yaml:"allowedDirs"`
+}
+
+// ReloadConfig -- the configuration is reloaded on SIGHUP, and with watch also when the
+// config file changes; invalid changes are rejected and the running config kept
+type ReloadConfig struct {
+	Watch    bool          `yaml:"watch"`
+	Debounce time.Duration `yaml:"debounce"`
+}
 
+type Config struct {
+	Server      ServerConfig        `yaml:"server"`
+	Logging     LoggingConfig       `yaml:"logging"`
+	Limits      LimitsConfig        `yaml:"limits"`
+	S3          S3Config            `yaml:"s3"`
+	Backends    map[string]S3Config `yaml:"backends"`
+	Routing     RoutingConfig       `yaml:"routing"`
+	Secrets     SecretsConfig       `yaml:"secrets"`
+	Timeouts    TimeoutsConfig      `yaml:"timeouts"`
+	Resilience  ResilienceConfig    `yaml:"resilience"`
+	DataDir     string              `yaml:"dataDir"`
+	Lifecycle   LifecycleConfig     `yaml:"lifecycle"`
+	Envelope    EnvelopeConfig      `yaml:"envelope"`
+	CORS        CORSConfig          `yaml:"cors"`
+	Events      EventsConfig        `yaml:"events"`
+	Replication ReplicationConfig   `yaml:"replication"`
+	Jobs        JobsConfig          `yaml:"jobs"`
+	Archive     ArchiveConfig       `yaml:"archive"`
+	Sync        SyncConfig          `yaml:"sync"`
+	Tracing     TracingConfig       `yaml:"tracing"`
+	Health      HealthConfig        `yaml:"health"`
+	Reload      ReloadConfig        `yaml:"reload"`
+}
+
+// LoadConfig -- loads the configuration from the command line, environment and config file, see
+// Load; with --print-config it prints the result and exits. Started as
+// "validate-config [flags]" it only reports whether the configuration is valid.
 func LoadConfig() {
-	file, err := os.ReadFile("config.yaml")
+	args := os.Args[1:]
+	if len(args) > 0 && args[0] == "validate-config" {
+		os.Exit(validateConfig(args[1:]))
+	}
+	cfg, opts, err := Load(args)
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
 	if err != nil {
-		log.Fatalf("Error reading config: %v", err)
+		log.Fatalf("Error loading config: %v", err)
+	}
+	store(&cfg)
+	loadArgs, loadedFile = args, opts.ConfigFile
+	if opts.PrintConfig {
+		if err := Print(os.Stdout, cfg); err != nil {
+			log.Fatalf("Error printing config: %v", err)
+		}
+		os.Exit(0)
+	}
+}
+
+// validateConfig -- the validate-config subcommand, returns the exit status
+func validateConfig(args []string) int {
+	_, opts, err := Load(args)
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	source := opts.ConfigFile
+	if source == "" {
+		source = "defaults and environment"
 	}
-	err = yaml.Unmarshal(file, &Cfg)
 	if err != nil {
-		log.Fatalf("Error parsing config: %v", err)
+		fmt.Fprintf(os.Stderr, "%s: %v\n", source, err)
+		return 1
+	}
+	fmt.Printf("%s: OK\n", source)
+	return 0
+}
+
+func applyDefaults(cfg *Config) {
+	if cfg.Server.Address == "" {
+		cfg.Server.Address = ":8080"
+	}
+	if cfg.Server.ShutdownTimeout <= 0 {
+		cfg.Server.ShutdownTimeout = 30 * time.Second
+	}
+	if cfg.Logging.File == "" {
+		cfg.Logging.File = "./logs/server.log"
+	}
+	if cfg.Logging.Level == "" {
+		cfg.Logging.Level = "info"
+	}
+	if cfg.Logging.MaxSizeMB <= 0 {
+		cfg.Logging.MaxSizeMB = 10
+	}
+	if cfg.Logging.MaxBackups <= 0 {
+		cfg.Logging.MaxBackups = 5
+	}
+	if cfg.Logging.MaxAgeDays <= 0 {
+		cfg.Logging.MaxAgeDays = 30
+	}
+	if cfg.Limits.MultipartMemory <= 0 {
+		cfg.Limits.MultipartMemory = 10 << 20
+	}
+	if cfg.Secrets.Refresh <= 0 {
+		cfg.Secrets.Refresh = time.Minute
+	}
+	if cfg.Secrets.Vault.Timeout <= 0 {
+		cfg.Secrets.Vault.Timeout = 5 * time.Second
+	}
+	if cfg.Timeouts.List <= 0 {
+		cfg.Timeouts.List = 30 * time.Second
+	}
+	if cfg.Timeouts.Stat <= 0 {
+		cfg.Timeouts.Stat = 10 * time.Second
+	}
+	if cfg.Timeouts.Put <= 0 {
+		cfg.Timeouts.Put = time.Hour
+	}
+	if cfg.Timeouts.Get <= 0 {
+		cfg.Timeouts.Get = time.Hour
+	}
+	if cfg.Timeouts.Other <= 0 {
+		cfg.Timeouts.Other = 30 * time.Second
+	}
+	if cfg.Resilience.Retries < 0 {
+		cfg.Resilience.Retries = 3
+	}
+	if cfg.Resilience.RetryBackoff <= 0 {
+		cfg.Resilience.RetryBackoff = 100 * time.Millisecond
+	}
+	if cfg.Resilience.RetryMaxBackoff <= 0 {
+		cfg.Resilience.RetryMaxBackoff = 2 * time.Second
+	}
+	if cfg.Resilience.BreakerThreshold <= 0 {
+		cfg.Resilience.BreakerThreshold = 5
+	}
+	if cfg.Resilience.BreakerCooldown <= 0 {
+		cfg.Resilience.BreakerCooldown = 30 * time.Second
+	}
+	if cfg.Replication.Workers <= 0 {
+		cfg.Replication.Workers = 4
+	}
+	if cfg.Replication.MaxAttempts <= 0 {
+		cfg.Replication.MaxAttempts = 10
+	}
+	if cfg.Replication.Backoff <= 0 {
+		cfg.Replication.Backoff = time.Second
+	}
+	if cfg.Replication.MaxBackoff <= 0 {
+		cfg.Replication.MaxBackoff = 5 * time.Minute
+	}
+	if cfg.Jobs.Workers <= 0 {
+		cfg.Jobs.Workers = 2
+	}
+	if cfg.Jobs.Concurrency <= 0 {
+		cfg.Jobs.Concurrency = 8
+	}
+	if cfg.Jobs.MaxConcurrency <= 0 {
+		cfg.Jobs.MaxConcurrency = 64
+	}
+	if cfg.Jobs.MaxConcurrency < cfg.Jobs.Concurrency {
+		cfg.Jobs.MaxConcurrency = cfg.Jobs.Concurrency
+	}
+	if cfg.Jobs.Retention <= 0 {
+		cfg.Jobs.Retention = 7 * 24 * time.Hour
+	}
+	if cfg.Archive.MaxObjects <= 0 {
+		cfg.Archive.MaxObjects = 10000
+	}
+	if cfg.Archive.MaxBytes <= 0 {
+		cfg.Archive.MaxBytes = 5 << 30
+	}
+	if cfg.Sync.Workers <= 0 {
+		cfg.Sync.Workers = 8
+	}
+	if cfg.Reload.Debounce <= 0 {
+		cfg.Reload.Debounce = 500 * time.Millisecond
+	}
+	if cfg.DataDir == "" {
+		cfg.DataDir = "./data"
+	}
+	if cfg.Lifecycle.Mode == "" {
+		cfg.Lifecycle.Mode = "auto"
+	}
+	if cfg.Lifecycle.Interval <= 0 {
+		cfg.Lifecycle.Interval = time.Hour
+	}
+	if cfg.Envelope.ChunkSize <= 0 {
+		cfg.Envelope.ChunkSize = 64 << 10
+	}
+	if len(cfg.CORS.AllowedMethods) == 0 {
+		cfg.CORS.AllowedMethods = []string{"GET", "HEAD", "POST", "PUT", "DELETE"}
+	}
+	if cfg.Events.Webhooks.Workers <= 0 {
+		cfg.Events.Webhooks.Workers = 4
+	}
+	if cfg.Events.Webhooks.Timeout <= 0 {
+		cfg.Events.Webhooks.Timeout = 10 * time.Second
+	}
+	if cfg.Events.Webhooks.MaxAttempts <= 0 {
+		cfg.Events.Webhooks.MaxAttempts = 10
+	}
+	if cfg.Events.Webhooks.Backoff <= 0 {
+		cfg.Events.Webhooks.Backoff = 2 * time.Second
+	}
+	if cfg.Events.Webhooks.MaxBackoff <= 0 {
+		cfg.Events.Webhooks.MaxBackoff = 10 * time.Minute
+	}
+	if cfg.Events.Stream.Buffer <= 0 {
+		cfg.Events.Stream.Buffer = 1000
+	}
+	if cfg.Events.Stream.KeepAlive <= 0 {
+		cfg.Events.Stream.KeepAlive = 15 * time.Second
+	}
+	if cfg.Events.Stream.Retry <= 0 {
+		cfg.Events.Stream.Retry = 30 * time.Second
+	}
+	if cfg.Tracing.Exporter == "" {
+		cfg.Tracing.Exporter = "otlp"
+	}
+	if cfg.Tracing.Endpoint == "" {
+		cfg.Tracing.Endpoint = "localhost:4318"
+	}
+	if cfg.Tracing.File == "" {
+		cfg.Tracing.File = "./logs/traces.json"
+	}
+	if cfg.Tracing.ServiceName == "" {
+		cfg.Tracing.ServiceName = "kluisz-object-storage"
+	}
+	if cfg.Tracing.SampleRatio <= 0 {
+		cfg.Tracing.SampleRatio = 1
+	}
+	if cfg.Health.Timeout <= 0 {
+		cfg.Health.Timeout = 2 * time.Second
+	}
+	if cfg.Health.MinFreeBytes == 0 {
+		cfg.Health.MinFreeBytes = 100 << 20
+	}
+	if len(cfg.Health.RequiredBackends) == 0 {
+		cfg.Health.RequiredBackends = []string{DefaultBackend}
+	}
+	for i := range cfg.Events.Sinks {
+		sink := &cfg.Events.Sinks[i]
+		if sink.Topic == "" {
+			sink.Topic = "bucket-events"
+		}
+		if sink.Workers <= 0 {
+			sink.Workers = 1
+		}
+		if sink.MaxAttempts <= 0 {
+			sink.MaxAttempts = 20
+		}
+		if sink.Backoff <= 0 {
+			sink.Backoff = time.Second
+		}
+		if sink.MaxBackoff <= 0 {
+			sink.MaxBackoff = 5 * time.Minute
+		}
 	}
 }
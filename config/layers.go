@@ -0,0 +1,288 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"gopkg.in/yaml.v2"
+)
+
+// EnvPrefix -- environment variables override config values, named after the yaml
+// path: s3.endpoint is OBJSTORE_S3_ENDPOINT, server.shutdownTimeout is
+// OBJSTORE_SERVER_SHUTDOWN_TIMEOUT
+const EnvPrefix = "OBJSTORE_"
+
+// DefaultFile -- read when no --config flag or OBJSTORE_CONFIG is given, and only if
+// it exists
+const DefaultFile = "config.yaml"
+
+// Options -- command line switches that are not configuration values
+type Options struct {
+	ConfigFile  string
+	PrintConfig bool
+}
+
+// setting -- a scalar config value addressed by its yaml path, like "s3.endpoint"
+type setting struct {
+	path  string
+	value reflect.Value
+}
+
+// Load -- builds the configuration from, in increasing precedence, the built-in
+// defaults, the config file, OBJSTORE_* environment variables and command line flags.
+// Every scalar value has a flag named after its yaml path, e.g. --s3.endpoint.
+func Load(args []string) (Config, Options, error) {
+	var cfg Config
+	// settings where zero is a valid choice start out negative, "not set", and are
+	// given their default by applyDefaults
+	cfg.Resilience.Retries = -1
+	var opts Options
+	settings := collect(reflect.ValueOf(&cfg).Elem(), "")
+
+	fs := flag.NewFlagSet("kluisz-object-storage", flag.ContinueOnError)
+	fs.StringVar(&opts.ConfigFile, "config", "", "config file, default "+DefaultFile+" or $"+EnvPrefix+"CONFIG")
+	fs.BoolVar(&opts.PrintConfig, "print-config", false, "print the effective config, secrets redacted, and exit")
+	// flags are applied last, so only remember their values while parsing
+	var flagged []func()
+	for _, s := range settings {
+		usage := "overrides " + s.path + ", env " + EnvName(s.path)
+		set := func(raw string) error {
+			v := reflect.New(s.value.Type()).Elem()
+			if err := parse(v, raw); err != nil {
+				return err
+			}
+			flagged = append(flagged, func() { s.value.Set(v) })
+			return nil
+		}
+		if s.value.Kind() == reflect.Bool {
+			fs.BoolFunc(s.path, usage, set)
+		} else {
+			fs.Func(s.path, usage, set)
+		}
+	}
+	if err := fs.Parse(args); err != nil {
+		return cfg, opts, err
+	}
+
+	file, explicit := opts.ConfigFile, true
+	if file == "" {
+		file = os.Getenv(EnvPrefix + "CONFIG")
+	}
+	if file == "" {
+		file, explicit = DefaultFile, false
+	}
+	var problems Problems
+	raw, err := os.ReadFile(file)
+	switch {
+	case err == nil:
+		// unknown fields are reported with the rest, the known ones are still decoded
+		var typeErr *yaml.TypeError
+		if err := yaml.UnmarshalStrict(raw, &cfg); errors.As(err, &typeErr) {
+			problems = append(problems, unknownFields(typeErr.Errors)...)
+		} else if err != nil {
+			return cfg, opts, fmt.Errorf("parsing %s: %w", file, err)
+		}
+		opts.ConfigFile = file
+	case explicit || !errors.Is(err, os.ErrNotExist):
+		return cfg, opts, fmt.Errorf("reading config: %w", err)
+	}
+
+	for _, s := range settings {
+		if v, ok := os.LookupEnv(EnvName(s.path)); ok {
+			if err := parse(s.value, v); err != nil {
+				problems = append(problems, fmt.Sprintf("%s: %v", EnvName(s.path), err))
+			}
+		}
+	}
+	for _, apply := range flagged {
+		apply()
+	}
+
+	applyDefaults(&cfg)
+	problems = append(problems, Validate(cfg)...)
+	if len(problems) > 0 {
+		return cfg, opts, problems
+	}
+	return cfg, opts, nil
+}
+
+// collect -- every scalar and string list below v; lists of structs and maps, like
+// the event sinks and backends, can only be set in the config file
+func collect(v reflect.Value, prefix string) []setting {
+	var out []setting
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("yaml"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+		path := prefix + name
+		f := v.Field(i)
+		switch {
+		case f.Kind() == reflect.Struct:
+			out = append(out, collect(f, path+".")...)
+		case f.Kind() == reflect.Slice && f.Type().Elem().Kind() != reflect.String,
+			f.Kind() == reflect.Map:
+		default:
+			out = append(out, setting{path: path, value: f})
+		}
+	}
+	return out
+}
+
+// EnvName -- "s3.useSSL" becomes OBJSTORE_S3_USE_SSL
+func EnvName(path string) string {
+	var b strings.Builder
+	b.WriteString(EnvPrefix)
+	runes := []rune(path)
+	for i, r := range runes {
+		switch {
+		case r == '.':
+			b.WriteByte('_')
+			continue
+		case i > 0 && unicode.IsUpper(r) && runes[i-1] != '.':
+			// a new word starts at an upper case letter after a lower case one, or at
+			// the last capital of an acronym followed by lower case (SSLCert)
+			prevLower := unicode.IsLower(runes[i-1])
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if prevLower || (unicode.IsUpper(runes[i-1]) && nextLower) {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteRune(unicode.ToUpper(r))
+	}
+	return b.String()
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// parse -- sets v from its text form; lists are comma separated
+func parse(v reflect.Value, raw string) error {
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint64:
+		n, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		var list []string
+		for _, s := range strings.Split(raw, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				list = append(list, s)
+			}
+		}
+		v.Set(reflect.ValueOf(list))
+	default:
+		return fmt.Errorf("unsupported config type %s", v.Type())
+	}
+	return nil
+}
+
+// Print -- writes cfg as YAML with secrets redacted, see the secret struct tag
+func Print(w io.Writer, cfg Config) error {
+	// a deep copy, redacting must not touch the live config
+	raw, err := yaml.Marshal(cfg)
+	if err != nil {
+		return err
+	}
+	var out Config
+	if err := yaml.Unmarshal(raw, &out); err != nil {
+		return err
+	}
+	redact(reflect.ValueOf(&out).Elem())
+	raw, err = yaml.Marshal(out)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(raw)
+	return err
+}
+
+const redacted = "REDACTED"
+
+var referencePattern = regexp.MustCompile(`^[a-z][a-z0-9+.-]*://`)
+
+// isReference -- a secret reference like file:///run/secrets/key, see package secrets
+func isReference(value string) bool {
+	return referencePattern.MatchString(value)
+}
+
+// redact -- blanks string fields tagged secret:"true", and the password of those
+// tagged secret:"url"
+func redact(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := v.Field(i)
+			switch t.Field(i).Tag.Get("secret") {
+			case "true":
+				// references name where the secret lives and are shown as is
+				if f.String() != "" && !isReference(f.String()) {
+					f.SetString(redacted)
+				}
+			case "url":
+				if u, err := url.Parse(f.String()); err == nil && u.User != nil {
+					if _, ok := u.User.Password(); ok {
+						u.User = url.UserPassword(u.User.Username(), redacted)
+						f.SetString(u.String())
+					}
+				}
+			default:
+				redact(f)
+			}
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			redact(v.Index(i))
+		}
+	case reflect.Map:
+		// map values cannot be changed in place
+		iter := v.MapRange()
+		for iter.Next() {
+			e := reflect.New(iter.Value().Type()).Elem()
+			e.Set(iter.Value())
+			redact(e)
+			v.SetMapIndex(iter.Key(), e)
+		}
+	}
+}
@@ -0,0 +1,236 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"go.uber.org/zap"
+)
+
+// Snapshot -- one loaded configuration; Version counts successful loads
+type Snapshot struct {
+	Config
+	Version uint64
+	Loaded  time.Time
+}
+
+var (
+	current atomic.Pointer[Snapshot]
+	// what LoadConfig was started with, reloads repeat it
+	loadArgs   []string
+	loadedFile string
+)
+
+// Get -- the current configuration. It is replaced as a whole on reload, so callers
+// that need several values consistent with each other should call Get once.
+func Get() *Config {
+	return &current.Load().Config
+}
+
+// Current -- the current configuration with its version
+func Current() *Snapshot {
+	return current.Load()
+}
+
+func store(cfg *Config) *Snapshot {
+	var version uint64 = 1
+	if old := current.Load(); old != nil {
+		version = old.Version + 1
+	}
+	s := &Snapshot{Config: *cfg, Version: version, Loaded: time.Now()}
+	current.Store(s)
+	return s
+}
+
+// Use -- makes cfg the running configuration, for subcommands that Load it themselves
+func Use(cfg Config) {
+	store(&cfg)
+}
+
+// restartOnly -- settings read once when the gateway starts; a reload records them but
+// they only take effect after a restart
+var restartOnly = []string{
+	"server.address", "logging.file", "logging.maxSizeMB", "logging.maxBackups",
+	"logging.maxAgeDays", "logging.compress", "logging.logBodies", "dataDir", "lifecycle.",
+	"envelope.", "cors.", "events.", "replication.", "jobs.workers", "tracing.",
+	"reload.",
+}
+
+// Change -- one setting that differs between two configurations; secret values are
+// not included
+type Change struct {
+	Path    string
+	Old     string
+	New     string
+	Restart bool
+}
+
+func (c Change) String() string {
+	s := c.Path + ": " + c.Old + " -> " + c.New
+	if c.Restart {
+		s += " (after restart)"
+	}
+	return s
+}
+
+var (
+	reloadMu  sync.Mutex
+	reloaders []func(old, new *Config)
+)
+
+// OnReload -- fn is called after every successful reload, for components built from
+// the configuration that must be rebuilt when it changes
+func OnReload(fn func(old, new *Config)) {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+	reloaders = append(reloaders, fn)
+}
+
+// Reload -- loads the configuration again from the same file, environment and flags.
+// An invalid configuration is rejected and the current one kept; otherwise it replaces
+// the current one and the changed settings are returned.
+func Reload() ([]Change, error) {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	cfg, _, err := Load(loadArgs)
+	if err != nil {
+		return nil, err
+	}
+	old := Get()
+	changes := Diff(old, &cfg)
+	if len(changes) == 0 {
+		return nil, nil
+	}
+	next := store(&cfg)
+	for _, fn := range reloaders {
+		fn(old, &next.Config)
+	}
+	return changes, nil
+}
+
+// Diff -- the settings that differ between a and b, by yaml path
+func Diff(a, b *Config) []Change {
+	var out []Change
+	var walk func(x, y reflect.Value, t reflect.Type, prefix string)
+	walk = func(x, y reflect.Value, t reflect.Type, prefix string) {
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			name := strings.Split(f.Tag.Get("yaml"), ",")[0]
+			if name == "" || name == "-" {
+				continue
+			}
+			path := prefix + name
+			xv, yv := x.Field(i), y.Field(i)
+			if f.Type.Kind() == reflect.Struct {
+				walk(xv, yv, f.Type, path+".")
+				continue
+			}
+			if reflect.DeepEqual(xv.Interface(), yv.Interface()) {
+				continue
+			}
+			c := Change{Path: path, Old: fmt.Sprint(xv.Interface()), New: fmt.Sprint(yv.Interface())}
+			if f.Tag.Get("secret") != "" || (f.Type.Kind() == reflect.Slice || f.Type.Kind() == reflect.Map) && f.Type.Elem().Kind() == reflect.Struct {
+				c.Old, c.New = "(hidden)", "(changed)"
+			}
+			for _, p := range restartOnly {
+				if path == p || strings.HasSuffix(p, ".") && strings.HasPrefix(path, p) {
+					c.Restart = true
+				}
+			}
+			out = append(out, c)
+		}
+	}
+	walk(reflect.ValueOf(*a), reflect.ValueOf(*b), reflect.TypeOf(Config{}), "")
+	return out
+}
+
+// Watch -- reloads on SIGHUP and, when reload.watch is set, when the config file
+// changes, until ctx is cancelled. The file's directory is watched so editors that
+// replace the file and Kubernetes ConfigMap symlink swaps are both noticed.
+func Watch(ctx context.Context, logger *zap.Logger) error {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	var fileEvents chan fsnotify.Event
+	var watcher *fsnotify.Watcher
+	if Get().Reload.Watch && loadedFile != "" {
+		var err error
+		watcher, err = fsnotify.NewWatcher()
+		if err != nil {
+			signal.Stop(hup)
+			return err
+		}
+		if err := watcher.Add(filepath.Dir(loadedFile)); err != nil {
+			signal.Stop(hup)
+			watcher.Close()
+			return err
+		}
+		fileEvents = watcher.Events
+	}
+
+	reload := func(trigger string) {
+		changes, err := Reload()
+		if err != nil {
+			logger.Error("Configuration reload rejected, keeping the current one",
+				zap.String("trigger", trigger), zap.Error(err))
+			return
+		}
+		if len(changes) == 0 {
+			logger.Info("Configuration reloaded, nothing changed", zap.String("trigger", trigger))
+			return
+		}
+		diff := make([]string, len(changes))
+		for i, c := range changes {
+			diff[i] = c.String()
+		}
+		logger.Info("Configuration reloaded", zap.String("trigger", trigger),
+			zap.Uint64("version", Current().Version), zap.Strings("changed", diff))
+	}
+
+	go func() {
+		defer signal.Stop(hup)
+		if watcher != nil {
+			defer watcher.Close()
+		}
+		// editors write in several steps, reload once they settle
+		var settle <-chan time.Time
+		name := filepath.Base(loadedFile)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hup:
+				reload("SIGHUP")
+			case e := <-fileEvents:
+				base := filepath.Base(e.Name)
+				if base == name || base == "..data" {
+					settle = time.After(Get().Reload.Debounce)
+				}
+			case err := <-watcherErrors(watcher):
+				logger.Warn("Config file watch error", zap.Error(err))
+			case <-settle:
+				settle = nil
+				reload("file")
+			}
+		}
+	}()
+	return nil
+}
+
+func watcherErrors(w *fsnotify.Watcher) chan error {
+	if w == nil {
+		return nil
+	}
+	return w.Errors
+}
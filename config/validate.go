@@ -0,0 +1,270 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"path"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// Problems -- everything wrong with a configuration, reported together so one
+// round of fixes is enough
+type Problems []string
+
+func (p Problems) Error() string {
+	if len(p) == 1 {
+		return p[0]
+	}
+	return fmt.Sprintf("%d problems:\n  - %s", len(p), strings.Join(p, "\n  - "))
+}
+
+var regionPattern = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// Validate -- semantic checks of a configuration with defaults applied; each
+// problem names the setting and how to fix it
+func Validate(cfg Config) Problems {
+	var p Problems
+	add := func(path, format string, args ...any) {
+		p = append(p, path+": "+fmt.Sprintf(format, args...))
+	}
+
+	if _, _, err := net.SplitHostPort(cfg.Server.Address); err != nil {
+		add("server.address", "%q is not [host]:port, e.g. \":8080\"", cfg.Server.Address)
+	}
+
+	for _, name := range cfg.BackendNames() {
+		prefix := "backends." + name
+		if name == DefaultBackend {
+			prefix = "s3"
+		}
+		b, _ := cfg.Backend(name)
+		p = append(p, validateBackend(prefix, b, cfg.Secrets.Vault.Address != "")...)
+	}
+	if _, ok := cfg.Backends[DefaultBackend]; ok {
+		add("backends."+DefaultBackend, "the name is taken by the s3 backend, pick another")
+	}
+	routed := make([]string, 0, len(cfg.Routing.Buckets))
+	for bucket := range cfg.Routing.Buckets {
+		routed = append(routed, bucket)
+	}
+	sort.Strings(routed)
+	for _, bucket := range routed {
+		if name := cfg.Routing.Buckets[bucket]; !isBackend(cfg, name) {
+			add("routing.buckets."+bucket, "unknown backend %q", name)
+		}
+	}
+	for i, r := range cfg.Routing.Patterns {
+		if _, err := path.Match(r.Pattern, ""); err != nil || r.Pattern == "" {
+			add(fmt.Sprintf("routing.patterns[%d].pattern", i), "%q is not a valid pattern, e.g. \"archive-*\"", r.Pattern)
+		}
+		if !isBackend(cfg, r.Backend) {
+			add(fmt.Sprintf("routing.patterns[%d].backend", i), "unknown backend %q", r.Backend)
+		}
+	}
+	for i, name := range cfg.Health.RequiredBackends {
+		if !isBackend(cfg, name) {
+			add(fmt.Sprintf("health.requiredBackends[%d]", i), "unknown backend %q", name)
+		}
+	}
+	if a := cfg.Secrets.Vault.Address; a != "" {
+		if u, err := url.Parse(a); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			add("secrets.vault.address", "%q must be an http:// or https:// URL", a)
+		}
+	}
+	if strings.HasPrefix(cfg.Secrets.Vault.Token, "vault://") {
+		add("secrets.vault.token", "cannot be read from vault itself, use a value, file:// or env://")
+	}
+
+	if _, err := zapcore.ParseLevel(cfg.Logging.Level); err != nil {
+		add("logging.level", "%q must be debug, info, warn or error", cfg.Logging.Level)
+	}
+	if cfg.Limits.MaxUploadBytes < 0 {
+		add("limits.maxUploadBytes", "%d is negative, use 0 for no limit", cfg.Limits.MaxUploadBytes)
+	}
+
+	switch cfg.Lifecycle.Mode {
+	case "auto", "native", "gateway":
+	default:
+		add("lifecycle.mode", "%q must be auto, native or gateway", cfg.Lifecycle.Mode)
+	}
+	if cfg.Envelope.Enabled && cfg.Envelope.KeyFile == "" {
+		add("envelope.keyFile", "required when envelope encryption is enabled")
+	}
+
+	for _, origin := range cfg.CORS.AllowedOrigins {
+		if origin == "*" && cfg.CORS.AllowCredentials {
+			add("cors.allowCredentials", "browsers refuse credentials with origin \"*\", list the origins instead")
+		}
+	}
+
+	seen := make(map[string]bool)
+	for i, sink := range cfg.Events.Sinks {
+		path := fmt.Sprintf("events.sinks[%d]", i)
+		if sink.Name == "" || strings.ContainsAny(sink.Name, `/\`) {
+			add(path+".name", "required, without path separators")
+		} else if seen[sink.Name] {
+			add(path+".name", "%q is used by another sink", sink.Name)
+		}
+		seen[sink.Name] = true
+		switch sink.Type {
+		case "nats", "kafka", "amqp":
+		default:
+			add(path+".type", "%q must be nats, kafka or amqp", sink.Type)
+		}
+		if sink.URL == "" {
+			add(path+".url", "required")
+		}
+	}
+
+	if cfg.Tracing.Enabled {
+		switch cfg.Tracing.Exporter {
+		case "otlp", "stdout":
+		case "file":
+			if cfg.Tracing.File == "" {
+				add("tracing.file", "required with the file exporter")
+			}
+		default:
+			add("tracing.exporter", "%q must be otlp, stdout or file", cfg.Tracing.Exporter)
+		}
+	}
+	if r := cfg.Tracing.SampleRatio; r < 0 || r > 1 {
+		add("tracing.sampleRatio", "%v must be between 0 and 1", r)
+	}
+	for i, dir := range cfg.Sync.AllowedDirs {
+		if !filepath.IsAbs(dir) {
+			add(fmt.Sprintf("sync.allowedDirs[%d]", i), "%q must be an absolute path", dir)
+		}
+	}
+	return p
+}
+
+func isBackend(cfg Config, name string) bool {
+	_, ok := cfg.Backend(name)
+	return ok
+}
+
+// validateBackend -- the settings of one S3 backend, reported under prefix
+func validateBackend(prefix string, s3 S3Config, vault bool) Problems {
+	var p Problems
+	add := func(path, format string, args ...any) {
+		p = append(p, prefix+"."+path+": "+fmt.Sprintf(format, args...))
+	}
+
+	switch {
+	case s3.Endpoint == "":
+		add("endpoint", "required, e.g. \"localhost:9000\"")
+	case strings.Contains(s3.Endpoint, "://"):
+		u, err := url.Parse(s3.Endpoint)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			add("endpoint", "%q must be host[:port] without a scheme", s3.Endpoint)
+			break
+		}
+		add("endpoint", "%q must be host[:port] without a scheme, write %q and set useSSL: %t",
+			s3.Endpoint, u.Host, u.Scheme == "https")
+	default:
+		host, port, err := net.SplitHostPort(s3.Endpoint)
+		if err != nil {
+			// no port, the scheme's default is used
+			host, port = s3.Endpoint, ""
+		}
+		if host == "" || strings.ContainsAny(host, "/ ") {
+			add("endpoint", "%q must be host[:port], e.g. \"s3.us-east-1.amazonaws.com\"", s3.Endpoint)
+		} else if n, err := strconv.Atoi(port); port != "" && (err != nil || n < 1 || n > 65535) {
+			add("endpoint", "port %q must be a number from 1 to 65535", port)
+		} else if port == "443" && !s3.UseSSL {
+			add("useSSL", "false with endpoint port 443, set useSSL: true to connect over TLS")
+		}
+	}
+	if s3.Region != "" && !regionPattern.MatchString(s3.Region) {
+		add("region", "%q is not a region name, e.g. \"us-east-1\"", s3.Region)
+	}
+	if s3.AccessKey == "" || s3.SecretKey == "" {
+		add("accessKey", "accessKey and secretKey are required, as values or secret references")
+	}
+	if !vault {
+		for _, key := range []struct{ path, value string }{{"accessKey", s3.AccessKey}, {"secretKey", s3.SecretKey}} {
+			if strings.HasPrefix(key.value, "vault://") {
+				add(key.path, "vault reference without secrets.vault.address")
+			}
+		}
+	}
+	return p
+}
+
+var unknownField = regexp.MustCompile(`^(line \d+): field (\S+) not found in type config\.(\w+)$`)
+
+// unknownFields -- rewrites the decoder's unknown field errors with the section and
+// the closest known field, typos are the usual cause
+func unknownFields(errs []string) Problems {
+	sections := make(map[string]reflect.Type)
+	var walk func(t reflect.Type)
+	walk = func(t reflect.Type) {
+		sections[t.Name()] = t
+		for i := 0; i < t.NumField(); i++ {
+			ft := t.Field(i).Type
+			if ft.Kind() == reflect.Slice {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct && ft.PkgPath() == t.PkgPath() {
+				walk(ft)
+			}
+		}
+	}
+	walk(reflect.TypeOf(Config{}))
+
+	var p Problems
+	for _, e := range errs {
+		m := unknownField.FindStringSubmatch(e)
+		if m == nil || sections[m[3]] == nil {
+			p = append(p, e)
+			continue
+		}
+		msg := fmt.Sprintf("%s: unknown field %q", m[1], m[2])
+		if guess := closest(m[2], sections[m[3]]); guess != "" {
+			msg += fmt.Sprintf(", did you mean %q?", guess)
+		}
+		p = append(p, msg)
+	}
+	return p
+}
+
+// closest -- the field of t whose yaml name is nearest to name, if any is close
+func closest(name string, t reflect.Type) string {
+	best, bestDist := "", 3
+	for i := 0; i < t.NumField(); i++ {
+		field := strings.Split(t.Field(i).Tag.Get("yaml"), ",")[0]
+		if d := distance(strings.ToLower(name), strings.ToLower(field)); d < bestDist {
+			best, bestDist = field, d
+		}
+	}
+	return best
+}
+
+// distance -- Levenshtein edit distance
+func distance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
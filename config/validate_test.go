@@ -0,0 +1,66 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+// validConfig -- a minimal configuration with defaults applied, it has no problems
+func validConfig() Config {
+	cfg := Config{S3: S3Config{Endpoint: "localhost:9000", AccessKey: "a", SecretKey: "b", Region: "us-east-1"}}
+	cfg.Resilience.Retries = -1
+	applyDefaults(&cfg)
+	return cfg
+}
+
+func TestValidate(t *testing.T) {
+	if p := Validate(validConfig()); len(p) != 0 {
+		t.Fatalf("the minimal config has problems: %v", p)
+	}
+
+	tests := []struct {
+		name   string
+		change func(*Config)
+		want   string
+	}{
+		{"server address", func(c *Config) { c.Server.Address = "8080" }, "server.address"},
+		{"endpoint with scheme", func(c *Config) { c.S3.Endpoint = "https://s3.example.com" },
+			`write "s3.example.com" and set useSSL: true`},
+		{"endpoint port", func(c *Config) { c.S3.Endpoint = "minio:70000" }, "port \"70000\" must be a number"},
+		{"tls port without ssl", func(c *Config) { c.S3.Endpoint = "minio:443" }, "s3.useSSL"},
+		{"region", func(c *Config) { c.S3.Region = "us east" }, "s3.region"},
+		{"missing keys", func(c *Config) { c.S3.SecretKey = "" }, "s3.accessKey: accessKey and secretKey are required"},
+		{"vault without address", func(c *Config) { c.S3.SecretKey = "vault://kv/s3#key" }, "s3.secretKey: vault reference"},
+		{"vault token from vault", func(c *Config) {
+			c.Secrets.Vault = VaultConfig{Address: "https://vault:8200", Token: "vault://kv/token#t"}
+		}, "secrets.vault.token"},
+		{"backend named default", func(c *Config) { c.Backends = map[string]S3Config{DefaultBackend: c.S3} }, "backends.default: the name is taken"},
+		{"backend problems", func(c *Config) { c.Backends = map[string]S3Config{"dr": {Endpoint: "dr:9000"}} }, "backends.dr.accessKey"},
+		{"route to unknown backend", func(c *Config) { c.Routing.Buckets = map[string]string{"logs": "cold"} }, `routing.buckets.logs: unknown backend "cold"`},
+		{"bad route pattern", func(c *Config) { c.Routing.Patterns = []RoutePattern{{Pattern: "[", Backend: DefaultBackend}} }, "routing.patterns[0].pattern"},
+		{"unknown required backend", func(c *Config) { c.Health.RequiredBackends = []string{"dr"} }, "health.requiredBackends[0]"},
+		{"log level", func(c *Config) { c.Logging.Level = "loud" }, "logging.level"},
+		{"negative upload limit", func(c *Config) { c.Limits.MaxUploadBytes = -1 }, "limits.maxUploadBytes"},
+		{"lifecycle mode", func(c *Config) { c.Lifecycle.Mode = "manual" }, "lifecycle.mode"},
+		{"envelope without key", func(c *Config) { c.Envelope.Enabled = true }, "envelope.keyFile"},
+		{"credentials from every origin", func(c *Config) {
+			c.CORS.AllowedOrigins, c.CORS.AllowCredentials = []string{"*"}, true
+		}, "cors.allowCredentials"},
+		{"duplicate sink", func(c *Config) {
+			c.Events.Sinks = []SinkConfig{{Name: "q", Type: "nats", URL: "nats://a"}, {Name: "q", Type: "nats", URL: "nats://b"}}
+		}, `events.sinks[1].name: "q" is used by another sink`},
+		{"sink type", func(c *Config) { c.Events.Sinks = []SinkConfig{{Name: "q", Type: "sqs", URL: "x"}} }, "events.sinks[0].type"},
+		{"sample ratio", func(c *Config) { c.Tracing.SampleRatio = 2 }, "tracing.sampleRatio"},
+		{"relative sync dir", func(c *Config) { c.Sync.AllowedDirs = []string{"exports"} }, "sync.allowedDirs[0]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig()
+			tt.change(&cfg)
+			p := Validate(cfg)
+			if len(p) != 1 || !strings.Contains(p[0], tt.want) {
+				t.Errorf("got %v, want one problem containing %q", p, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,140 @@
+// Package cors matches browser requests against CORS rules, the gateway-wide ones
+// from config.yaml and the per-bucket ones kept in the data directory.
+package cors
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"kluisz-object-storage/config"
+	"kluisz-object-storage/jsonstore"
+	"kluisz-object-storage/models"
+)
+
+const maxRules = 100
+
+var methods = map[string]bool{
+	http.MethodGet:    true,
+	http.MethodHead:   true,
+	http.MethodPost:   true,
+	http.MethodPut:    true,
+	http.MethodDelete: true,
+}
+
+// Rules -- per-bucket CORS rules, set up by LoadRules
+var Rules *jsonstore.Store[models.CORSConfiguration]
+
+func LoadRules() error {
+	s, err := jsonstore.Open[models.CORSConfiguration](filepath.Join(config.Get().DataDir, "cors.json"))
+	if err != nil {
+		return err
+	}
+	Rules = s
+	return nil
+}
+
+// Validate -- checks a bucket CORS configuration, reporting every problem found
+func Validate(cfg models.CORSConfiguration) error {
+	if len(cfg.Rules) == 0 {
+		return errors.New("CORS configuration must contain at least one rule")
+	}
+	var errs []error
+	if len(cfg.Rules) > maxRules {
+		errs = append(errs, fmt.Errorf("CORS configuration has %d rules, at most %d are allowed", len(cfg.Rules), maxRules))
+	}
+	for i, r := range cfg.Rules {
+		fail := func(format string, args ...any) {
+			errs = append(errs, fmt.Errorf("rule %d: %s", i, fmt.Sprintf(format, args...)))
+		}
+		if len(r.AllowedOrigins) == 0 {
+			fail("allowedOrigins is required")
+		}
+		for _, o := range r.AllowedOrigins {
+			if strings.Count(o, "*") > 1 {
+				fail("origin %q may contain at most one wildcard", o)
+			}
+			if r.AllowCredentials && anyOrigin(o) {
+				fail("origin %q matches every site, it cannot be combined with allowCredentials", o)
+			}
+		}
+		if len(r.AllowedMethods) == 0 {
+			fail("allowedMethods is required")
+		}
+		for _, m := range r.AllowedMethods {
+			if !methods[m] {
+				fail("method %q is not one of GET, HEAD, POST, PUT, DELETE", m)
+			}
+		}
+		for _, h := range r.AllowedHeaders {
+			if strings.Count(h, "*") > 1 {
+				fail("header %q may contain at most one wildcard", h)
+			}
+		}
+		if r.MaxAgeSeconds < 0 {
+			fail("maxAgeSeconds must not be negative")
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// anyOrigin -- the origin pattern matches every site, like "*" or "https://*"
+func anyOrigin(pattern string) bool {
+	before, after, wild := strings.Cut(pattern, "*")
+	return wild && after == "" && (before == "" || strings.HasSuffix(before, "://"))
+}
+
+// Match -- the first rule allowing the origin, method and every requested header
+func Match(rules []models.CORSRule, origin, method string, headers []string) *models.CORSRule {
+	for i := range rules {
+		r := &rules[i]
+		if !matchAny(r.AllowedOrigins, origin, false) || !contains(r.AllowedMethods, method) {
+			continue
+		}
+		allowed := true
+		for _, h := range headers {
+			if !matchAny(r.AllowedHeaders, h, true) {
+				allowed = false
+				break
+			}
+		}
+		if allowed {
+			return r
+		}
+	}
+	return nil
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// matchAny -- patterns may hold a single "*" wildcard; header names compare case-insensitively
+func matchAny(patterns []string, s string, fold bool) bool {
+	if fold {
+		s = strings.ToLower(s)
+	}
+	for _, p := range patterns {
+		if fold {
+			p = strings.ToLower(p)
+		}
+		before, after, wild := strings.Cut(p, "*")
+		if !wild {
+			if p == s {
+				return true
+			}
+			continue
+		}
+		if len(s) >= len(before)+len(after) && strings.HasPrefix(s, before) && strings.HasSuffix(s, after) {
+			return true
+		}
+	}
+	return false
+}
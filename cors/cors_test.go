@@ -0,0 +1,94 @@
+package cors
+
+import (
+	"strings"
+	"testing"
+
+	"kluisz-object-storage/models"
+)
+
+func TestMatch(t *testing.T) {
+	rules := []models.CORSRule{
+		{ID: "app", AllowedOrigins: []string{"https://app.example.com"}, AllowedMethods: []string{"GET", "PUT"},
+			AllowedHeaders: []string{"Content-Type", "x-amz-meta-*"}},
+		{ID: "sub", AllowedOrigins: []string{"https://*.example.com"}, AllowedMethods: []string{"GET"}},
+		{ID: "any", AllowedOrigins: []string{"*"}, AllowedMethods: []string{"HEAD"}},
+	}
+	tests := []struct {
+		name    string
+		origin  string
+		method  string
+		headers []string
+		want    string
+	}{
+		{"exact origin", "https://app.example.com", "PUT", nil, "app"},
+		{"headers case-insensitive", "https://app.example.com", "PUT", []string{"content-type", "X-Amz-Meta-Owner"}, "app"},
+		{"header not allowed falls through", "https://app.example.com", "GET", []string{"Authorization"}, ""},
+		{"first matching rule wins", "https://app.example.com", "GET", nil, "app"},
+		{"wildcard subdomain", "https://cdn.example.com", "GET", nil, "sub"},
+		{"wildcard matches an empty run too", "https://.example.com", "GET", nil, "sub"},
+		{"wildcard does not match the suffix alone", "https://example.com", "GET", nil, ""},
+		{"other scheme", "http://cdn.example.com", "GET", nil, ""},
+		{"method not allowed", "https://cdn.example.com", "DELETE", nil, ""},
+		{"method is case-sensitive", "https://cdn.example.com", "get", nil, ""},
+		{"any origin", "https://evil.test", "HEAD", nil, "any"},
+		{"origin is case-sensitive", "https://APP.example.com", "PUT", nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ""
+			if r := Match(rules, tt.origin, tt.method, tt.headers); r != nil {
+				got = r.ID
+			}
+			if got != tt.want {
+				t.Errorf("matched rule %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	rule := func(change func(*models.CORSRule)) models.CORSConfiguration {
+		r := models.CORSRule{AllowedOrigins: []string{"https://app.example.com"}, AllowedMethods: []string{"GET"}}
+		change(&r)
+		return models.CORSConfiguration{Rules: []models.CORSRule{r}}
+	}
+	tests := []struct {
+		name string
+		cfg  models.CORSConfiguration
+		want string
+	}{
+		{"valid", rule(func(r *models.CORSRule) {}), ""},
+		{"credentials for listed origins", rule(func(r *models.CORSRule) { r.AllowCredentials = true }), ""},
+		{"credentials for a subdomain wildcard", rule(func(r *models.CORSRule) {
+			r.AllowedOrigins, r.AllowCredentials = []string{"https://*.example.com"}, true
+		}), ""},
+		{"no rules", models.CORSConfiguration{}, "at least one rule"},
+		{"no origins", rule(func(r *models.CORSRule) { r.AllowedOrigins = nil }), "allowedOrigins is required"},
+		{"two wildcards", rule(func(r *models.CORSRule) { r.AllowedOrigins = []string{"https://*.*.com"} }), "at most one wildcard"},
+		{"credentials from every origin", rule(func(r *models.CORSRule) {
+			r.AllowedOrigins, r.AllowCredentials = []string{"*"}, true
+		}), "cannot be combined with allowCredentials"},
+		{"credentials from every https origin", rule(func(r *models.CORSRule) {
+			r.AllowedOrigins, r.AllowCredentials = []string{"https://*"}, true
+		}), "cannot be combined with allowCredentials"},
+		{"method", rule(func(r *models.CORSRule) { r.AllowedMethods = []string{"PATCH"} }), `method "PATCH"`},
+		{"header wildcards", rule(func(r *models.CORSRule) { r.AllowedHeaders = []string{"x-*-*"} }), "at most one wildcard"},
+		{"max age", rule(func(r *models.CORSRule) { r.MaxAgeSeconds = -1 }), "maxAgeSeconds"},
+		{"too many rules", models.CORSConfiguration{Rules: make([]models.CORSRule, maxRules+1)}, "at most"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.cfg)
+			if tt.want == "" {
+				if err != nil {
+					t.Errorf("unexpected error %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("got %v, want an error containing %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -18,31 +18,113 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/bucket": {
+        "/archive/{bucket}": {
+            "get": {
+                "description": "The archive is streamed as it is built. Paths are the keys without the prefix's parent folders, so downloading \"photos/2024/\" gives 2024/...; folder marker objects are left out. The last entry, _manifest.json, lists the objects that could not be read.",
+                "produces": [
+                    "application/zip",
+                    "application/gzip"
+                ],
+                "tags": [
+                    "objects"
+                ],
+                "summary": "Download every object under a prefix as one ZIP or tar.gz archive",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bucket name",
+                        "name": "bucket",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Key prefix, the whole bucket without it",
+                        "name": "prefix",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "zip (default) or tar.gz",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "The archive",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse400"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse404"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse500"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse503"
+                        }
+                    },
+                    "504": {
+                        "description": "Gateway Timeout",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse504"
+                        }
+                    }
+                }
+            },
             "post": {
+                "description": "Like GET /archive/{bucket}; listed keys keep their full path. Objects that are missing or would take the archive past archive.maxBytes are left out and named in _manifest.json.",
                 "consumes": [
                     "application/json"
                 ],
+                "produces": [
+                    "application/zip",
+                    "application/gzip"
+                ],
                 "tags": [
-                    "buckets"
+                    "objects"
                 ],
-                "summary": "Create a new S3 bucket",
+                "summary": "Download the listed objects, or a prefix, as one ZIP or tar.gz archive",
                 "parameters": [
                     {
-                        "description": "Bucket name payload",
+                        "type": "string",
+                        "description": "Bucket name",
+                        "name": "bucket",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Objects to download",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.CreateBucketRequest"
+                            "$ref": "#/definitions/models.ArchiveRequest"
                         }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "OK",
+                        "description": "The archive",
                         "schema": {
-                            "$ref": "#/definitions/models.BucketResponseC"
+                            "type": "file"
                         }
                     },
                     "400": {
@@ -51,83 +133,155 @@ const docTemplate = `{
                             "$ref": "#/definitions/models.ErrorResponse400"
                         }
                     },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse404"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse500"
                         }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse503"
+                        }
+                    },
+                    "504": {
+                        "description": "Gateway Timeout",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse504"
+                        }
                     }
                 }
             }
         },
-        "/bucket/{bucket}": {
-            "delete": {
+        "/batch/jobs": {
+            "post": {
+                "description": "The manifest is an object of CSV lines bucket,key[,versionId] or JSON lines {\"bucket\",\"key\",\"versionId\"}. Operations: copy, delete, tag (replace the tag set), metadata (replace user metadata) and restore (make the listed version current again). Runs as a background job, see GET /jobs/{id}; the per-object report is stored as \u003creport.prefix\u003ebatch-\u003cjob id\u003e.csv in the report bucket.",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "buckets"
+                    "jobs"
                 ],
-                "summary": "Delete an existing S3 bucket",
+                "summary": "Apply one operation to every object listed in a manifest",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Bucket name",
-                        "name": "bucket",
-                        "in": "path",
-                        "required": true
+                        "description": "Batch job",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.BatchRequest"
+                        }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "status message",
+                    "202": {
+                        "description": "Accepted",
                         "schema": {
-                            "$ref": "#/definitions/models.BucketResponseD"
+                            "$ref": "#/definitions/models.Job"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse400"
                         }
                     },
                     "500": {
-                        "description": "error message",
+                        "description": "Internal Server Error",
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse500"
                         }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse503"
+                        }
+                    },
+                    "504": {
+                        "description": "Gateway Timeout",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse504"
+                        }
                     }
                 }
             }
         },
-        "/buckets": {
-            "get": {
-                "produces": [
+        "/bucket": {
+            "post": {
+                "consumes": [
                     "application/json"
                 ],
                 "tags": [
                     "buckets"
                 ],
-                "summary": "List all available S3 buckets",
+                "summary": "Create a new S3 bucket",
+                "parameters": [
+                    {
+                        "description": "Bucket name payload",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.CreateBucketRequest"
+                        }
+                    }
+                ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.ListBucketsResponse"
+                            "$ref": "#/definitions/models.BucketResponseC"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse400"
                         }
                     },
                     "500": {
-                        "description": "error message",
+                        "description": "Internal Server Error",
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse500"
                         }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse503"
+                        }
+                    },
+                    "504": {
+                        "description": "Gateway Timeout",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse504"
+                        }
                     }
                 }
             }
         },
-        "/download/{bucket}/{key}": {
-            "get": {
+        "/bucket/{bucket}": {
+            "delete": {
+                "description": "Only an empty bucket is deleted, unless force is set: then a background job deletes every object, version and incomplete upload first, see GET /jobs/{id}. Its lifecycle and CORS rules, replication rules and webhooks are removed with it",
                 "produces": [
-                    "application/octet-stream"
+                    "application/json"
                 ],
                 "tags": [
-                    "files"
+                    "buckets"
                 ],
-                "summary": "Download a file from a bucket",
+                "summary": "Delete an existing S3 bucket",
                 "parameters": [
                     {
                         "type": "string",
@@ -137,50 +291,60 @@ const docTemplate = `{
                         "required": true
                     },
                     {
-                        "type": "string",
-                        "description": "Object key",
-                        "name": "key",
-                        "in": "path",
-                        "required": true
+                        "type": "boolean",
+                        "description": "Delete the bucket's contents too",
+                        "name": "force",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "File downloaded",
+                        "description": "status message",
                         "schema": {
-                            "type": "file"
+                            "$ref": "#/definitions/models.BucketResponseD"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "202": {
+                        "description": "with force",
                         "schema": {
-                            "$ref": "#/definitions/models.ErrorResponse404"
+                            "$ref": "#/definitions/models.Job"
                         }
                     },
                     "500": {
-                        "description": "Internal Server Error",
+                        "description": "error message",
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse500"
                         }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse503"
+                        }
+                    },
+                    "504": {
+                        "description": "Gateway Timeout",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse504"
+                        }
                     }
                 }
             }
         },
-        "/objects/{bucket}": {
+        "/bucket/{name}/cors": {
             "get": {
-                "description": "Lists all object names in a specified bucket",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "objects"
+                    "buckets"
                 ],
-                "summary": "List objects in a bucket",
+                "summary": "Get the CORS rules of a bucket",
                 "parameters": [
                     {
                         "type": "string",
                         "description": "Bucket name",
-                        "name": "bucket",
+                        "name": "name",
                         "in": "path",
                         "required": true
                     }
@@ -189,46 +353,58 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.ListObjectsResponse"
+                            "$ref": "#/definitions/models.CORSResponse"
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
-                            "$ref": "#/definitions/models.ErrorResponse500"
+                            "$ref": "#/definitions/models.ErrorResponse404"
                         }
                     }
                 }
-            }
-        },
-        "/objects/{bucket}/{file}": {
-            "delete": {
-                "description": "Deletes a specified file from a given bucket",
+            },
+            "put": {
+                "description": "The rules apply to the bucket's object routes (upload, download, objects, public) instead of the gateway-wide CORS settings",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
                 "tags": [
-                    "objects"
+                    "buckets"
                 ],
-                "summary": "Delete a file from a bucket",
+                "summary": "Create or replace the CORS rules of a bucket",
                 "parameters": [
                     {
                         "type": "string",
                         "description": "Bucket name",
-                        "name": "bucket",
+                        "name": "name",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "type": "string",
-                        "description": "File name",
-                        "name": "file",
-                        "in": "path",
-                        "required": true
+                        "description": "CORS rules",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.CORSConfiguration"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.DeleteObjectResponse"
+                            "$ref": "#/definitions/models.CORSResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse400"
                         }
                     },
                     "500": {
@@ -238,47 +414,2271 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/upload/{bucket}": {
-            "post": {
-                "consumes": [
-                    "multipart/form-data"
-                ],
+            },
+            "delete": {
                 "produces": [
-                    "text/plain"
+                    "application/json"
                 ],
                 "tags": [
-                    "files"
+                    "buckets"
                 ],
-                "summary": "Upload a file to a given bucket",
+                "summary": "Remove the CORS rules of a bucket, the gateway-wide settings apply again",
                 "parameters": [
                     {
                         "type": "string",
                         "description": "Bucket name",
-                        "name": "bucket",
+                        "name": "name",
                         "in": "path",
                         "required": true
-                    },
-                    {
-                        "type": "file",
-                        "description": "File to upload",
-                        "name": "file",
-                        "in": "formData",
-                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.UploadFileResponse"
-                        }
-                    },
-                    "400": {
-                        "description": "Bad Request",
-                        "schema": {
-                            "$ref": "#/definitions/models.ErrorResponse400"
+                            "$ref": "#/definitions/models.BucketResponseD"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse500"
+                        }
+                    }
+                }
+            }
+        },
+        "/bucket/{name}/encryption": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "buckets"
+                ],
+                "summary": "Get the default encryption of a bucket",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bucket name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.BucketEncryptionResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse404"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse500"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse503"
+                        }
+                    },
+                    "504": {
+                        "description": "Gateway Timeout",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse504"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "New objects are encrypted at rest with SSE-S3 (AES256) or SSE-KMS (aws:kms) even when uploads carry no encryption headers",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "buckets"
+                ],
+                "summary": "Set the default encryption of a bucket",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bucket name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Default encryption",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.BucketEncryptionRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.BucketEncryptionResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse400"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse500"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse503"
+                        }
+                    },
+                    "504": {
+                        "description": "Gateway Timeout",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse504"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Objects already stored stay encrypted",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "buckets"
+                ],
+                "summary": "Remove the default encryption of a bucket",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bucket name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.BucketResponseD"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse500"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse503"
+                        }
+                    },
+                    "504": {
+                        "description": "Gateway Timeout",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse504"
+                        }
+                    }
+                }
+            }
+        },
+        "/bucket/{name}/lifecycle": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "buckets"
+                ],
+                "summary": "Get the lifecycle configuration of a bucket",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bucket name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.LifecycleResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse404"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse500"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse503"
+                        }
+                    },
+                    "504": {
+                        "description": "Gateway Timeout",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse504"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Rules are sent to the backend; when it has no lifecycle support they are stored and enforced by the gateway (transitions excepted)",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "buckets"
+                ],
+                "summary": "Create or replace the lifecycle configuration of a bucket",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bucket name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Lifecycle rules",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.LifecycleConfiguration"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.LifecycleMessageResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse400"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse500"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse503"
+                        }
+                    },
+                    "504": {
+                        "description": "Gateway Timeout",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse504"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "buckets"
+                ],
+                "summary": "Remove the lifecycle configuration of a bucket",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bucket name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.LifecycleMessageResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse500"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse503"
+                        }
+                    },
+                    "504": {
+                        "description": "Gateway Timeout",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse504"
+                        }
+                    }
+                }
+            }
+        },
+        "/bucket/{name}/object-lock": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "buckets"
+                ],
+                "summary": "Get the object lock configuration and default retention of a bucket",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bucket name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.ObjectLockConfigResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse500"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse503"
+                        }
+                    },
+                    "504": {
+                        "description": "Gateway Timeout",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse504"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "buckets"
+                ],
+                "summary": "Set or remove the default retention of a bucket created with object locking",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bucket name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Default retention, empty to remove it",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.ObjectLockConfigRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.ObjectLockConfigResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse400"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse500"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse503"
+                        }
+                    },
+                    "504": {
+                        "description": "Gateway Timeout",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse504"
+                        }
+                    }
+                }
+            }
+        },
+        "/bucket/{name}/policy": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "buckets"
+                ],
+                "summary": "Get the policy of a bucket as raw JSON",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bucket name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/policy.Document"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse404"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse500"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse503"
+                        }
+                    },
+                    "504": {
+                        "description": "Gateway Timeout",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse504"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "The policy is validated before it is sent to the backend; every problem found is reported",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "buckets"
+                ],
+                "summary": "Replace the policy of a bucket",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bucket name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Bucket policy",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/policy.Document"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.PublicReadResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse400"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse500"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse503"
+                        }
+                    },
+                    "504": {
+                        "description": "Gateway Timeout",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse504"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "buckets"
+                ],
+                "summary": "Remove the policy of a bucket",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bucket name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.BucketResponseD"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse500"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse503"
+                        }
+                    },
+                    "504": {
+                        "description": "Gateway Timeout",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse504"
+                        }
+                    }
+                }
+            }
+        },
+        "/bucket/{name}/policy/public-read": {
+            "put": {
+                "description": "Adds an anonymous s3:GetObject statement for the prefix to the bucket policy, other statements are kept",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "buckets"
+                ],
+                "summary": "Make objects below a prefix publicly readable",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bucket name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Prefix to publish",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.PublicReadRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.PublicReadResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse400"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse500"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse503"
+                        }
+                    },
+                    "504": {
+                        "description": "Gateway Timeout",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse504"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "buckets"
+                ],
+                "summary": "Stop publishing objects below a prefix",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bucket name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Prefix previously published",
+                        "name": "prefix",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.PublicReadResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse500"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse503"
+                        }
+                    },
+                    "504": {
+                        "description": "Gateway Timeout",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse504"
+                        }
+                    }
+                }
+            }
+        },
+        "/buckets": {
+            "get": {
+                "description": "Buckets of every configured backend; backends that cannot be listed are named in unavailable",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "buckets"
+                ],
+                "summary": "List all available S3 buckets",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.ListBucketsResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "error message",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse500"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse503"
+                        }
+                    },
+                    "504": {
+                        "description": "Gateway Timeout",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse504"
+                        }
+                    }
+                }
+            }
+        },
+        "/download/{bucket}/{key}": {
+            "get": {
+                "produces": [
+                    "application/octet-stream"
+                ],
+                "tags": [
+                    "files"
+                ],
+                "summary": "Download a file from a bucket",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bucket name",
+                        "name": "bucket",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Object key",
+                        "name": "key",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "AES256 when the object was uploaded with SSE-C",
+                        "name": "X-Amz-Server-Side-Encryption-Customer-Algorithm",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Base64 encoded SSE-C key used for the upload",
+                        "name": "X-Amz-Server-Side-Encryption-Customer-Key",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Base64 encoded MD5 of the SSE-C key",
+                        "name": "X-Amz-Server-Side-Encryption-Customer-Key-MD5",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Single byte range, e.g. bytes=0-1023",
+                        "name": "Range",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "File downloaded",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "206": {
+                        "description": "Requested range of the file",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse400"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse404"
+                        }
+                    },
+                    "416": {
+                        "description": "Requested Range Not Satisfiable",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse416"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse500"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse503"
+                        }
+                    },
+                    "504": {
+                        "description": "Gateway Timeout",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse504"
+                        }
+                    }
+                }
+            }
+        },
+        "/events/{bucket}": {
+            "get": {
+                "description": "Server-Sent Events stream of ObjectCreated and ObjectRemoved events, or a WebSocket of the same events as JSON messages when the request asks for an upgrade.\nReconnecting clients pass the last event ID they saw (Last-Event-ID header or lastEventId query) to receive what they missed; a Resync event means that ID is too old.\nChanges made through the gateway are always included, changes made directly on the backend when it supports bucket notifications.",
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "events"
+                ],
+                "summary": "Live object events for a bucket",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bucket name",
+                        "name": "bucket",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only keys under this prefix",
+                        "name": "prefix",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Resume after this event, same as the Last-Event-ID header",
+                        "name": "lastEventId",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/events.Event"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse404"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse500"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse503"
+                        }
+                    },
+                    "504": {
+                        "description": "Gateway Timeout",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse504"
+                        }
+                    }
+                }
+            }
+        },
+        "/health": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "Detailed health report with the status and latency of every dependency",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.HealthResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/models.HealthResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/healthz": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "Liveness probe, the process is up and serving",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.HealthResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/jobs": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "jobs"
+                ],
+                "summary": "List kept jobs, the most recent first",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Only jobs of this type",
+                        "name": "type",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only jobs with this status: queued, running, succeeded, failed or cancelled",
+                        "name": "status",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.ListJobsResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Types: copy (models.CopyJobParams), delete (models.DeleteJobParams), delete-bucket (models.DeleteBucketJobParams), sync (models.SyncRequest), replication-backfill (models.BackfillJobParams) and batch (models.BatchRequest). Jobs run a few at a time, the rest wait queued; progress and the result are shown by GET /jobs/{id}",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "jobs"
+                ],
+                "summary": "Start a bulk operation in the background",
+                "parameters": [
+                    {
+                        "description": "Job",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.JobRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/models.Job"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse400"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse500"
+                        }
+                    }
+                }
+            }
+        },
+        "/jobs/{id}": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "jobs"
+                ],
+                "summary": "Get a job's status, progress and result",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Job ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Job"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse404"
+                        }
+                    }
+                }
+            }
+        },
+        "/jobs/{id}/cancel": {
+            "post": {
+                "description": "A running job stops at its next object, what it did so far is not undone",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "jobs"
+                ],
+                "summary": "Cancel a queued or running job",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Job ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/models.Job"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse404"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse409"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse500"
+                        }
+                    }
+                }
+            }
+        },
+        "/objects/{bucket}": {
+            "get": {
+                "description": "Lists all object names in a specified bucket",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "objects"
+                ],
+                "summary": "List objects in a bucket",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bucket name",
+                        "name": "bucket",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.ListObjectsResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse500"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse503"
+                        }
+                    },
+                    "504": {
+                        "description": "Gateway Timeout",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse504"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Runs as a background job, see GET /jobs/{id}; to empty a whole bucket submit a delete job through POST /jobs",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "objects"
+                ],
+                "summary": "Delete every object under a prefix",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bucket name",
+                        "name": "bucket",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Key prefix",
+                        "name": "prefix",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/models.Job"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse400"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse500"
+                        }
+                    }
+                }
+            }
+        },
+        "/objects/{bucket}/{file}": {
+            "delete": {
+                "description": "Deletes a specified file from a given bucket",
+                "tags": [
+                    "objects"
+                ],
+                "summary": "Delete a file from a bucket",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bucket name",
+                        "name": "bucket",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "File name",
+                        "name": "file",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Version to delete permanently; without it a versioned bucket only gets a delete marker",
+                        "name": "versionId",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Delete a version under governance-mode retention",
+                        "name": "bypassGovernance",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.DeleteObjectResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse403"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse500"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse503"
+                        }
+                    },
+                    "504": {
+                        "description": "Gateway Timeout",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse504"
+                        }
+                    }
+                }
+            }
+        },
+        "/objects/{bucket}/{file}/legal-hold": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "objects"
+                ],
+                "summary": "Get the legal hold status of an object",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bucket name",
+                        "name": "bucket",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "File name",
+                        "name": "file",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Object version, latest when empty",
+                        "name": "versionId",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.LegalHoldResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse404"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse500"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse503"
+                        }
+                    },
+                    "504": {
+                        "description": "Gateway Timeout",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse504"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "objects"
+                ],
+                "summary": "Turn the legal hold of an object on or off",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bucket name",
+                        "name": "bucket",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "File name",
+                        "name": "file",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Legal hold status, ON or OFF",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.LegalHoldRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.LegalHoldResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse400"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse500"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse503"
+                        }
+                    },
+                    "504": {
+                        "description": "Gateway Timeout",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse504"
+                        }
+                    }
+                }
+            }
+        },
+        "/objects/{bucket}/{file}/retention": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "objects"
+                ],
+                "summary": "Get the retention of an object",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bucket name",
+                        "name": "bucket",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "File name",
+                        "name": "file",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Object version, latest when empty",
+                        "name": "versionId",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.ObjectRetentionResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse404"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse500"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse503"
+                        }
+                    },
+                    "504": {
+                        "description": "Gateway Timeout",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse504"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Compliance retention can only be extended; governance retention can be shortened with bypassGovernance",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "objects"
+                ],
+                "summary": "Set the retention of an object",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bucket name",
+                        "name": "bucket",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "File name",
+                        "name": "file",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Retention mode and date",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.ObjectRetentionRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.ObjectRetentionResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse400"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse403"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse500"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse503"
+                        }
+                    },
+                    "504": {
+                        "description": "Gateway Timeout",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse504"
+                        }
+                    }
+                }
+            }
+        },
+        "/public/{bucket}/{key}": {
+            "get": {
+                "description": "Served only when the bucket policy grants anonymous s3:GetObject on the key",
+                "produces": [
+                    "application/octet-stream"
+                ],
+                "tags": [
+                    "files"
+                ],
+                "summary": "Download an object without credentials",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bucket name",
+                        "name": "bucket",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Object key, may contain slashes",
+                        "name": "key",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Single byte range, e.g. bytes=0-1023",
+                        "name": "Range",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "File downloaded",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "206": {
+                        "description": "Requested range of the file",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse403"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse404"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse500"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse503"
+                        }
+                    },
+                    "504": {
+                        "description": "Gateway Timeout",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse504"
+                        }
+                    }
+                }
+            }
+        },
+        "/readyz": {
+            "get": {
+                "description": "Checks the config, the backends (list buckets within the health timeout) and free disk space for logs and local state. Only the backends in health.requiredBackends fail readiness, the others are listed with status warn.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "Readiness probe, fails while a dependency check fails or the gateway is shutting down",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.HealthResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/models.HealthResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/replication/rules": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "replication"
+                ],
+                "summary": "List replication rules",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.ListReplicationRulesResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "New and overwritten objects under the prefix are copied asynchronously after each upload; use the backfill endpoint for objects that already exist",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "replication"
+                ],
+                "summary": "Replicate a bucket's objects to another backend",
+                "parameters": [
+                    {
+                        "description": "Replication rule",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.ReplicationRuleRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.ReplicationRule"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse400"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse500"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse503"
+                        }
+                    },
+                    "504": {
+                        "description": "Gateway Timeout",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse504"
+                        }
+                    }
+                }
+            }
+        },
+        "/replication/rules/{id}": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "replication"
+                ],
+                "summary": "Get a replication rule",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Rule ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.ReplicationRule"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse404"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "replication"
+                ],
+                "summary": "Remove a replication rule, queued copies for it are dropped",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Rule ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.DeleteReplicationRuleResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse404"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse500"
+                        }
+                    }
+                }
+            }
+        },
+        "/replication/rules/{id}/backfill": {
+            "post": {
+                "description": "Lists the source bucket under the rule's prefix and queues every object that is missing at the target or differs from it; progress is shown by GET /replication/status and by the job",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "replication"
+                ],
+                "summary": "Copy the objects that existed before the rule, or were missed",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Rule ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/models.BackfillStartedResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse404"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse409"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse500"
+                        }
+                    }
+                }
+            }
+        },
+        "/replication/status": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "replication"
+                ],
+                "summary": "Lag, progress and failures of every replication rule",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.ReplicationStatus"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse500"
+                        }
+                    }
+                }
+            }
+        },
+        "/replication/status/{bucket}/{key}": {
+            "get": {
+                "description": "pending and failed come from the queue, otherwise the target is checked: replicated or missing",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "replication"
+                ],
+                "summary": "Replication state of one object for each rule covering it",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Source bucket",
+                        "name": "bucket",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Object key",
+                        "name": "key",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.ObjectReplicationStatus"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse500"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse503"
+                        }
+                    },
+                    "504": {
+                        "description": "Gateway Timeout",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse504"
+                        }
+                    }
+                }
+            }
+        },
+        "/sync/jobs": {
+            "post": {
+                "description": "Only new and changed objects are transferred, compared by size and modification time or, with checksum, by MD5; with delete, destination keys missing from the source are removed. Local directories must be under one of the configured sync.allowedDirs. Runs as a background job, whose progress and report are shown by GET /jobs/{id}",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sync"
+                ],
+                "summary": "Sync a local directory or a bucket prefix into a bucket",
+                "parameters": [
+                    {
+                        "description": "Sync job",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.SyncRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/models.Job"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse400"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse403"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse500"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse503"
+                        }
+                    },
+                    "504": {
+                        "description": "Gateway Timeout",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse504"
+                        }
+                    }
+                }
+            }
+        },
+        "/upload/{bucket}": {
+            "post": {
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "files"
+                ],
+                "summary": "Upload a file to a given bucket",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bucket name",
+                        "name": "bucket",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "file",
+                        "description": "File to upload",
+                        "name": "file",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Retention mode, GOVERNANCE or COMPLIANCE",
+                        "name": "retentionMode",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Retention end, RFC 3339",
+                        "name": "retainUntilDate",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Legal hold, ON or OFF",
+                        "name": "legalHold",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "AES256 (SSE-S3) or aws:kms (SSE-KMS)",
+                        "name": "X-Amz-Server-Side-Encryption",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "KMS key for aws:kms",
+                        "name": "X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "AES256 for a customer provided key (SSE-C)",
+                        "name": "X-Amz-Server-Side-Encryption-Customer-Algorithm",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Base64 encoded 256 bit SSE-C key",
+                        "name": "X-Amz-Server-Side-Encryption-Customer-Key",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Base64 encoded MD5 of the SSE-C key",
+                        "name": "X-Amz-Server-Side-Encryption-Customer-Key-MD5",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.UploadFileResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse400"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse403"
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse413"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse500"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse503"
+                        }
+                    },
+                    "504": {
+                        "description": "Gateway Timeout",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse504"
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "events"
+                ],
+                "summary": "List webhook subscriptions",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.ListWebhooksResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Deliveries are POSTed as JSON, signed with X-Gateway-Signature: sha256=HMAC(secret, timestamp + \".\" + body), and retried with backoff until acknowledged with a 2xx",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "events"
+                ],
+                "summary": "Subscribe a URL to bucket and object events",
+                "parameters": [
+                    {
+                        "description": "Webhook subscription",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.WebhookRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Webhook"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse400"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse500"
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks/{id}": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "events"
+                ],
+                "summary": "Get a webhook subscription",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Webhook ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Webhook"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse404"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "events"
+                ],
+                "summary": "Remove a webhook subscription, pending deliveries to it are dropped",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Webhook ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.DeleteWebhookResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse404"
                         }
                     },
                     "500": {
@@ -292,6 +2692,262 @@ const docTemplate = `{
         }
     },
     "definitions": {
+        "events.Event": {
+            "type": "object",
+            "properties": {
+                "bucket": {
+                    "type": "string",
+                    "example": "mybucket"
+                },
+                "contentType": {
+                    "type": "string",
+                    "example": "text/plain"
+                },
+                "etag": {
+                    "type": "string",
+                    "example": "abcd1234"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "6f1c2a9e-8f53-4f7e-9d0a-2b8b0c7d1e55"
+                },
+                "key": {
+                    "type": "string",
+                    "example": "file.txt"
+                },
+                "requestId": {
+                    "type": "string"
+                },
+                "size": {
+                    "type": "integer",
+                    "example": 1234
+                },
+                "source": {
+                    "description": "what caused the change: api, lifecycle, ...",
+                    "type": "string",
+                    "example": "api"
+                },
+                "time": {
+                    "type": "string"
+                },
+                "type": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/events.Type"
+                        }
+                    ],
+                    "example": "ObjectCreated"
+                },
+                "versionId": {
+                    "type": "string"
+                }
+            }
+        },
+        "events.Type": {
+            "type": "string",
+            "enum": [
+                "ObjectCreated",
+                "ObjectRemoved",
+                "BucketCreated",
+                "BucketRemoved"
+            ],
+            "x-enum-varnames": [
+                "ObjectCreated",
+                "ObjectRemoved",
+                "BucketCreated",
+                "BucketRemoved"
+            ]
+        },
+        "models.ArchiveRequest": {
+            "type": "object",
+            "properties": {
+                "format": {
+                    "type": "string",
+                    "example": "zip"
+                },
+                "keys": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "reports/q1.csv",
+                        "reports/q2.csv"
+                    ]
+                },
+                "prefix": {
+                    "type": "string",
+                    "example": "reports/"
+                }
+            }
+        },
+        "models.BackfillStartedResponse": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "string",
+                    "example": "0d9c8c1e-5b7a-4f0e-b7a5-1f2e3d4c5b6a"
+                },
+                "job": {
+                    "type": "string",
+                    "example": "9a7e5c3b-1d2f-4e6a-8b9c-0d1e2f3a4b5c"
+                },
+                "message": {
+                    "type": "string",
+                    "example": "Backfill started"
+                }
+            }
+        },
+        "models.BackfillStatus": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "finished": {
+                    "type": "string"
+                },
+                "job": {
+                    "type": "string",
+                    "example": "9a7e5c3b-1d2f-4e6a-8b9c-0d1e2f3a4b5c"
+                },
+                "queued": {
+                    "type": "integer",
+                    "example": 12
+                },
+                "running": {
+                    "type": "boolean",
+                    "example": false
+                },
+                "scanned": {
+                    "type": "integer",
+                    "example": 1000
+                },
+                "started": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.BatchManifest": {
+            "type": "object",
+            "properties": {
+                "bucket": {
+                    "type": "string",
+                    "example": "manifests"
+                },
+                "format": {
+                    "type": "string",
+                    "example": "csv"
+                },
+                "key": {
+                    "type": "string",
+                    "example": "retag-2024.csv"
+                }
+            }
+        },
+        "models.BatchOperation": {
+            "type": "object",
+            "properties": {
+                "contentType": {
+                    "type": "string",
+                    "example": "text/csv"
+                },
+                "destinationBucket": {
+                    "description": "copy: to this bucket, under destinationPrefix followed by the key",
+                    "type": "string",
+                    "example": "archive"
+                },
+                "destinationPrefix": {
+                    "type": "string",
+                    "example": "2024/"
+                },
+                "metadata": {
+                    "description": "metadata: replaces every object's user metadata, and the content type if set",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "tags": {
+                    "description": "tag: replaces every object's tag set",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "type": {
+                    "type": "string",
+                    "example": "tag"
+                }
+            }
+        },
+        "models.BatchReportLocation": {
+            "type": "object",
+            "properties": {
+                "bucket": {
+                    "type": "string",
+                    "example": "manifests"
+                },
+                "failedOnly": {
+                    "description": "only list the objects that failed",
+                    "type": "boolean",
+                    "example": false
+                },
+                "prefix": {
+                    "type": "string",
+                    "example": "reports/"
+                }
+            }
+        },
+        "models.BatchRequest": {
+            "type": "object",
+            "properties": {
+                "concurrency": {
+                    "description": "objects worked on at a time, default jobs.concurrency, at most jobs.maxConcurrency",
+                    "type": "integer",
+                    "example": 16
+                },
+                "manifest": {
+                    "$ref": "#/definitions/models.BatchManifest"
+                },
+                "operation": {
+                    "$ref": "#/definitions/models.BatchOperation"
+                },
+                "report": {
+                    "$ref": "#/definitions/models.BatchReportLocation"
+                }
+            }
+        },
+        "models.BucketEncryptionRequest": {
+            "type": "object",
+            "properties": {
+                "algorithm": {
+                    "type": "string",
+                    "example": "AES256"
+                },
+                "kmsKeyId": {
+                    "type": "string",
+                    "example": "my-minio-key"
+                }
+            }
+        },
+        "models.BucketEncryptionResponse": {
+            "type": "object",
+            "properties": {
+                "algorithm": {
+                    "type": "string",
+                    "example": "AES256"
+                },
+                "bucket": {
+                    "type": "string",
+                    "example": "mybucket"
+                },
+                "kmsKeyId": {
+                    "type": "string",
+                    "example": "my-minio-key"
+                }
+            }
+        },
         "models.BucketResponseC": {
             "type": "object",
             "properties": {
@@ -299,35 +2955,793 @@ const docTemplate = `{
                     "type": "string",
                     "example": "mybucket"
                 },
-                "message": {
+                "message": {
+                    "type": "string",
+                    "example": "Bucket created"
+                }
+            }
+        },
+        "models.BucketResponseD": {
+            "type": "object",
+            "properties": {
+                "bucket": {
+                    "type": "string",
+                    "example": "mybucket"
+                },
+                "message": {
+                    "type": "string",
+                    "example": "Bucket deleted"
+                }
+            }
+        },
+        "models.CORSConfiguration": {
+            "type": "object",
+            "properties": {
+                "rules": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.CORSRule"
+                    }
+                }
+            }
+        },
+        "models.CORSResponse": {
+            "type": "object",
+            "properties": {
+                "bucket": {
+                    "type": "string",
+                    "example": "mybucket"
+                },
+                "cors": {
+                    "$ref": "#/definitions/models.CORSConfiguration"
+                }
+            }
+        },
+        "models.CORSRule": {
+            "type": "object",
+            "properties": {
+                "allowCredentials": {
+                    "type": "boolean",
+                    "example": false
+                },
+                "allowedHeaders": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "Content-Type",
+                        "Range"
+                    ]
+                },
+                "allowedMethods": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "GET",
+                        "POST"
+                    ]
+                },
+                "allowedOrigins": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "https://app.example.com"
+                    ]
+                },
+                "exposeHeaders": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "ETag",
+                        "Content-Range"
+                    ]
+                },
+                "id": {
+                    "type": "string",
+                    "example": "webapp"
+                },
+                "maxAgeSeconds": {
+                    "type": "integer",
+                    "example": 600
+                }
+            }
+        },
+        "models.CheckResult": {
+            "type": "object",
+            "properties": {
+                "detail": {
+                    "type": "string",
+                    "example": "12.4 GiB free"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "latencyMs": {
+                    "type": "number",
+                    "example": 3.2
+                },
+                "status": {
+                    "type": "string",
+                    "example": "ok"
+                }
+            }
+        },
+        "models.CreateBucketRequest": {
+            "type": "object",
+            "properties": {
+                "bucketName": {
+                    "type": "string",
+                    "example": "mybucket"
+                },
+                "defaultRetention": {
+                    "$ref": "#/definitions/models.DefaultRetention"
+                },
+                "objectLocking": {
+                    "description": "object locking can only be enabled when the bucket is created, it also turns on versioning",
+                    "type": "boolean",
+                    "example": false
+                }
+            }
+        },
+        "models.DefaultRetention": {
+            "type": "object",
+            "properties": {
+                "days": {
+                    "type": "integer",
+                    "example": 0
+                },
+                "mode": {
+                    "type": "string",
+                    "example": "COMPLIANCE"
+                },
+                "years": {
+                    "type": "integer",
+                    "example": 7
+                }
+            }
+        },
+        "models.DeleteObjectResponse": {
+            "type": "object",
+            "properties": {
+                "bucket": {
+                    "type": "string",
+                    "example": "mybucket"
+                },
+                "file": {
+                    "type": "string",
+                    "example": "file.txt"
+                },
+                "message": {
+                    "type": "string",
+                    "example": "File deleted"
+                },
+                "versionId": {
+                    "type": "string",
+                    "example": "3HL4kqtJlcpXroDTDmjVBH40Nrjfkd"
+                }
+            }
+        },
+        "models.DeleteReplicationRuleResponse": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "string",
+                    "example": "0d9c8c1e-5b7a-4f0e-b7a5-1f2e3d4c5b6a"
+                },
+                "message": {
+                    "type": "string",
+                    "example": "Replication rule deleted"
+                }
+            }
+        },
+        "models.DeleteWebhookResponse": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "string",
+                    "example": "4b1d7c0e-2f0a-4c55-a1e3-8d5e6f7a9b10"
+                },
+                "message": {
+                    "type": "string",
+                    "example": "Webhook deleted"
+                }
+            }
+        },
+        "models.ErrorResponse400": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "integer",
+                    "example": 400
+                },
+                "error": {
+                    "type": "string",
+                    "example": "Bad request Error message"
+                }
+            }
+        },
+        "models.ErrorResponse403": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "integer",
+                    "example": 403
+                },
+                "error": {
+                    "type": "string",
+                    "example": "Forbidden Error message"
+                }
+            }
+        },
+        "models.ErrorResponse404": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "integer",
+                    "example": 404
+                },
+                "error": {
+                    "type": "string",
+                    "example": "Not Found Error message"
+                }
+            }
+        },
+        "models.ErrorResponse409": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "integer",
+                    "example": 409
+                },
+                "error": {
+                    "type": "string",
+                    "example": "Conflict Error message"
+                }
+            }
+        },
+        "models.ErrorResponse413": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "integer",
+                    "example": 413
+                },
+                "error": {
+                    "type": "string",
+                    "example": "Request Entity Too Large Error message"
+                }
+            }
+        },
+        "models.ErrorResponse416": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "integer",
+                    "example": 416
+                },
+                "error": {
+                    "type": "string",
+                    "example": "Range Not Satisfiable Error message"
+                }
+            }
+        },
+        "models.ErrorResponse500": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "integer",
+                    "example": 500
+                },
+                "error": {
+                    "type": "string",
+                    "example": "Internal Server Error message"
+                }
+            }
+        },
+        "models.ErrorResponse503": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "integer",
+                    "example": 503
+                },
+                "error": {
+                    "type": "string",
+                    "example": "Service Unavailable Error message"
+                }
+            }
+        },
+        "models.ErrorResponse504": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "integer",
+                    "example": 504
+                },
+                "error": {
+                    "type": "string",
+                    "example": "Gateway Timeout Error message"
+                }
+            }
+        },
+        "models.HealthResponse": {
+            "type": "object",
+            "properties": {
+                "checks": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/models.CheckResult"
+                    }
+                },
+                "status": {
+                    "type": "string",
+                    "example": "ok"
+                }
+            }
+        },
+        "models.Job": {
+            "type": "object",
+            "properties": {
+                "created": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "failures": {
+                    "description": "the first failed items, progress.failed counts all of them",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.JobFailure"
+                    }
+                },
+                "finished": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "9a7e5c3b-1d2f-4e6a-8b9c-0d1e2f3a4b5c"
+                },
+                "params": {
+                    "type": "object"
+                },
+                "progress": {
+                    "$ref": "#/definitions/models.JobProgress"
+                },
+                "restarts": {
+                    "description": "interrupted by a restart and run again from the start, this many times",
+                    "type": "integer",
+                    "example": 0
+                },
+                "result": {
+                    "description": "type specific, e.g. the report of a sync",
+                    "type": "object"
+                },
+                "started": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "running"
+                },
+                "type": {
+                    "type": "string",
+                    "example": "copy"
+                }
+            }
+        },
+        "models.JobFailure": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "Access Denied."
+                },
+                "item": {
+                    "type": "string",
+                    "example": "reports/q1.csv"
+                }
+            }
+        },
+        "models.JobProgress": {
+            "type": "object",
+            "properties": {
+                "bytes": {
+                    "type": "integer",
+                    "example": 1048576
+                },
+                "done": {
+                    "type": "integer",
+                    "example": 250
+                },
+                "failed": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "total": {
+                    "type": "integer",
+                    "example": 1000
+                }
+            }
+        },
+        "models.JobRequest": {
+            "type": "object",
+            "required": [
+                "type"
+            ],
+            "properties": {
+                "params": {
+                    "type": "object"
+                },
+                "type": {
+                    "type": "string",
+                    "example": "copy"
+                }
+            }
+        },
+        "models.LegalHoldRequest": {
+            "type": "object",
+            "properties": {
+                "status": {
+                    "type": "string",
+                    "example": "ON"
+                },
+                "versionId": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.LegalHoldResponse": {
+            "type": "object",
+            "properties": {
+                "bucket": {
+                    "type": "string",
+                    "example": "mybucket"
+                },
+                "file": {
+                    "type": "string",
+                    "example": "file.txt"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "ON"
+                },
+                "versionId": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.LifecycleConfiguration": {
+            "type": "object",
+            "properties": {
+                "rules": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.LifecycleRule"
+                    }
+                }
+            }
+        },
+        "models.LifecycleExpiration": {
+            "type": "object",
+            "properties": {
+                "date": {
+                    "type": "string",
+                    "example": "2026-01-01"
+                },
+                "days": {
+                    "type": "integer",
+                    "example": 30
+                }
+            }
+        },
+        "models.LifecycleFilter": {
+            "type": "object",
+            "properties": {
+                "objectSizeGreaterThan": {
+                    "type": "integer"
+                },
+                "objectSizeLessThan": {
+                    "type": "integer"
+                },
+                "prefix": {
+                    "type": "string",
+                    "example": "raw/"
+                },
+                "tags": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "models.LifecycleMessageResponse": {
+            "type": "object",
+            "properties": {
+                "bucket": {
+                    "type": "string",
+                    "example": "mybucket"
+                },
+                "message": {
+                    "type": "string",
+                    "example": "Lifecycle configuration saved"
+                },
+                "mode": {
+                    "type": "string",
+                    "example": "native"
+                }
+            }
+        },
+        "models.LifecycleResponse": {
+            "type": "object",
+            "properties": {
+                "bucket": {
+                    "type": "string",
+                    "example": "mybucket"
+                },
+                "lifecycle": {
+                    "$ref": "#/definitions/models.LifecycleConfiguration"
+                },
+                "mode": {
+                    "type": "string",
+                    "example": "native"
+                }
+            }
+        },
+        "models.LifecycleRule": {
+            "type": "object",
+            "properties": {
+                "abortIncompleteMultipartUploadDays": {
+                    "type": "integer",
+                    "example": 7
+                },
+                "expiration": {
+                    "$ref": "#/definitions/models.LifecycleExpiration"
+                },
+                "filter": {
+                    "description": "objects the rule applies to; an empty filter matches the whole bucket",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.LifecycleFilter"
+                        }
+                    ]
+                },
+                "id": {
+                    "type": "string",
+                    "example": "expire-raw"
+                },
+                "noncurrentVersionExpiration": {
+                    "$ref": "#/definitions/models.NoncurrentExpiration"
+                },
+                "noncurrentVersionTransition": {
+                    "$ref": "#/definitions/models.NoncurrentTransition"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "Enabled"
+                },
+                "transition": {
+                    "$ref": "#/definitions/models.LifecycleTransition"
+                }
+            }
+        },
+        "models.LifecycleTransition": {
+            "type": "object",
+            "properties": {
+                "date": {
+                    "type": "string",
+                    "example": "2026-01-01"
+                },
+                "days": {
+                    "type": "integer",
+                    "example": 30
+                },
+                "storageClass": {
+                    "type": "string",
+                    "example": "GLACIER"
+                }
+            }
+        },
+        "models.ListBucketsResponse": {
+            "type": "object",
+            "properties": {
+                "backends": {
+                    "description": "bucket names by backend, when several are configured",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        }
+                    }
+                },
+                "buckets": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "unavailable": {
+                    "description": "backends that could not be listed, their buckets are missing from the list",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "models.ListJobsResponse": {
+            "type": "object",
+            "properties": {
+                "jobs": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Job"
+                    }
+                }
+            }
+        },
+        "models.ListObjectsResponse": {
+            "type": "object",
+            "properties": {
+                "bucket": {
+                    "type": "string",
+                    "example": "mybucket"
+                },
+                "objects": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "models.ListReplicationRulesResponse": {
+            "type": "object",
+            "properties": {
+                "rules": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ReplicationRule"
+                    }
+                }
+            }
+        },
+        "models.ListWebhooksResponse": {
+            "type": "object",
+            "properties": {
+                "webhooks": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Webhook"
+                    }
+                }
+            }
+        },
+        "models.NoncurrentExpiration": {
+            "type": "object",
+            "properties": {
+                "newerNoncurrentVersions": {
+                    "type": "integer",
+                    "example": 0
+                },
+                "noncurrentDays": {
+                    "type": "integer",
+                    "example": 7
+                }
+            }
+        },
+        "models.NoncurrentTransition": {
+            "type": "object",
+            "properties": {
+                "noncurrentDays": {
+                    "type": "integer",
+                    "example": 7
+                },
+                "storageClass": {
+                    "type": "string",
+                    "example": "GLACIER"
+                }
+            }
+        },
+        "models.ObjectLockConfigRequest": {
+            "type": "object",
+            "properties": {
+                "defaultRetention": {
+                    "description": "leave empty to remove the default retention",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.DefaultRetention"
+                        }
+                    ]
+                }
+            }
+        },
+        "models.ObjectLockConfigResponse": {
+            "type": "object",
+            "properties": {
+                "bucket": {
+                    "type": "string",
+                    "example": "mybucket"
+                },
+                "defaultRetention": {
+                    "$ref": "#/definitions/models.DefaultRetention"
+                },
+                "enabled": {
+                    "type": "boolean",
+                    "example": true
+                }
+            }
+        },
+        "models.ObjectReplicationByRule": {
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "type": "integer",
+                    "example": 0
+                },
+                "error": {
+                    "type": "string"
+                },
+                "ruleId": {
                     "type": "string",
-                    "example": "Bucket created"
+                    "example": "0d9c8c1e-5b7a-4f0e-b7a5-1f2e3d4c5b6a"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "replicated"
                 }
             }
         },
-        "models.BucketResponseD": {
+        "models.ObjectReplicationStatus": {
             "type": "object",
             "properties": {
                 "bucket": {
                     "type": "string",
-                    "example": "mybucket"
+                    "example": "prod-data"
                 },
-                "message": {
+                "key": {
                     "type": "string",
-                    "example": "Bucket deleted"
+                    "example": "reports/q1.csv"
+                },
+                "rules": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ObjectReplicationByRule"
+                    }
                 }
             }
         },
-        "models.CreateBucketRequest": {
+        "models.ObjectRetentionRequest": {
             "type": "object",
             "properties": {
-                "bucketName": {
+                "bypassGovernance": {
+                    "description": "needed to shorten or remove a governance-mode retention",
+                    "type": "boolean",
+                    "example": false
+                },
+                "mode": {
                     "type": "string",
-                    "example": "mybucket"
+                    "example": "GOVERNANCE"
+                },
+                "retainUntilDate": {
+                    "type": "string",
+                    "example": "2032-01-01T00:00:00Z"
+                },
+                "versionId": {
+                    "type": "string"
                 }
             }
         },
-        "models.DeleteObjectResponse": {
+        "models.ObjectRetentionResponse": {
             "type": "object",
             "properties": {
                 "bucket": {
@@ -338,74 +3752,211 @@ const docTemplate = `{
                     "type": "string",
                     "example": "file.txt"
                 },
-                "message": {
+                "mode": {
                     "type": "string",
-                    "example": "File deleted"
+                    "example": "GOVERNANCE"
+                },
+                "retainUntilDate": {
+                    "type": "string",
+                    "example": "2032-01-01T00:00:00Z"
+                },
+                "versionId": {
+                    "type": "string"
                 }
             }
         },
-        "models.ErrorResponse400": {
+        "models.PublicReadRequest": {
             "type": "object",
             "properties": {
-                "code": {
+                "prefix": {
+                    "description": "objects below this prefix become readable without credentials, empty for the whole bucket",
+                    "type": "string",
+                    "example": "assets/"
+                }
+            }
+        },
+        "models.PublicReadResponse": {
+            "type": "object",
+            "properties": {
+                "bucket": {
+                    "type": "string",
+                    "example": "mybucket"
+                },
+                "publicPrefixes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "models.ReplicationFailure": {
+            "type": "object",
+            "properties": {
+                "attempts": {
                     "type": "integer",
-                    "example": 400
+                    "example": 10
+                },
+                "bucket": {
+                    "type": "string",
+                    "example": "prod-data"
                 },
                 "error": {
                     "type": "string",
-                    "example": "Bad request Error message"
+                    "example": "The specified bucket does not exist"
+                },
+                "key": {
+                    "type": "string",
+                    "example": "reports/q1.csv"
+                },
+                "permanent": {
+                    "type": "boolean",
+                    "example": true
+                },
+                "since": {
+                    "type": "string"
                 }
             }
         },
-        "models.ErrorResponse404": {
+        "models.ReplicationRule": {
             "type": "object",
             "properties": {
-                "code": {
-                    "type": "integer",
-                    "example": 404
+                "created": {
+                    "type": "string"
                 },
-                "error": {
+                "deletes": {
+                    "type": "boolean",
+                    "example": false
+                },
+                "id": {
                     "type": "string",
-                    "example": "Not Found Error message"
+                    "example": "0d9c8c1e-5b7a-4f0e-b7a5-1f2e3d4c5b6a"
+                },
+                "prefix": {
+                    "type": "string",
+                    "example": "reports/"
+                },
+                "sourceBucket": {
+                    "type": "string",
+                    "example": "prod-data"
+                },
+                "targetBackend": {
+                    "type": "string",
+                    "example": "dr"
+                },
+                "targetBucket": {
+                    "type": "string",
+                    "example": "prod-data"
                 }
             }
         },
-        "models.ErrorResponse500": {
+        "models.ReplicationRuleRequest": {
             "type": "object",
             "properties": {
-                "code": {
-                    "type": "integer",
-                    "example": 500
+                "deletes": {
+                    "description": "also remove objects from the target when they are deleted at the source",
+                    "type": "boolean",
+                    "example": false
                 },
-                "error": {
+                "prefix": {
                     "type": "string",
-                    "example": "Internal Server Error message"
+                    "example": "reports/"
+                },
+                "sourceBucket": {
+                    "type": "string",
+                    "example": "prod-data"
+                },
+                "targetBackend": {
+                    "type": "string",
+                    "example": "dr"
+                },
+                "targetBucket": {
+                    "type": "string",
+                    "example": "prod-data"
                 }
             }
         },
-        "models.ListBucketsResponse": {
+        "models.ReplicationStatus": {
             "type": "object",
             "properties": {
-                "buckets": {
+                "rules": {
                     "type": "array",
                     "items": {
-                        "type": "string"
+                        "$ref": "#/definitions/models.RuleStatus"
                     }
                 }
             }
         },
-        "models.ListObjectsResponse": {
+        "models.RuleStatus": {
             "type": "object",
             "properties": {
-                "bucket": {
-                    "type": "string",
-                    "example": "mybucket"
+                "backfill": {
+                    "$ref": "#/definitions/models.BackfillStatus"
                 },
-                "objects": {
+                "failed": {
+                    "type": "integer",
+                    "example": 0
+                },
+                "failures": {
                     "type": "array",
                     "items": {
-                        "type": "string"
+                        "$ref": "#/definitions/models.ReplicationFailure"
                     }
+                },
+                "lagSeconds": {
+                    "type": "number",
+                    "example": 1.5
+                },
+                "lastReplicated": {
+                    "type": "string"
+                },
+                "pending": {
+                    "type": "integer",
+                    "example": 3
+                },
+                "replicated": {
+                    "type": "integer",
+                    "example": 120
+                },
+                "rule": {
+                    "$ref": "#/definitions/models.ReplicationRule"
+                }
+            }
+        },
+        "models.SyncRequest": {
+            "type": "object",
+            "required": [
+                "destination",
+                "source"
+            ],
+            "properties": {
+                "checksum": {
+                    "description": "compare checksums instead of modification times",
+                    "type": "boolean",
+                    "example": false
+                },
+                "delete": {
+                    "description": "delete destination keys that are not in the source",
+                    "type": "boolean",
+                    "example": false
+                },
+                "destination": {
+                    "type": "string",
+                    "example": "s3://archive/reports/"
+                },
+                "dryRun": {
+                    "description": "only report what would be done",
+                    "type": "boolean",
+                    "example": true
+                },
+                "source": {
+                    "type": "string",
+                    "example": "s3://prod-data/reports/"
+                },
+                "workers": {
+                    "description": "files transferred at a time, default sync.workers, at most jobs.maxConcurrency",
+                    "type": "integer",
+                    "example": 8
                 }
             }
         },
@@ -431,6 +3982,148 @@ const docTemplate = `{
                 "size": {
                     "type": "integer",
                     "example": 1234
+                },
+                "versionId": {
+                    "description": "set when the bucket is versioned",
+                    "type": "string",
+                    "example": "3HL4kqtJlcpXroDTDmjVBH40Nrjfkd"
+                }
+            }
+        },
+        "models.Webhook": {
+            "type": "object",
+            "properties": {
+                "bucket": {
+                    "type": "string",
+                    "example": "mybucket"
+                },
+                "created": {
+                    "type": "string"
+                },
+                "events": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "ObjectCreated",
+                        "ObjectRemoved"
+                    ]
+                },
+                "id": {
+                    "type": "string",
+                    "example": "4b1d7c0e-2f0a-4c55-a1e3-8d5e6f7a9b10"
+                },
+                "prefix": {
+                    "type": "string",
+                    "example": "raw/"
+                },
+                "secret": {
+                    "type": "string"
+                },
+                "suffix": {
+                    "type": "string",
+                    "example": ".csv"
+                },
+                "url": {
+                    "type": "string",
+                    "example": "https://hooks.example.com/storage"
+                }
+            }
+        },
+        "models.WebhookRequest": {
+            "type": "object",
+            "properties": {
+                "bucket": {
+                    "type": "string",
+                    "example": "mybucket"
+                },
+                "events": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "ObjectCreated",
+                        "ObjectRemoved"
+                    ]
+                },
+                "prefix": {
+                    "type": "string",
+                    "example": "raw/"
+                },
+                "secret": {
+                    "description": "HMAC-SHA256 key for the X-Gateway-Signature header, generated when empty",
+                    "type": "string"
+                },
+                "suffix": {
+                    "type": "string",
+                    "example": ".csv"
+                },
+                "url": {
+                    "type": "string",
+                    "example": "https://hooks.example.com/storage"
+                }
+            }
+        },
+        "policy.Document": {
+            "type": "object",
+            "properties": {
+                "Id": {
+                    "type": "string"
+                },
+                "Statement": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/policy.Statement"
+                    }
+                },
+                "Version": {
+                    "type": "string"
+                }
+            }
+        },
+        "policy.Statement": {
+            "type": "object",
+            "properties": {
+                "Action": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "Condition": {
+                    "type": "object"
+                },
+                "Effect": {
+                    "type": "string"
+                },
+                "NotAction": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "NotPrincipal": {
+                    "type": "object"
+                },
+                "NotResource": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "Principal": {
+                    "type": "object"
+                },
+                "Resource": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "Sid": {
+                    "type": "string"
                 }
             }
         }
@@ -0,0 +1,259 @@
+// Package envelope encrypts object payloads in the gateway before they reach the backend.
+//
+// Every object gets a random AES-256 data key. The payload is split into chunks
+// which are sealed independently with AES-GCM, so large objects stream and a
+// byte range can be decrypted without reading the chunks before it. The data
+// key is wrapped with the master key and stored in the object's user metadata.
+package envelope
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const (
+	algorithm        = "AES256-GCM-CHUNKED-V1"
+	defaultChunkSize = 64 << 10
+	tagSize          = 16
+
+	// user metadata keys, stored by the backend as X-Amz-Meta-*
+	metaPrefix    = "Gw-Enc-"
+	metaAlgorithm = "Gw-Enc-Alg"
+	metaKey       = "Gw-Enc-Key"
+	metaKeyID     = "Gw-Enc-Key-Id"
+	metaChunkSize = "Gw-Enc-Chunk"
+	metaSize      = "Gw-Enc-Size"
+)
+
+// ErrWrongKey -- the object was sealed with another master key
+var ErrWrongKey = errors.New("object was encrypted with a different master key")
+
+// Sealed -- an encrypted object's parameters, enough to decrypt any byte range of it
+type Sealed struct {
+	aead      cipher.AEAD
+	chunkSize int64
+	// Size -- plaintext size
+	Size int64
+}
+
+// IsSealed -- the object was encrypted by the gateway
+func IsSealed(meta map[string]string) bool {
+	return meta[metaAlgorithm] != ""
+}
+
+// PlaintextSize -- the size of a sealed object before encryption
+func PlaintextSize(meta map[string]string) (int64, bool) {
+	size, err := strconv.ParseInt(meta[metaSize], 10, 64)
+	return size, IsSealed(meta) && err == nil
+}
+
+// Reserved -- name, with or without the X-Amz-Meta- prefix and in any case, is a user
+// metadata key of the envelope; callers setting metadata must not overwrite those
+func Reserved(name string) bool {
+	name = strings.ToLower(name)
+	name = strings.TrimPrefix(name, "x-amz-meta-")
+	return strings.HasPrefix(name, strings.ToLower(metaPrefix))
+}
+
+// Metadata -- the envelope entries of meta, which must be kept when an object's
+// metadata is replaced
+func Metadata(meta map[string]string) map[string]string {
+	out := make(map[string]string)
+	for _, k := range []string{metaAlgorithm, metaKey, metaKeyID, metaChunkSize, metaSize} {
+		if v, ok := meta[k]; ok {
+			out[k] = v
+		}
+	}
+	return out
+}
+
+// Seal -- encrypts size bytes read from r, returns the ciphertext stream, its exact length
+// and the user metadata to store with the object
+func (k *MasterKey) Seal(r io.Reader, size int64, chunkSize int) (io.Reader, int64, map[string]string, error) {
+	if chunkSize <= 0 {
+		chunkSize = defaultChunkSize
+	}
+	dataKey := make([]byte, keySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, 0, nil, err
+	}
+	aead, err := newGCM(dataKey)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	wrapped, err := k.wrap(dataKey)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+
+	s := &Sealed{aead: aead, chunkSize: int64(chunkSize), Size: size}
+	meta := map[string]string{
+		metaAlgorithm: algorithm,
+		metaKey:       base64.StdEncoding.EncodeToString(wrapped),
+		metaKeyID:     k.ID,
+		metaChunkSize: strconv.Itoa(chunkSize),
+		metaSize:      strconv.FormatInt(size, 10),
+	}
+	enc := &sealReader{s: s, r: r, remaining: size, buf: make([]byte, chunkSize)}
+	return enc, s.cipherSize(), meta, nil
+}
+
+// Open -- unwraps the data key of an object sealed by the gateway
+func (k *MasterKey) Open(meta map[string]string) (*Sealed, error) {
+	if alg := meta[metaAlgorithm]; alg != algorithm {
+		return nil, fmt.Errorf("unsupported envelope algorithm %q", alg)
+	}
+	if id := meta[metaKeyID]; id != "" && id != k.ID {
+		return nil, ErrWrongKey
+	}
+	chunkSize, err := strconv.ParseInt(meta[metaChunkSize], 10, 64)
+	if err != nil || chunkSize <= 0 {
+		return nil, errors.New("invalid envelope chunk size")
+	}
+	size, err := strconv.ParseInt(meta[metaSize], 10, 64)
+	if err != nil || size < 0 {
+		return nil, errors.New("invalid envelope plaintext size")
+	}
+	wrapped, err := base64.StdEncoding.DecodeString(meta[metaKey])
+	if err != nil {
+		return nil, errors.New("invalid wrapped data key")
+	}
+	dataKey, err := k.unwrap(wrapped)
+	if err != nil {
+		return nil, ErrWrongKey
+	}
+	aead, err := newGCM(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	return &Sealed{aead: aead, chunkSize: chunkSize, Size: size}, nil
+}
+
+// CipherRange -- the ciphertext bytes holding the plaintext range [start, end], both inclusive
+func (s *Sealed) CipherRange(start, end int64) (int64, int64) {
+	first, last := start/s.chunkSize, end/s.chunkSize
+	sealedChunk := s.chunkSize + tagSize
+	cEnd := (last+1)*sealedChunk - 1
+	if limit := s.cipherSize() - 1; cEnd > limit {
+		cEnd = limit
+	}
+	return first * sealedChunk, cEnd
+}
+
+// NewReader -- decrypts the plaintext range [start, end] from r, which must be positioned
+// at the beginning of CipherRange(start, end)
+func (s *Sealed) NewReader(r io.Reader, start, end int64) io.Reader {
+	first := start / s.chunkSize
+	dec := &openReader{
+		s:     s,
+		r:     r,
+		index: first,
+		skip:  start - first*s.chunkSize,
+		buf:   make([]byte, s.chunkSize+tagSize),
+	}
+	return io.LimitReader(dec, end-start+1)
+}
+
+func (s *Sealed) chunks() int64 {
+	n := (s.Size + s.chunkSize - 1) / s.chunkSize
+	if n == 0 {
+		// an empty object still gets one authenticated chunk
+		n = 1
+	}
+	return n
+}
+
+func (s *Sealed) cipherSize() int64 {
+	return s.Size + s.chunks()*tagSize
+}
+
+// nonce and additional data of a chunk: the data key is unique per object so a counter
+// is a safe nonce, and flagging the final chunk makes truncation detectable
+func (s *Sealed) chunkParams(index int64) ([]byte, []byte) {
+	nonce := make([]byte, s.aead.NonceSize())
+	binary.BigEndian.PutUint64(nonce[len(nonce)-8:], uint64(index))
+	final := []byte{0}
+	if index == s.chunks()-1 {
+		final[0] = 1
+	}
+	return nonce, final
+}
+
+type sealReader struct {
+	s         *Sealed
+	r         io.Reader
+	remaining int64
+	index     int64
+	buf       []byte
+	out       []byte
+	done      bool
+}
+
+func (e *sealReader) Read(p []byte) (int, error) {
+	if len(e.out) == 0 {
+		if e.done {
+			return 0, io.EOF
+		}
+		n := int64(len(e.buf))
+		if e.remaining < n {
+			n = e.remaining
+		}
+		if _, err := io.ReadFull(e.r, e.buf[:n]); err != nil {
+			return 0, err
+		}
+		e.remaining -= n
+		nonce, ad := e.s.chunkParams(e.index)
+		e.out = e.s.aead.Seal(e.out[:0], nonce, e.buf[:n], ad)
+		e.index++
+		e.done = e.remaining == 0
+	}
+	n := copy(p, e.out)
+	e.out = e.out[n:]
+	return n, nil
+}
+
+type openReader struct {
+	s     *Sealed
+	r     io.Reader
+	index int64
+	skip  int64
+	buf   []byte
+	out   []byte
+}
+
+func (d *openReader) Read(p []byte) (int, error) {
+	for len(d.out) == 0 {
+		if d.index >= d.s.chunks() {
+			return 0, io.EOF
+		}
+		plain := d.s.chunkSize
+		if rest := d.s.Size - d.index*d.s.chunkSize; rest < plain {
+			plain = rest
+		}
+		sealed := d.buf[:plain+tagSize]
+		if _, err := io.ReadFull(d.r, sealed); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return 0, err
+		}
+		nonce, ad := d.s.chunkParams(d.index)
+		out, err := d.s.aead.Open(sealed[:0], nonce, sealed, ad)
+		if err != nil {
+			return 0, fmt.Errorf("chunk %d failed authentication: %w", d.index, err)
+		}
+		d.index++
+		d.out = out[d.skip:]
+		d.skip = 0
+	}
+	n := copy(p, d.out)
+	d.out = d.out[n:]
+	return n, nil
+}
@@ -0,0 +1,144 @@
+package envelope
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+const testChunk = 16
+
+func testKey(t *testing.T, fill byte) *MasterKey {
+	t.Helper()
+	k, err := NewMasterKey(bytes.Repeat([]byte{fill}, keySize))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k
+}
+
+func plaintext(size int) []byte {
+	p := make([]byte, size)
+	for i := range p {
+		p[i] = byte(i*7 + 3)
+	}
+	return p
+}
+
+// seal -- the ciphertext of plain and the opened Sealed for it
+func seal(t *testing.T, k *MasterKey, plain []byte) ([]byte, *Sealed) {
+	t.Helper()
+	r, size, meta, err := k.Seal(bytes.NewReader(plain), int64(len(plain)), testChunk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ct, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if int64(len(ct)) != size {
+		t.Fatalf("Seal reported %d ciphertext bytes, produced %d", size, len(ct))
+	}
+	s, err := k.Open(meta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Size != int64(len(plain)) {
+		t.Fatalf("Open reported plaintext size %d, want %d", s.Size, len(plain))
+	}
+	return ct, s
+}
+
+func TestRoundTrip(t *testing.T) {
+	k := testKey(t, 1)
+	for _, size := range []int{0, 1, testChunk - 1, testChunk, testChunk + 1, 2 * testChunk, 2*testChunk + 1, 100} {
+		plain := plaintext(size)
+		ct, s := seal(t, k, plain)
+		chunks := max(1, (size+testChunk-1)/testChunk)
+		if want := size + chunks*tagSize; len(ct) != want {
+			t.Errorf("size %d: ciphertext is %d bytes, want %d", size, len(ct), want)
+		}
+		got, err := io.ReadAll(s.NewReader(bytes.NewReader(ct), 0, int64(size)-1))
+		if err != nil {
+			t.Errorf("size %d: %v", size, err)
+			continue
+		}
+		if !bytes.Equal(got, plain) {
+			t.Errorf("size %d: plaintext differs after round trip", size)
+		}
+	}
+}
+
+func TestRangeRead(t *testing.T) {
+	const size = 3*testChunk + 5
+	plain := plaintext(size)
+	ct, s := seal(t, testKey(t, 1), plain)
+	tests := []struct {
+		name       string
+		start, end int64
+	}{
+		{"first byte", 0, 0},
+		{"last byte", size - 1, size - 1},
+		{"last byte of a chunk", testChunk - 1, testChunk - 1},
+		{"first byte of a chunk", testChunk, testChunk},
+		{"one whole chunk", testChunk, 2*testChunk - 1},
+		{"across chunks", 5, 2*testChunk + 3},
+		{"into the short last chunk", 2*testChunk + 10, size - 1},
+		{"whole object", 0, size - 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cStart, cEnd := s.CipherRange(tt.start, tt.end)
+			if cStart%(testChunk+tagSize) != 0 {
+				t.Fatalf("cipher range starts at %d, inside a sealed chunk", cStart)
+			}
+			if cEnd >= int64(len(ct)) {
+				t.Fatalf("cipher range ends at %d, past the %d byte ciphertext", cEnd, len(ct))
+			}
+			got, err := io.ReadAll(s.NewReader(bytes.NewReader(ct[cStart:cEnd+1]), tt.start, tt.end))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := plain[tt.start : tt.end+1]; !bytes.Equal(got, want) {
+				t.Errorf("got %x, want %x", got, want)
+			}
+		})
+	}
+}
+
+func TestTampered(t *testing.T) {
+	const size = 3 * testChunk
+	k := testKey(t, 1)
+	ct, s := seal(t, k, plaintext(size))
+	sealedChunk := testChunk + tagSize
+
+	flipped := bytes.Clone(ct)
+	flipped[sealedChunk+3] ^= 0x80
+	truncated := ct[:2*sealedChunk]
+	swapped := bytes.Clone(ct)
+	copy(swapped, ct[sealedChunk:2*sealedChunk])
+	copy(swapped[sealedChunk:], ct[:sealedChunk])
+
+	for name, data := range map[string][]byte{
+		"flipped bit":      flipped,
+		"last chunk cut":   truncated,
+		"chunks reordered": swapped,
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := io.ReadAll(s.NewReader(bytes.NewReader(data), 0, size-1)); err == nil {
+				t.Error("tampered ciphertext was decrypted without an error")
+			}
+		})
+	}
+}
+
+func TestWrongKey(t *testing.T) {
+	_, _, meta, err := testKey(t, 1).Seal(bytes.NewReader(plaintext(10)), 10, testChunk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := testKey(t, 2).Open(meta); !errors.Is(err, ErrWrongKey) {
+		t.Errorf("Open with another master key: got %v, want ErrWrongKey", err)
+	}
+}
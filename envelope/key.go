@@ -0,0 +1,114 @@
+package envelope
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"kluisz-object-storage/config"
+)
+
+const keySize = 32
+
+// MasterKey -- wraps the per-object data keys, loaded from a local keyfile
+type MasterKey struct {
+	aead cipher.AEAD
+	// ID -- short fingerprint stored with every object, tells which master key wrapped it
+	ID string
+}
+
+// Key -- the configured master key, nil when no keyfile is configured
+var Key *MasterKey
+
+// Load -- reads the keyfile named in the config, see config.EnvelopeConfig
+func Load() error {
+	Key = nil
+	if config.Get().Envelope.KeyFile == "" {
+		if config.Get().Envelope.Enabled {
+			return errors.New("envelope encryption is enabled but no keyFile is configured")
+		}
+		return nil
+	}
+	k, err := LoadMasterKey(config.Get().Envelope.KeyFile)
+	if err != nil {
+		return err
+	}
+	Key = k
+	return nil
+}
+
+// Enabled -- new uploads are encrypted by the gateway
+func Enabled() bool {
+	return config.Get().Envelope.Enabled && Key != nil
+}
+
+// LoadMasterKey -- the keyfile holds 32 bytes, raw or hex or base64 encoded
+func LoadMasterKey(path string) (*MasterKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading master key: %w", err)
+	}
+	key, err := decodeKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("master key %s: %w", path, err)
+	}
+	return NewMasterKey(key)
+}
+
+func NewMasterKey(key []byte) (*MasterKey, error) {
+	if len(key) != keySize {
+		return nil, fmt.Errorf("master key must be %d bytes, got %d", keySize, len(key))
+	}
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(key)
+	return &MasterKey{aead: aead, ID: hex.EncodeToString(sum[:8])}, nil
+}
+
+func decodeKey(data []byte) ([]byte, error) {
+	if len(data) == keySize {
+		return data, nil
+	}
+	text := strings.TrimSpace(string(data))
+	if b, err := hex.DecodeString(text); err == nil && len(b) == keySize {
+		return b, nil
+	}
+	if b, err := base64.StdEncoding.DecodeString(text); err == nil && len(b) == keySize {
+		return b, nil
+	}
+	return nil, errors.New("expected 32 raw bytes, 64 hex characters or base64 of 32 bytes")
+}
+
+// wrap -- seals a data key with the master key, output is nonce || ciphertext
+func (k *MasterKey) wrap(dataKey []byte) ([]byte, error) {
+	nonce := make([]byte, k.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return k.aead.Seal(nonce, nonce, dataKey, []byte(algorithm)), nil
+}
+
+func (k *MasterKey) unwrap(wrapped []byte) ([]byte, error) {
+	n := k.aead.NonceSize()
+	if len(wrapped) < n {
+		return nil, errors.New("wrapped data key is too short")
+	}
+	return k.aead.Open(nil, wrapped[:n], wrapped[n:], []byte(algorithm))
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,112 @@
+// Package events carries bucket and object change notifications from the code paths
+// that mutate storage to whoever subscribed, such as the webhook dispatcher.
+package events
+
+import (
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Type string
+
+const (
+	ObjectCreated Type = "ObjectCreated"
+	ObjectRemoved Type = "ObjectRemoved"
+	BucketCreated Type = "BucketCreated"
+	BucketRemoved Type = "BucketRemoved"
+)
+
+// Types -- every event type, in a stable order
+var Types = []Type{ObjectCreated, ObjectRemoved, BucketCreated, BucketRemoved}
+
+// Event sources
+const (
+	SourceAPI       = "api"
+	SourceLifecycle = "lifecycle"
+	SourceSync      = "sync"
+	SourceJob       = "job"
+)
+
+type Event struct {
+	ID          string    `json:"id" example:"6f1c2a9e-8f53-4f7e-9d0a-2b8b0c7d1e55"`
+	Type        Type      `json:"type" example:"ObjectCreated"`
+	Time        time.Time `json:"time"`
+	Bucket      string    `json:"bucket" example:"mybucket"`
+	Key         string    `json:"key,omitempty" example:"file.txt"`
+	Size        int64     `json:"size,omitempty" example:"1234"`
+	ETag        string    `json:"etag,omitempty" example:"abcd1234"`
+	VersionID   string    `json:"versionId,omitempty"`
+	ContentType string    `json:"contentType,omitempty" example:"text/plain"`
+	// what caused the change: api, lifecycle, ...
+	Source    string `json:"source" example:"api"`
+	RequestID string `json:"requestId,omitempty"`
+}
+
+// Valid -- t is a known event type
+func (t Type) Valid() bool {
+	for _, known := range Types {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Match -- the filter shared by webhooks and sinks; empty lists and affixes match
+// everything, and an affix never matches bucket events since they have no key
+func Match(types, buckets []string, prefix, suffix string, e Event) bool {
+	if len(types) > 0 && !slices.Contains(types, string(e.Type)) {
+		return false
+	}
+	if len(buckets) > 0 && !slices.Contains(buckets, e.Bucket) {
+		return false
+	}
+	if prefix != "" || suffix != "" {
+		if e.Key == "" || !strings.HasPrefix(e.Key, prefix) || !strings.HasSuffix(e.Key, suffix) {
+			return false
+		}
+	}
+	return true
+}
+
+type bus struct {
+	mu   sync.RWMutex
+	next int
+	subs map[int]func(Event)
+}
+
+var defaultBus = &bus{subs: make(map[int]func(Event))}
+
+// Publish -- hands the event to every subscriber; subscribers run on the caller's
+// goroutine and must not block
+func Publish(e Event) {
+	if e.ID == "" {
+		e.ID = uuid.New().String()
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+	defaultBus.mu.RLock()
+	defer defaultBus.mu.RUnlock()
+	for _, fn := range defaultBus.subs {
+		fn(e)
+	}
+}
+
+// Subscribe -- fn receives every published event until cancel is called
+func Subscribe(fn func(Event)) (cancel func()) {
+	defaultBus.mu.Lock()
+	defer defaultBus.mu.Unlock()
+	id := defaultBus.next
+	defaultBus.next++
+	defaultBus.subs[id] = fn
+	return func() {
+		defaultBus.mu.Lock()
+		defer defaultBus.mu.Unlock()
+		delete(defaultBus.subs, id)
+	}
+}
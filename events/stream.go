@@ -0,0 +1,235 @@
+package events
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"kluisz-object-storage/config"
+)
+
+// SourceBackend -- changes reported by the backend's own bucket notifications,
+// usually made by clients that bypass the gateway
+const SourceBackend = "backend"
+
+// ListenFunc -- follows the backend's object notifications for a bucket, calling emit
+// for each change, until ctx is cancelled or the backend ends the stream
+type ListenFunc func(ctx context.Context, bucket string, emit func(Event)) error
+
+// Stream -- live object events per bucket, set up by StartStream
+var Stream *Feed
+
+// how long a gateway change suppresses the backend notification for the same object
+const echoWindow = 10 * time.Second
+
+// Feed -- object events for live clients. The most recent events are kept in memory
+// so a reconnecting client can resume after the last event it saw, and while a bucket
+// has watchers the backend's notifications for it are followed as well.
+type Feed struct {
+	mu        sync.Mutex
+	ring      []Event
+	head      int // next slot to write
+	full      bool
+	next      int
+	watchers  map[int]*Watcher
+	listeners map[string]*listener
+	seen      map[string]time.Time
+	listen    ListenFunc
+	retry     time.Duration
+	logger    *zap.Logger
+}
+
+type listener struct {
+	refs   int
+	cancel context.CancelFunc
+}
+
+// Watcher -- one live client; Events is closed when the client falls too far behind
+// or the feed is closed
+type Watcher struct {
+	Events <-chan Event
+	ch     chan Event
+	id     int
+	bucket string
+	prefix string
+	feed   *Feed
+	once   sync.Once
+}
+
+// StartStream -- keeps recent object events from the bus for streaming clients;
+// listen may be nil when the backend cannot report its own changes
+func StartStream(listen ListenFunc, logger *zap.Logger) {
+	cfg := config.Get().Events.Stream
+	Stream = NewFeed(cfg.Buffer, listen, cfg.Retry, logger)
+	Subscribe(func(e Event) {
+		Stream.add(e, false)
+	})
+}
+
+func NewFeed(size int, listen ListenFunc, retry time.Duration, logger *zap.Logger) *Feed {
+	return &Feed{
+		ring:      make([]Event, size),
+		watchers:  make(map[int]*Watcher),
+		listeners: make(map[string]*listener),
+		seen:      make(map[string]time.Time),
+		listen:    listen,
+		retry:     retry,
+		logger:    logger,
+	}
+}
+
+// Watch -- registers a client for object events in bucket under prefix. When lastID
+// is set, the buffered events after it are returned as the backlog; resumed is false
+// if lastID is no longer buffered, so the client may have missed events.
+func (f *Feed) Watch(bucket, prefix, lastID string) (w *Watcher, backlog []Event, resumed bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	resumed = lastID == ""
+	for _, e := range f.buffered() {
+		if !resumed {
+			resumed = e.ID == lastID
+			continue
+		}
+		if lastID != "" && watches(bucket, prefix, e) {
+			backlog = append(backlog, e)
+		}
+	}
+	if !resumed {
+		backlog = nil
+	}
+
+	ch := make(chan Event, 64)
+	w = &Watcher{Events: ch, ch: ch, id: f.next, bucket: bucket, prefix: prefix, feed: f}
+	f.next++
+	f.watchers[w.id] = w
+
+	if f.listen != nil {
+		l, ok := f.listeners[bucket]
+		if !ok {
+			ctx, cancel := context.WithCancel(context.Background())
+			l = &listener{cancel: cancel}
+			f.listeners[bucket] = l
+			go f.follow(ctx, bucket)
+		}
+		l.refs++
+	}
+	return w, backlog, resumed
+}
+
+// Close -- unregisters the watcher, the backend listener stops with the last one
+func (w *Watcher) Close() {
+	w.once.Do(func() {
+		f := w.feed
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.drop(w)
+		if l, ok := f.listeners[w.bucket]; ok {
+			l.refs--
+			if l.refs == 0 {
+				l.cancel()
+				delete(f.listeners, w.bucket)
+			}
+		}
+	})
+}
+
+// Close -- ends every watcher's stream, used at shutdown so clients reconnect to
+// another instance instead of holding the server open
+func (f *Feed) Close() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, w := range f.watchers {
+		f.drop(w)
+	}
+}
+
+// drop -- removes the watcher and closes its channel; f.mu must be held
+func (f *Feed) drop(w *Watcher) {
+	if _, ok := f.watchers[w.id]; ok {
+		delete(f.watchers, w.id)
+		close(w.ch)
+	}
+}
+
+func (f *Feed) add(e Event, fromBackend bool) {
+	if e.Type != ObjectCreated && e.Type != ObjectRemoved {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	now := time.Now()
+	echo := string(e.Type) + "\x00" + e.Bucket + "\x00" + e.Key
+	if fromBackend {
+		if t, ok := f.seen[echo]; ok && now.Sub(t) < echoWindow {
+			delete(f.seen, echo)
+			return
+		}
+	} else {
+		if len(f.seen) > 1024 {
+			for k, t := range f.seen {
+				if now.Sub(t) >= echoWindow {
+					delete(f.seen, k)
+				}
+			}
+		}
+		f.seen[echo] = now
+	}
+
+	if len(f.ring) > 0 {
+		f.ring[f.head] = e
+		f.head = (f.head + 1) % len(f.ring)
+		f.full = f.full || f.head == 0
+	}
+	for _, w := range f.watchers {
+		if !watches(w.bucket, w.prefix, e) {
+			continue
+		}
+		select {
+		case w.ch <- e:
+		default:
+			// too slow, the client reconnects and resumes from the buffer
+			f.drop(w)
+		}
+	}
+}
+
+// buffered -- the kept events, oldest first; f.mu must be held
+func (f *Feed) buffered() []Event {
+	if !f.full {
+		return f.ring[:f.head]
+	}
+	return append(append([]Event(nil), f.ring[f.head:]...), f.ring[:f.head]...)
+}
+
+func (f *Feed) follow(ctx context.Context, bucket string) {
+	for {
+		err := f.listen(ctx, bucket, func(e Event) {
+			e.ID = uuid.New().String()
+			e.Source = SourceBackend
+			if e.Time.IsZero() {
+				e.Time = time.Now().UTC()
+			}
+			f.add(e, true)
+		})
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			f.logger.Debug("Backend bucket notifications unavailable", zap.String("bucket", bucket), zap.Error(err))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(f.retry):
+		}
+	}
+}
+
+func watches(bucket, prefix string, e Event) bool {
+	return e.Bucket == bucket && strings.HasPrefix(e.Key, prefix)
+}
@@ -0,0 +1,139 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+	"kluisz-object-storage/config"
+	"kluisz-object-storage/jsonstore"
+	"kluisz-object-storage/models"
+	"kluisz-object-storage/queue"
+)
+
+// Webhooks -- subscriptions by ID, set up by StartWebhooks
+var Webhooks *jsonstore.Store[models.Webhook]
+
+// delivery -- what the outbox keeps per pending webhook call
+type delivery struct {
+	WebhookID string `json:"webhookId"`
+	Event     Event  `json:"event"`
+}
+
+// StartWebhooks -- loads the subscriptions and the on-disk outbox, then delivers
+// matching events until ctx is cancelled. Events are written to the outbox before
+// the publishing request returns, so pending deliveries survive restarts.
+func StartWebhooks(ctx context.Context, logger *zap.Logger) error {
+	store, err := jsonstore.Open[models.Webhook](filepath.Join(config.Get().DataDir, "webhooks.json"))
+	if err != nil {
+		return err
+	}
+	outbox, err := queue.Open(filepath.Join(config.Get().DataDir, "outbox", "webhooks"))
+	if err != nil {
+		return err
+	}
+	Webhooks = store
+
+	Subscribe(func(e Event) {
+		for _, w := range Webhooks.All() {
+			if !WebhookMatches(w, e) {
+				continue
+			}
+			if _, err := outbox.Push(w.ID+"/"+e.Bucket+"/"+e.Key, delivery{WebhookID: w.ID, Event: e}); err != nil {
+				logger.Error("Webhook delivery could not be queued", zap.String("webhook", w.ID), zap.String("event", e.ID), zap.Error(err))
+			}
+		}
+		// webhooks of a removed bucket get its BucketRemoved event, then they are
+		// dropped so they do not fire for a future bucket with the same name
+		if e.Type == BucketRemoved && e.Bucket != "" {
+			for id, w := range Webhooks.All() {
+				if w.Bucket != e.Bucket {
+					continue
+				}
+				if err := Webhooks.Delete(id); err != nil {
+					logger.Error("Webhook of a removed bucket could not be deleted", zap.String("webhook", id), zap.Error(err))
+				}
+			}
+		}
+	})
+
+	cfg := config.Get().Events.Webhooks
+	client := &http.Client{Timeout: cfg.Timeout}
+	go outbox.Run(ctx, queue.Options{
+		Name:        "webhooks",
+		Workers:     cfg.Workers,
+		MaxAttempts: cfg.MaxAttempts,
+		Backoff:     cfg.Backoff,
+		MaxBackoff:  cfg.MaxBackoff,
+		Logger:      logger,
+	}, func(ctx context.Context, it queue.Item) error {
+		return deliver(ctx, client, it)
+	})
+	return nil
+}
+
+// WebhookMatches -- bucket is an exact match, prefix and suffix apply to object keys
+// so a webhook with either set never receives bucket events
+func WebhookMatches(w models.Webhook, e Event) bool {
+	var buckets []string
+	if w.Bucket != "" {
+		buckets = []string{w.Bucket}
+	}
+	return Match(w.Events, buckets, w.Prefix, w.Suffix, e)
+}
+
+func deliver(ctx context.Context, client *http.Client, it queue.Item) error {
+	var d delivery
+	if err := json.Unmarshal(it.Payload, &d); err != nil {
+		return nil // undecodable, retrying cannot help
+	}
+	w, ok := Webhooks.Get(d.WebhookID)
+	if !ok {
+		return nil // unsubscribed since the event was queued
+	}
+
+	body, err := json.Marshal(d.Event)
+	if err != nil {
+		return nil
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "kluisz-object-storage-webhook")
+	req.Header.Set("X-Gateway-Event", string(d.Event.Type))
+	req.Header.Set("X-Gateway-Delivery", it.ID)
+	req.Header.Set("X-Gateway-Timestamp", ts)
+	req.Header.Set("X-Gateway-Signature", "sha256="+Sign(w.Secret, ts, body))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook %s answered %s", w.URL, resp.Status)
+	}
+	return nil
+}
+
+// Sign -- hex HMAC-SHA256 of "<timestamp>.<body>"; receivers recompute it and reject
+// stale timestamps to guard against replays
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/minio/minio-go/v7"
+	"kluisz-object-storage/archive"
+	"kluisz-object-storage/config"
+	"kluisz-object-storage/metrics"
+	"kluisz-object-storage/models"
+)
+
+// Download Archive
+// @Summary Download every object under a prefix as one ZIP or tar.gz archive
+// @Description The archive is streamed as it is built. Paths are the keys without the prefix's parent folders, so downloading "photos/2024/" gives 2024/...; folder marker objects are left out. The last entry, _manifest.json, lists the objects that could not be read.
+// @Tags objects
+// @Produce application/zip
+// @Produce application/gzip
+// @Param bucket path string true "Bucket name"
+// @Param prefix query string false "Key prefix, the whole bucket without it"
+// @Param format query string false "zip (default) or tar.gz"
+// @Success 200 {file} file "The archive"
+// @Failure 400 {object} models.ErrorResponse400
+// @Failure 404 {object} models.ErrorResponse404
+// @Failure 500 {object} models.ErrorResponse500
+// @Failure 503 {object} models.ErrorResponse503
+// @Failure 504 {object} models.ErrorResponse504
+// @Router /archive/{bucket} [get]
+func DownloadArchive(c *gin.Context) {
+	downloadArchive(c, models.ArchiveRequest{Prefix: c.Query("prefix"), Format: c.Query("format")})
+}
+
+// Download Archive Of Keys
+// @Summary Download the listed objects, or a prefix, as one ZIP or tar.gz archive
+// @Description Like GET /archive/{bucket}; listed keys keep their full path. Objects that are missing or would take the archive past archive.maxBytes are left out and named in _manifest.json.
+// @Tags objects
+// @Accept json
+// @Produce application/zip
+// @Produce application/gzip
+// @Param bucket path string true "Bucket name"
+// @Param request body models.ArchiveRequest true "Objects to download"
+// @Success 200 {file} file "The archive"
+// @Failure 400 {object} models.ErrorResponse400
+// @Failure 404 {object} models.ErrorResponse404
+// @Failure 500 {object} models.ErrorResponse500
+// @Failure 503 {object} models.ErrorResponse503
+// @Failure 504 {object} models.ErrorResponse504
+// @Router /archive/{bucket} [post]
+func DownloadArchiveOfKeys(c *gin.Context) {
+	var req models.ArchiveRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, models.ErrorResponse400{
+			Code:  http.StatusBadRequest,
+			Error: "Bad Request- Invalid archive request: " + err.Error(),
+		})
+		return
+	}
+	downloadArchive(c, req)
+}
+
+func downloadArchive(c *gin.Context, req models.ArchiveRequest) {
+	bucket := c.Param("bucket")
+	format, ok := archive.Format(req.Format)
+	if !ok {
+		c.IndentedJSON(http.StatusBadRequest, models.ErrorResponse400{
+			Code:  http.StatusBadRequest,
+			Error: "Bad Request- format must be zip or tar.gz",
+		})
+		return
+	}
+	limits := config.Get().Archive
+	if len(req.Keys) > limits.MaxObjects {
+		c.IndentedJSON(http.StatusBadRequest, models.ErrorResponse400{
+			Code:  http.StatusBadRequest,
+			Error: fmt.Sprintf("Bad Request- %d keys, an archive holds at most %d objects", len(req.Keys), limits.MaxObjects),
+		})
+		return
+	}
+
+	var entries []archive.Entry
+	for _, key := range req.Keys {
+		if name := archive.Name(key, ""); name != "" {
+			entries = append(entries, archive.Entry{Key: key, Name: name})
+		}
+	}
+	name := bucket
+	if len(req.Keys) == 0 {
+		// the objects are listed first, so the limits are checked before anything is sent
+		client, err := getMinioClient(bucket)
+		if err != nil {
+			c.IndentedJSON(http.StatusInternalServerError, models.ErrorResponse500{
+				Code:  http.StatusInternalServerError,
+				Error: "Internal Server Error - Try again in sometime ",
+			})
+			return
+		}
+		ctx, cancel := opContext(c, config.Get().Timeouts.List)
+		defer cancel()
+		// paths start at the prefix's last folder
+		folder := strings.TrimSuffix(req.Prefix, "/")
+		strip := ""
+		if i := strings.LastIndex(folder, "/"); i >= 0 {
+			strip = folder[:i+1]
+		}
+		var size int64
+		for obj := range client.ListObjects(ctx, bucket, minio.ListObjectsOptions{Prefix: req.Prefix, Recursive: true}) {
+			if obj.Err != nil {
+				if backendError(c, obj.Err) {
+					return
+				}
+				if minio.ToErrorResponse(obj.Err).Code == "NoSuchBucket" {
+					c.IndentedJSON(http.StatusNotFound, models.ErrorResponse404{
+						Code:  http.StatusNotFound,
+						Error: "Bucket not found",
+					})
+					return
+				}
+				c.IndentedJSON(http.StatusInternalServerError, models.ErrorResponse500{
+					Code:  http.StatusInternalServerError,
+					Error: "Failed to list objects: " + obj.Err.Error(),
+				})
+				return
+			}
+			n := archive.Name(obj.Key, strip)
+			if n == "" {
+				continue
+			}
+			entries = append(entries, archive.Entry{Key: obj.Key, Name: n})
+			size += obj.Size
+			if len(entries) > limits.MaxObjects || size > limits.MaxBytes {
+				c.IndentedJSON(http.StatusBadRequest, models.ErrorResponse400{
+					Code: http.StatusBadRequest,
+					Error: fmt.Sprintf("Bad Request- The archive would exceed %d objects or %d bytes, download a narrower prefix",
+						limits.MaxObjects, limits.MaxBytes),
+				})
+				return
+			}
+		}
+		if folder != "" {
+			name = path.Base(folder)
+		}
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name+"."+format))
+	c.Header("Content-Type", archive.ContentType(format))
+	c.Status(http.StatusOK)
+	counter := &countingWriter{w: c.Writer}
+	// the status is sent, failures from here on can only be logged
+	_, err := archive.Write(c.Request.Context(), counter, format, bucket, req.Prefix, entries, limits.MaxBytes)
+	metrics.BytesDownloaded.WithLabelValues(bucket).Add(float64(counter.n))
+	if err != nil {
+		c.Error(err)
+	}
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"kluisz-object-storage/config"
+	"kluisz-object-storage/models"
+	"kluisz-object-storage/storage"
+)
+
+// opContext -- the request's context, so a client that goes away cancels the backend
+// call, bounded by the configured timeout for the kind of call
+func opContext(c *gin.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(c.Request.Context(), timeout)
+}
+
+// backendError -- answers 504 when the backend call ran past its timeout and 503 while
+// the backend's circuit breaker is open
+func backendError(c *gin.Context, err error) bool {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		c.IndentedJSON(http.StatusGatewayTimeout, models.ErrorResponse504{
+			Code:  http.StatusGatewayTimeout,
+			Error: "Gateway Timeout - The storage backend did not answer in time",
+		})
+		return true
+	case errors.Is(err, storage.ErrCircuitOpen):
+		c.Header("Retry-After", strconv.Itoa(int(config.Get().Resilience.BreakerCooldown.Seconds())))
+		c.IndentedJSON(http.StatusServiceUnavailable, models.ErrorResponse503{
+			Code:  http.StatusServiceUnavailable,
+			Error: "Service Unavailable - The storage backend is failing, try again later",
+		})
+		return true
+	}
+	return false
+}
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/minio/minio-go/v7"
+	"kluisz-object-storage/batch"
+	"kluisz-object-storage/config"
+	"kluisz-object-storage/models"
+)
+
+// Start Batch Job
+// @Summary Apply one operation to every object listed in a manifest
+// @Description The manifest is an object of CSV lines bucket,key[,versionId] or JSON lines {"bucket","key","versionId"}. Operations: copy, delete, tag (replace the tag set), metadata (replace user metadata) and restore (make the listed version current again). Runs as a background job, see GET /jobs/{id}; the per-object report is stored as <report.prefix>batch-<job id>.csv in the report bucket.
+// @Tags jobs
+// @Accept json
+// @Produce json
+// @Param request body models.BatchRequest true "Batch job"
+// @Success 202 {object} models.Job
+// @Failure 400 {object} models.ErrorResponse400
+// @Failure 500 {object} models.ErrorResponse500
+// @Failure 503 {object} models.ErrorResponse503
+// @Failure 504 {object} models.ErrorResponse504
+// @Router /batch/jobs [post]
+func StartBatchJob(c *gin.Context) {
+	var req models.BatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, models.ErrorResponse400{
+			Code:  http.StatusBadRequest,
+			Error: "Bad Request- Invalid batch job: " + err.Error(),
+		})
+		return
+	}
+	if err := batch.Check(req); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, models.ErrorResponse400{
+			Code:  http.StatusBadRequest,
+			Error: "Bad Request- " + err.Error(),
+		})
+		return
+	}
+
+	// a missing manifest or report bucket is reported now rather than by a failed job
+	ctx, cancel := opContext(c, config.Get().Timeouts.Stat)
+	defer cancel()
+	manifestClient, err := getMinioClient(req.Manifest.Bucket)
+	if err == nil {
+		_, err = manifestClient.StatObject(ctx, req.Manifest.Bucket, req.Manifest.Key, minio.StatObjectOptions{})
+	}
+	if minio.ToErrorResponse(err).StatusCode == http.StatusNotFound {
+		c.IndentedJSON(http.StatusBadRequest, models.ErrorResponse400{
+			Code:  http.StatusBadRequest,
+			Error: "Bad Request- Manifest " + req.Manifest.Bucket + "/" + req.Manifest.Key + " not found",
+		})
+		return
+	}
+	exists := false
+	if err == nil {
+		var reportClient *minio.Client
+		if reportClient, err = getMinioClient(req.Report.Bucket); err == nil {
+			exists, err = reportClient.BucketExists(ctx, req.Report.Bucket)
+		}
+	}
+	if err != nil {
+		if backendError(c, err) {
+			return
+		}
+		c.IndentedJSON(http.StatusInternalServerError, models.ErrorResponse500{
+			Code:  http.StatusInternalServerError,
+			Error: "Batch job could not be checked: " + err.Error(),
+		})
+		return
+	}
+	if !exists {
+		c.IndentedJSON(http.StatusBadRequest, models.ErrorResponse400{
+			Code:  http.StatusBadRequest,
+			Error: "Bad Request- Report bucket " + req.Report.Bucket + " does not exist",
+		})
+		return
+	}
+	submitJob(c, "batch", req)
+}
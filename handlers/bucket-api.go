@@ -6,8 +6,15 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/minio/minio-go/v7"
 	"kluisz-object-storage/config"
+	"kluisz-object-storage/cors"
+	"kluisz-object-storage/events"
+	"kluisz-object-storage/lifecycle"
 	"kluisz-object-storage/models"
-	"context"
+	"kluisz-object-storage/storage"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
 )
 
 
@@ -19,6 +26,8 @@ import (
 // @Success 200 {object} models.BucketResponseC
 // @Failure 400 {object} models.ErrorResponse400
 // @Failure 500 {object} models.ErrorResponse500
+// @Failure 503 {object} models.ErrorResponse503
+// @Failure 504 {object} models.ErrorResponse504
 // @Router /bucket [post]
 func CreateBucket(c *gin.Context) {
 	var req models.CreateBucketRequest
@@ -29,8 +38,21 @@ func CreateBucket(c *gin.Context) {
 		})
 		return
 	}
+	if req.DefaultRetention != nil {
+		err := validateDefaultRetention(*req.DefaultRetention)
+		if err == nil && !req.ObjectLocking {
+			err = errors.New("default retention needs objectLocking")
+		}
+		if err != nil {
+			c.IndentedJSON(http.StatusBadRequest, models.ErrorResponse400{
+				Code:  http.StatusBadRequest,
+				Error: "Bad Request- Bucket could not be created: " + err.Error(),
+			})
+			return
+		}
+	}
 
-	client, err := getMinioClient()
+	client, err := getMinioClient(req.BucketName)
 	if err != nil {
 		//c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Client init failed: " + err.Error()})
 		c.IndentedJSON(http.StatusInternalServerError, models.ErrorResponse500{
@@ -40,14 +62,38 @@ func CreateBucket(c *gin.Context) {
 		return
 	}
 
-	err = client.MakeBucket(context.Background(), req.BucketName, minio.MakeBucketOptions{Region: config.Cfg.S3.Region})
+	ctx, cancel := opContext(c, config.Get().Timeouts.Other)
+	defer cancel()
+	cfg := config.Get()
+	backend, _ := cfg.Backend(cfg.Route(req.BucketName))
+	err = client.MakeBucket(ctx, req.BucketName, minio.MakeBucketOptions{
+		Region:        backend.Region,
+		ObjectLocking: req.ObjectLocking,
+	})
 	if err != nil {
+		if backendError(c, err) {
+			return
+		}
 		c.IndentedJSON(http.StatusInternalServerError, models.ErrorResponse500{
 			Code:  http.StatusInternalServerError,
 			Error: "Bucket creation failed: " + err.Error(),
 		})
 		return
 	}
+	if req.DefaultRetention != nil {
+		err = setDefaultRetention(ctx, client, req.BucketName, req.DefaultRetention)
+		if err != nil {
+			if backendError(c, err) {
+				return
+			}
+			c.IndentedJSON(http.StatusInternalServerError, models.ErrorResponse500{
+				Code:  http.StatusInternalServerError,
+				Error: "Bucket created but setting default retention failed: " + err.Error(),
+			})
+			return
+		}
+	}
+	publish(c, events.Event{Type: events.BucketCreated, Bucket: req.BucketName})
 	c.IndentedJSON(http.StatusOK, models.BucketResponseC{
 		Message: "Bucket created",
 		Bucket:  req.BucketName,
@@ -55,15 +101,24 @@ func CreateBucket(c *gin.Context) {
 }
 // Delete Bucket
 // @Summary Delete an existing S3 bucket
+// @Description Only an empty bucket is deleted, unless force is set: then a background job deletes every object, version and incomplete upload first, see GET /jobs/{id}. Its lifecycle and CORS rules, replication rules and webhooks are removed with it
 // @Tags buckets
 // @Produce json
 // @Param bucket path string true "Bucket name"
+// @Param force query bool false "Delete the bucket's contents too"
 // @Success 200 {object} models.BucketResponseD "status message"
+// @Success 202 {object} models.Job "with force"
 // @Failure 500 {object} models.ErrorResponse500 "error message"
+// @Failure 503 {object} models.ErrorResponse503
+// @Failure 504 {object} models.ErrorResponse504
 // @Router /bucket/{bucket} [delete]
 func DeleteBucket(c *gin.Context) {
 	bucket := c.Param("name")
-	client, err := getMinioClient()
+	if c.Query("force") == "true" {
+		submitJob(c, "delete-bucket", models.DeleteBucketJobParams{Bucket: bucket})
+		return
+	}
+	client, err := getMinioClient(bucket)
 	if err != nil {
 		c.IndentedJSON(http.StatusInternalServerError, models.ErrorResponse500{
 			Code:  http.StatusInternalServerError,
@@ -72,14 +127,28 @@ func DeleteBucket(c *gin.Context) {
 		return
 	}
 
-	err = client.RemoveBucket(context.Background(), bucket)
+	ctx, cancel := opContext(c, config.Get().Timeouts.Other)
+	defer cancel()
+	err = client.RemoveBucket(ctx, bucket)
 	if err != nil {
+		if backendError(c, err) {
+			return
+		}
 		c.IndentedJSON(http.StatusInternalServerError, models.ErrorResponse500{
 			Code:  http.StatusInternalServerError,
 			Error: "Bucket deletion failed: " + err.Error(),
 		})
 		return
 	}
+	// rules of a removed bucket must not apply to a future bucket with the same name,
+	// replication rules and webhooks are dropped on the BucketRemoved event
+	if err := lifecycle.Rules.Delete(bucket); err != nil {
+		c.Error(err)
+	}
+	if err := cors.Rules.Delete(bucket); err != nil {
+		c.Error(err)
+	}
+	publish(c, events.Event{Type: events.BucketRemoved, Bucket: bucket})
 	c.IndentedJSON(http.StatusOK, models.BucketResponseD{
 		Message: "Bucket deleted",
 		Bucket:  bucket,
@@ -88,37 +157,76 @@ func DeleteBucket(c *gin.Context) {
 
 // List Buckets
 // @Summary List all available S3 buckets
+// @Description Buckets of every configured backend; backends that cannot be listed are named in unavailable
 // @Tags buckets
 // @Produce json
 // @Success 200 {object} models.ListBucketsResponse
 // @Failure 500 {object} models.ErrorResponse500 "error message"
+// @Failure 503 {object} models.ErrorResponse503
+// @Failure 504 {object} models.ErrorResponse504
 // @Router /buckets [get]
 func ListBuckets(c *gin.Context) {
-	client, err := getMinioClient()
-	if err != nil {
-		c.IndentedJSON(http.StatusInternalServerError, models.ErrorResponse500{
-			Code:  http.StatusInternalServerError,
-			Error: "Internal Server Error - Try again in sometime ",
-		})
-		return
+	ctx, cancel := opContext(c, config.Get().Timeouts.List)
+	defer cancel()
+
+	// every backend is listed at once, one that is down leaves its buckets out
+	names := config.Get().BackendNames()
+	listed := make([][]minio.BucketInfo, len(names))
+	errs := make([]error, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			client, err := storage.NewBackendClient(name)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			listed[i], errs[i] = client.ListBuckets(ctx)
+		}()
 	}
+	wg.Wait()
 
-	buckets, err := client.ListBuckets(context.Background())
-	if err != nil {
+	resp := models.ListBucketsResponse{Buckets: []string{}}
+	if len(names) > 1 {
+		resp.Backends = make(map[string][]string)
+	}
+	seen := make(map[string]bool)
+	var firstErr error
+	for i, name := range names {
+		if errs[i] != nil {
+			c.Error(fmt.Errorf("listing backend %s: %w", name, errs[i]))
+			if firstErr == nil {
+				firstErr = errs[i]
+			}
+			resp.Unavailable = append(resp.Unavailable, name)
+			continue
+		}
+		bucketNames := make([]string, len(listed[i]))
+		for j, bucket := range listed[i] {
+			bucketNames[j] = bucket.Name
+			if !seen[bucket.Name] {
+				seen[bucket.Name] = true
+				resp.Buckets = append(resp.Buckets, bucket.Name)
+			}
+		}
+		if resp.Backends != nil {
+			resp.Backends[name] = bucketNames
+		}
+	}
+	if len(resp.Unavailable) == len(names) {
+		if backendError(c, firstErr) {
+			return
+		}
 		c.IndentedJSON(http.StatusInternalServerError, models.ErrorResponse500{
 			Code:  http.StatusInternalServerError,
-			Error: "Failed to list buckets: " + err.Error(),
+			Error: "Failed to list buckets: " + firstErr.Error(),
 		})
 		return
 	}
+	sort.Strings(resp.Buckets)
 
-	bucketNames := make([]string, len(buckets))
-	for i, bucket := range buckets {
-		bucketNames[i] = bucket.Name
-	}
-
-	c.IndentedJSON(http.StatusOK, models.ListBucketsResponse{
-		Buckets: bucketNames,
-    })
+	c.IndentedJSON(http.StatusOK, resp)
 }
 
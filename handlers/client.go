@@ -3,15 +3,11 @@ package handlers
 import (
 
 	"github.com/minio/minio-go/v7"
-	"github.com/minio/minio-go/v7/pkg/credentials"
-	"kluisz-object-storage/config"
+	"kluisz-object-storage/storage"
 )
 
 
-func getMinioClient() (*minio.Client, error) {
-	return minio.New(config.Cfg.S3.Endpoint, &minio.Options{
-		Creds:  credentials.NewStaticV4(config.Cfg.S3.AccessKey, config.Cfg.S3.SecretKey, ""),
-		Secure: config.Cfg.S3.UseSSL,
-		Region: config.Cfg.S3.Region,
-	})
-}
\ No newline at end of file
+// getMinioClient -- client for the backend bucket is routed to
+func getMinioClient(bucket string) (*minio.Client, error) {
+	return storage.ClientFor(bucket)
+}
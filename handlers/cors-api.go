@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"kluisz-object-storage/cors"
+	"kluisz-object-storage/models"
+)
+
+// Get Bucket CORS
+// @Summary Get the CORS rules of a bucket
+// @Tags buckets
+// @Produce json
+// @Param name path string true "Bucket name"
+// @Success 200 {object} models.CORSResponse
+// @Failure 404 {object} models.ErrorResponse404
+// @Router /bucket/{name}/cors [get]
+func GetBucketCORS(c *gin.Context) {
+	bucket := c.Param("name")
+
+	cfg, ok := cors.Rules.Get(bucket)
+	if !ok {
+		c.IndentedJSON(http.StatusNotFound, models.ErrorResponse404{
+			Code:  http.StatusNotFound,
+			Error: "No CORS configuration for bucket " + bucket,
+		})
+		return
+	}
+	c.IndentedJSON(http.StatusOK, models.CORSResponse{Bucket: bucket, CORS: cfg})
+}
+
+// Put Bucket CORS
+// @Summary Create or replace the CORS rules of a bucket
+// @Description The rules apply to the bucket's object routes (upload, download, objects, public) instead of the gateway-wide CORS settings
+// @Tags buckets
+// @Accept json
+// @Produce json
+// @Param name path string true "Bucket name"
+// @Param request body models.CORSConfiguration true "CORS rules"
+// @Success 200 {object} models.CORSResponse
+// @Failure 400 {object} models.ErrorResponse400
+// @Failure 500 {object} models.ErrorResponse500
+// @Router /bucket/{name}/cors [put]
+func PutBucketCORS(c *gin.Context) {
+	bucket := c.Param("name")
+
+	var req models.CORSConfiguration
+	err := c.ShouldBindJSON(&req)
+	if err == nil {
+		err = cors.Validate(req)
+	}
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, models.ErrorResponse400{
+			Code:  http.StatusBadRequest,
+			Error: "Bad Request- Invalid CORS configuration: " + err.Error(),
+		})
+		return
+	}
+
+	err = cors.Rules.Put(bucket, req)
+	if err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, models.ErrorResponse500{
+			Code:  http.StatusInternalServerError,
+			Error: "Setting bucket CORS failed: " + err.Error(),
+		})
+		return
+	}
+	c.IndentedJSON(http.StatusOK, models.CORSResponse{Bucket: bucket, CORS: req})
+}
+
+// Delete Bucket CORS
+// @Summary Remove the CORS rules of a bucket, the gateway-wide settings apply again
+// @Tags buckets
+// @Produce json
+// @Param name path string true "Bucket name"
+// @Success 200 {object} models.BucketResponseD
+// @Failure 500 {object} models.ErrorResponse500
+// @Router /bucket/{name}/cors [delete]
+func DeleteBucketCORS(c *gin.Context) {
+	bucket := c.Param("name")
+
+	err := cors.Rules.Delete(bucket)
+	if err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, models.ErrorResponse500{
+			Code:  http.StatusInternalServerError,
+			Error: "Removing bucket CORS failed: " + err.Error(),
+		})
+		return
+	}
+	c.IndentedJSON(http.StatusOK, models.BucketResponseD{
+		Message: "Bucket CORS removed",
+		Bucket:  bucket,
+	})
+}
@@ -0,0 +1,223 @@
+package handlers
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/encrypt"
+	"github.com/minio/minio-go/v7/pkg/sse"
+	"kluisz-object-storage/config"
+	"kluisz-object-storage/models"
+)
+
+const (
+	sseAlgorithmS3  = "AES256"
+	sseAlgorithmKMS = "aws:kms"
+)
+
+// Get Bucket Encryption
+// @Summary Get the default encryption of a bucket
+// @Tags buckets
+// @Produce json
+// @Param name path string true "Bucket name"
+// @Success 200 {object} models.BucketEncryptionResponse
+// @Failure 404 {object} models.ErrorResponse404
+// @Failure 500 {object} models.ErrorResponse500
+// @Failure 503 {object} models.ErrorResponse503
+// @Failure 504 {object} models.ErrorResponse504
+// @Router /bucket/{name}/encryption [get]
+func GetBucketEncryption(c *gin.Context) {
+	bucket := c.Param("name")
+
+	client, err := getMinioClient(bucket)
+	if err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, models.ErrorResponse500{
+			Code:  http.StatusInternalServerError,
+			Error: "Internal Server Error - Try again in sometime ",
+		})
+		return
+	}
+
+	ctx, cancel := opContext(c, config.Get().Timeouts.Other)
+	defer cancel()
+	cfg, err := client.GetBucketEncryption(ctx, bucket)
+	if err != nil || len(cfg.Rules) == 0 {
+		if backendError(c, err) {
+			return
+		}
+		if err == nil || minio.ToErrorResponse(err).Code == "ServerSideEncryptionConfigurationNotFoundError" {
+			c.IndentedJSON(http.StatusNotFound, models.ErrorResponse404{
+				Code:  http.StatusNotFound,
+				Error: "No default encryption for bucket " + bucket,
+			})
+			return
+		}
+		c.IndentedJSON(http.StatusInternalServerError, models.ErrorResponse500{
+			Code:  http.StatusInternalServerError,
+			Error: "Failed to get bucket encryption: " + err.Error(),
+		})
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, models.BucketEncryptionResponse{
+		Bucket:    bucket,
+		Algorithm: cfg.Rules[0].Apply.SSEAlgorithm,
+		KMSKeyID:  cfg.Rules[0].Apply.KmsMasterKeyID,
+	})
+}
+
+// Put Bucket Encryption
+// @Summary Set the default encryption of a bucket
+// @Description New objects are encrypted at rest with SSE-S3 (AES256) or SSE-KMS (aws:kms) even when uploads carry no encryption headers
+// @Tags buckets
+// @Accept json
+// @Produce json
+// @Param name path string true "Bucket name"
+// @Param request body models.BucketEncryptionRequest true "Default encryption"
+// @Success 200 {object} models.BucketEncryptionResponse
+// @Failure 400 {object} models.ErrorResponse400
+// @Failure 500 {object} models.ErrorResponse500
+// @Failure 503 {object} models.ErrorResponse503
+// @Failure 504 {object} models.ErrorResponse504
+// @Router /bucket/{name}/encryption [put]
+func PutBucketEncryption(c *gin.Context) {
+	bucket := c.Param("name")
+
+	var req models.BucketEncryptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, models.ErrorResponse400{
+			Code:  http.StatusBadRequest,
+			Error: "Bad Request- Invalid encryption configuration: " + err.Error(),
+		})
+		return
+	}
+
+	var cfg *sse.Configuration
+	switch {
+	case req.Algorithm == sseAlgorithmS3 && req.KMSKeyID == "":
+		cfg = sse.NewConfigurationSSES3()
+	case req.Algorithm == sseAlgorithmKMS && req.KMSKeyID != "":
+		cfg = sse.NewConfigurationSSEKMS(req.KMSKeyID)
+	default:
+		c.IndentedJSON(http.StatusBadRequest, models.ErrorResponse400{
+			Code:  http.StatusBadRequest,
+			Error: "Bad Request- algorithm must be AES256, or aws:kms together with kmsKeyId",
+		})
+		return
+	}
+
+	client, err := getMinioClient(bucket)
+	if err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, models.ErrorResponse500{
+			Code:  http.StatusInternalServerError,
+			Error: "Internal Server Error - Try again in sometime ",
+		})
+		return
+	}
+
+	ctx, cancel := opContext(c, config.Get().Timeouts.Other)
+	defer cancel()
+	err = client.SetBucketEncryption(ctx, bucket, cfg)
+	if err != nil {
+		if backendError(c, err) {
+			return
+		}
+		c.IndentedJSON(http.StatusInternalServerError, models.ErrorResponse500{
+			Code:  http.StatusInternalServerError,
+			Error: "Setting bucket encryption failed: " + err.Error(),
+		})
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, models.BucketEncryptionResponse{
+		Bucket:    bucket,
+		Algorithm: req.Algorithm,
+		KMSKeyID:  req.KMSKeyID,
+	})
+}
+
+// Delete Bucket Encryption
+// @Summary Remove the default encryption of a bucket
+// @Description Objects already stored stay encrypted
+// @Tags buckets
+// @Produce json
+// @Param name path string true "Bucket name"
+// @Success 200 {object} models.BucketResponseD
+// @Failure 500 {object} models.ErrorResponse500
+// @Failure 503 {object} models.ErrorResponse503
+// @Failure 504 {object} models.ErrorResponse504
+// @Router /bucket/{name}/encryption [delete]
+func DeleteBucketEncryption(c *gin.Context) {
+	bucket := c.Param("name")
+
+	client, err := getMinioClient(bucket)
+	if err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, models.ErrorResponse500{
+			Code:  http.StatusInternalServerError,
+			Error: "Internal Server Error - Try again in sometime ",
+		})
+		return
+	}
+
+	ctx, cancel := opContext(c, config.Get().Timeouts.Other)
+	defer cancel()
+	err = client.RemoveBucketEncryption(ctx, bucket)
+	if backendError(c, err) {
+		return
+	}
+	if err != nil && minio.ToErrorResponse(err).Code != "ServerSideEncryptionConfigurationNotFoundError" {
+		c.IndentedJSON(http.StatusInternalServerError, models.ErrorResponse500{
+			Code:  http.StatusInternalServerError,
+			Error: "Removing bucket encryption failed: " + err.Error(),
+		})
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, models.BucketResponseD{
+		Message: "Bucket encryption removed",
+		Bucket:  bucket,
+	})
+}
+
+// sseFromHeaders -- encryption requested by the client with the standard S3 headers
+// SSE-S3 and SSE-KMS only apply to uploads, SSE-C keys are needed for uploads and downloads alike
+func sseFromHeaders(h http.Header, upload bool) (encrypt.ServerSide, error) {
+	if alg := h.Get(encrypt.SseCustomerAlgorithm); alg != "" {
+		if alg != sseAlgorithmS3 {
+			return nil, errors.New(encrypt.SseCustomerAlgorithm + " must be AES256")
+		}
+		key, err := base64.StdEncoding.DecodeString(h.Get(encrypt.SseCustomerKey))
+		if err != nil || len(key) != 32 {
+			return nil, errors.New(encrypt.SseCustomerKey + " must be a base64 encoded 256 bit key")
+		}
+		if sum := h.Get(encrypt.SseCustomerKeyMD5); sum != "" {
+			md := md5.Sum(key)
+			if sum != base64.StdEncoding.EncodeToString(md[:]) {
+				return nil, errors.New(encrypt.SseCustomerKeyMD5 + " does not match the key")
+			}
+		}
+		return encrypt.NewSSEC(key)
+	}
+	if !upload {
+		return nil, nil
+	}
+
+	switch alg := h.Get(encrypt.SseGenericHeader); alg {
+	case "":
+		return nil, nil
+	case sseAlgorithmS3:
+		return encrypt.NewSSE(), nil
+	case sseAlgorithmKMS:
+		keyID := h.Get(encrypt.SseKmsKeyID)
+		if keyID == "" {
+			return nil, errors.New(encrypt.SseKmsKeyID + " is required for aws:kms")
+		}
+		return encrypt.NewSSEKMS(keyID, nil)
+	default:
+		return nil, errors.New(encrypt.SseGenericHeader + " must be AES256 or aws:kms")
+	}
+}
@@ -0,0 +1,185 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"kluisz-object-storage/config"
+	"kluisz-object-storage/events"
+	"kluisz-object-storage/models"
+)
+
+// resync -- sent instead of the backlog when the requested last event is no longer
+// buffered; the client should re-list the bucket
+const resync = "Resync"
+
+// Stream Bucket Events
+// @Summary Live object events for a bucket
+// @Description Server-Sent Events stream of ObjectCreated and ObjectRemoved events, or a WebSocket of the same events as JSON messages when the request asks for an upgrade.
+// @Description Reconnecting clients pass the last event ID they saw (Last-Event-ID header or lastEventId query) to receive what they missed; a Resync event means that ID is too old.
+// @Description Changes made through the gateway are always included, changes made directly on the backend when it supports bucket notifications.
+// @Tags events
+// @Produce text/event-stream
+// @Param bucket path string true "Bucket name"
+// @Param prefix query string false "Only keys under this prefix"
+// @Param lastEventId query string false "Resume after this event, same as the Last-Event-ID header"
+// @Success 200 {object} events.Event
+// @Failure 404 {object} models.ErrorResponse404
+// @Failure 500 {object} models.ErrorResponse500
+// @Failure 503 {object} models.ErrorResponse503
+// @Failure 504 {object} models.ErrorResponse504
+// @Router /events/{bucket} [get]
+func StreamBucketEvents(c *gin.Context) {
+	bucket := c.Param("bucket")
+	prefix := c.Query("prefix")
+	lastID := c.GetHeader("Last-Event-ID")
+	if lastID == "" {
+		lastID = c.Query("lastEventId")
+	}
+
+	client, err := getMinioClient(bucket)
+	if err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, models.ErrorResponse500{
+			Code:  http.StatusInternalServerError,
+			Error: "Internal Server Error - Try again in sometime ",
+		})
+		return
+	}
+	ctx, cancel := opContext(c, config.Get().Timeouts.Stat)
+	defer cancel()
+	exists, err := client.BucketExists(ctx, bucket)
+	if err != nil {
+		if backendError(c, err) {
+			return
+		}
+		c.IndentedJSON(http.StatusInternalServerError, models.ErrorResponse500{
+			Code:  http.StatusInternalServerError,
+			Error: "Internal Server Error - Try again in sometime ",
+		})
+		return
+	}
+	if !exists {
+		c.IndentedJSON(http.StatusNotFound, models.ErrorResponse404{
+			Code:  http.StatusNotFound,
+			Error: "Bucket not found",
+		})
+		return
+	}
+
+	if websocket.IsWebSocketUpgrade(c.Request) {
+		streamWebSocket(c, bucket, prefix, lastID)
+		return
+	}
+
+	h := c.Writer.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("X-Accel-Buffering", "no") // nginx would otherwise hold the stream back
+	c.Status(http.StatusOK)
+
+	w, backlog, resumed := events.Stream.Watch(bucket, prefix, lastID)
+	defer w.Close()
+
+	if !resumed {
+		fmt.Fprintf(c.Writer, "event: %s\ndata: {}\n\n", resync)
+	}
+	for _, e := range backlog {
+		writeSSE(c, e)
+	}
+	c.Writer.Flush()
+
+	keepAlive := time.NewTicker(config.Get().Events.Stream.KeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case e, ok := <-w.Events:
+			if !ok {
+				return
+			}
+			writeSSE(c, e)
+		case <-keepAlive.C:
+			fmt.Fprint(c.Writer, ": keep-alive\n\n")
+		}
+		c.Writer.Flush()
+	}
+}
+
+func writeSSE(c *gin.Context, e events.Event) {
+	data, _ := json.Marshal(e)
+	fmt.Fprintf(c.Writer, "id: %s\nevent: %s\ndata: %s\n\n", e.ID, e.Type, data)
+}
+
+func streamWebSocket(c *gin.Context, bucket, prefix, lastID string) {
+	upgrader := websocket.Upgrader{
+		// browsers send Origin on WebSocket handshakes but do not enforce CORS on them,
+		// so accept same-host pages and the origins the CORS middleware allowed
+		CheckOrigin: func(r *http.Request) bool {
+			origin := r.Header.Get("Origin")
+			if origin == "" || origin == c.Writer.Header().Get("Access-Control-Allow-Origin") {
+				return true
+			}
+			u, err := url.Parse(origin)
+			return err == nil && u.Host == r.Host
+		},
+	}
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// the upgrader has already answered the request
+		c.Error(err)
+		return
+	}
+	defer conn.Close()
+
+	w, backlog, resumed := events.Stream.Watch(bucket, prefix, lastID)
+	defer w.Close()
+
+	// the client only sends control frames; reading handles them and notices a close
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	if !resumed {
+		if conn.WriteJSON(map[string]string{"type": resync}) != nil {
+			return
+		}
+	}
+	for _, e := range backlog {
+		if conn.WriteJSON(e) != nil {
+			return
+		}
+	}
+
+	keepAlive := time.NewTicker(config.Get().Events.Stream.KeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-closed:
+			return
+		case e, ok := <-w.Events:
+			if !ok {
+				conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "stream ended, reconnect with lastEventId"))
+				return
+			}
+			if conn.WriteJSON(e) != nil {
+				return
+			}
+		case <-keepAlive.C:
+			if conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(10*time.Second)) != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"kluisz-object-storage/health"
+	"kluisz-object-storage/models"
+)
+
+// Liveness
+// @Summary Liveness probe, the process is up and serving
+// @Tags health
+// @Produce json
+// @Success 200 {object} models.HealthResponse
+// @Router /healthz [get]
+func Liveness(c *gin.Context) {
+	c.IndentedJSON(http.StatusOK, models.HealthResponse{Status: health.StatusOK})
+}
+
+// Readiness
+// @Summary Readiness probe, fails while a dependency check fails or the gateway is shutting down
+// @Description Checks the config, the backends (list buckets within the health timeout) and free disk space for logs and local state. Only the backends in health.requiredBackends fail readiness, the others are listed with status warn.
+// @Tags health
+// @Produce json
+// @Success 200 {object} models.HealthResponse
+// @Failure 503 {object} models.HealthResponse
+// @Router /readyz [get]
+func Readiness(c *gin.Context) {
+	report := health.Run(c.Request.Context())
+	// probes only need the verdict and what failed
+	for name, r := range report.Checks {
+		if r.Status == health.StatusOK {
+			delete(report.Checks, name)
+		}
+	}
+	c.IndentedJSON(healthStatus(report), report)
+}
+
+// Health
+// @Summary Detailed health report with the status and latency of every dependency
+// @Tags health
+// @Produce json
+// @Success 200 {object} models.HealthResponse
+// @Failure 503 {object} models.HealthResponse
+// @Router /health [get]
+func Health(c *gin.Context) {
+	report := health.Run(c.Request.Context())
+	c.IndentedJSON(healthStatus(report), report)
+}
+
+func healthStatus(report models.HealthResponse) int {
+	if report.Status != health.StatusOK {
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusOK
+}
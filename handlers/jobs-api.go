@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"kluisz-object-storage/jobs"
+	"kluisz-object-storage/models"
+)
+
+// Submit Job
+// @Summary Start a bulk operation in the background
+// @Description Types: copy (models.CopyJobParams), delete (models.DeleteJobParams), delete-bucket (models.DeleteBucketJobParams), sync (models.SyncRequest), replication-backfill (models.BackfillJobParams) and batch (models.BatchRequest). Jobs run a few at a time, the rest wait queued; progress and the result are shown by GET /jobs/{id}
+// @Tags jobs
+// @Accept json
+// @Produce json
+// @Param request body models.JobRequest true "Job"
+// @Success 202 {object} models.Job
+// @Failure 400 {object} models.ErrorResponse400
+// @Failure 500 {object} models.ErrorResponse500
+// @Router /jobs [post]
+func SubmitJob(c *gin.Context) {
+	var req models.JobRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, models.ErrorResponse400{
+			Code:  http.StatusBadRequest,
+			Error: "Bad Request- Invalid job: " + err.Error(),
+		})
+		return
+	}
+	submitJob(c, req.Type, req.Params)
+}
+
+// submitJob -- answers 202 with the queued job
+func submitJob(c *gin.Context, typ string, params any) {
+	job, err := jobs.Submit(typ, params)
+	if errors.Is(err, jobs.ErrUnknownType) || errors.Is(err, jobs.ErrInvalid) {
+		c.IndentedJSON(http.StatusBadRequest, models.ErrorResponse400{
+			Code:  http.StatusBadRequest,
+			Error: "Bad Request- " + err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, models.ErrorResponse500{
+			Code:  http.StatusInternalServerError,
+			Error: "Job could not be saved: " + err.Error(),
+		})
+		return
+	}
+	c.IndentedJSON(http.StatusAccepted, job)
+}
+
+// List Jobs
+// @Summary List kept jobs, the most recent first
+// @Tags jobs
+// @Produce json
+// @Param type query string false "Only jobs of this type"
+// @Param status query string false "Only jobs with this status: queued, running, succeeded, failed or cancelled"
+// @Success 200 {object} models.ListJobsResponse
+// @Router /jobs [get]
+func ListJobs(c *gin.Context) {
+	typ, status := c.Query("type"), c.Query("status")
+	list := []models.Job{}
+	for _, j := range jobs.List() {
+		if (typ == "" || j.Type == typ) && (status == "" || j.Status == status) {
+			list = append(list, j)
+		}
+	}
+	c.IndentedJSON(http.StatusOK, models.ListJobsResponse{Jobs: list})
+}
+
+// Get Job
+// @Summary Get a job's status, progress and result
+// @Tags jobs
+// @Produce json
+// @Param id path string true "Job ID"
+// @Success 200 {object} models.Job
+// @Failure 404 {object} models.ErrorResponse404
+// @Router /jobs/{id} [get]
+func GetJob(c *gin.Context) {
+	job, ok := jobs.Get(c.Param("id"))
+	if !ok {
+		c.IndentedJSON(http.StatusNotFound, models.ErrorResponse404{
+			Code:  http.StatusNotFound,
+			Error: "Job not found",
+		})
+		return
+	}
+	c.IndentedJSON(http.StatusOK, job)
+}
+
+// Cancel Job
+// @Summary Cancel a queued or running job
+// @Description A running job stops at its next object, what it did so far is not undone
+// @Tags jobs
+// @Produce json
+// @Param id path string true "Job ID"
+// @Success 202 {object} models.Job
+// @Failure 404 {object} models.ErrorResponse404
+// @Failure 409 {object} models.ErrorResponse409
+// @Failure 500 {object} models.ErrorResponse500
+// @Router /jobs/{id}/cancel [post]
+func CancelJob(c *gin.Context) {
+	job, err := jobs.Cancel(c.Param("id"))
+	switch {
+	case errors.Is(err, jobs.ErrNotFound):
+		c.IndentedJSON(http.StatusNotFound, models.ErrorResponse404{
+			Code:  http.StatusNotFound,
+			Error: "Job not found",
+		})
+	case errors.Is(err, jobs.ErrFinished):
+		c.IndentedJSON(http.StatusConflict, models.ErrorResponse409{
+			Code:  http.StatusConflict,
+			Error: "Conflict- Job already " + job.Status,
+		})
+	case err != nil:
+		c.IndentedJSON(http.StatusInternalServerError, models.ErrorResponse500{
+			Code:  http.StatusInternalServerError,
+			Error: "Job could not be cancelled: " + err.Error(),
+		})
+	default:
+		c.IndentedJSON(http.StatusAccepted, job)
+	}
+}
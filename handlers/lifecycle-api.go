@@ -0,0 +1,241 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/minio/minio-go/v7"
+	s3lifecycle "github.com/minio/minio-go/v7/pkg/lifecycle"
+	"kluisz-object-storage/config"
+	"kluisz-object-storage/lifecycle"
+	"kluisz-object-storage/models"
+)
+
+const (
+	lifecycleModeNative  = "native"
+	lifecycleModeGateway = "gateway"
+)
+
+// Put Bucket Lifecycle
+// @Summary Create or replace the lifecycle configuration of a bucket
+// @Description Rules are sent to the backend; when it has no lifecycle support they are stored and enforced by the gateway (transitions excepted)
+// @Tags buckets
+// @Accept json
+// @Produce json
+// @Param name path string true "Bucket name"
+// @Param request body models.LifecycleConfiguration true "Lifecycle rules"
+// @Success 200 {object} models.LifecycleMessageResponse
+// @Failure 400 {object} models.ErrorResponse400
+// @Failure 500 {object} models.ErrorResponse500
+// @Failure 503 {object} models.ErrorResponse503
+// @Failure 504 {object} models.ErrorResponse504
+// @Router /bucket/{name}/lifecycle [put]
+func PutBucketLifecycle(c *gin.Context) {
+	bucket := c.Param("name")
+
+	var req models.LifecycleConfiguration
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, models.ErrorResponse400{
+			Code:  http.StatusBadRequest,
+			Error: "Bad Request- Invalid lifecycle configuration: " + err.Error(),
+		})
+		return
+	}
+	if err := lifecycle.Validate(req); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, models.ErrorResponse400{
+			Code:  http.StatusBadRequest,
+			Error: "Bad Request- Invalid lifecycle configuration: " + err.Error(),
+		})
+		return
+	}
+
+	mode := lifecycleModeGateway
+	if config.Get().Lifecycle.Mode != lifecycleModeGateway {
+		client, err := getMinioClient(bucket)
+		if err != nil {
+			c.IndentedJSON(http.StatusInternalServerError, models.ErrorResponse500{
+				Code:  http.StatusInternalServerError,
+				Error: "Internal Server Error - Try again in sometime ",
+			})
+			return
+		}
+
+		ctx, cancel := opContext(c, config.Get().Timeouts.Other)
+		defer cancel()
+		err = client.SetBucketLifecycle(ctx, bucket, lifecycle.ToS3(req))
+		switch {
+		case err == nil:
+			mode = lifecycleModeNative
+		case backendError(c, err):
+			return
+		case config.Get().Lifecycle.Mode == lifecycleModeNative || !lifecycleNotSupported(err):
+			c.IndentedJSON(http.StatusInternalServerError, models.ErrorResponse500{
+				Code:  http.StatusInternalServerError,
+				Error: "Setting bucket lifecycle failed: " + err.Error(),
+			})
+			return
+		}
+	}
+
+	if mode == lifecycleModeNative {
+		// the backend owns the rules now, stop enforcing an older copy here
+		err := lifecycle.Rules.Delete(bucket)
+		if err != nil {
+			c.IndentedJSON(http.StatusInternalServerError, models.ErrorResponse500{
+				Code:  http.StatusInternalServerError,
+				Error: "Setting bucket lifecycle failed: " + err.Error(),
+			})
+			return
+		}
+	} else {
+		if lifecycle.HasTransitions(req) {
+			c.IndentedJSON(http.StatusBadRequest, models.ErrorResponse400{
+				Code:  http.StatusBadRequest,
+				Error: "Bad Request- Transitions need lifecycle support in the storage backend",
+			})
+			return
+		}
+		err := lifecycle.Rules.Put(bucket, req)
+		if err != nil {
+			c.IndentedJSON(http.StatusInternalServerError, models.ErrorResponse500{
+				Code:  http.StatusInternalServerError,
+				Error: "Setting bucket lifecycle failed: " + err.Error(),
+			})
+			return
+		}
+	}
+
+	c.IndentedJSON(http.StatusOK, models.LifecycleMessageResponse{
+		Message: "Lifecycle configuration saved",
+		Bucket:  bucket,
+		Mode:    mode,
+	})
+}
+
+// Get Bucket Lifecycle
+// @Summary Get the lifecycle configuration of a bucket
+// @Tags buckets
+// @Produce json
+// @Param name path string true "Bucket name"
+// @Success 200 {object} models.LifecycleResponse
+// @Failure 404 {object} models.ErrorResponse404
+// @Failure 500 {object} models.ErrorResponse500
+// @Failure 503 {object} models.ErrorResponse503
+// @Failure 504 {object} models.ErrorResponse504
+// @Router /bucket/{name}/lifecycle [get]
+func GetBucketLifecycle(c *gin.Context) {
+	bucket := c.Param("name")
+
+	if cfg, ok := lifecycle.Rules.Get(bucket); ok {
+		c.IndentedJSON(http.StatusOK, models.LifecycleResponse{
+			Bucket:    bucket,
+			Mode:      lifecycleModeGateway,
+			Lifecycle: cfg,
+		})
+		return
+	}
+	if config.Get().Lifecycle.Mode == lifecycleModeGateway {
+		c.IndentedJSON(http.StatusNotFound, models.ErrorResponse404{
+			Code:  http.StatusNotFound,
+			Error: "No lifecycle configuration for bucket " + bucket,
+		})
+		return
+	}
+
+	client, err := getMinioClient(bucket)
+	if err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, models.ErrorResponse500{
+			Code:  http.StatusInternalServerError,
+			Error: "Internal Server Error - Try again in sometime ",
+		})
+		return
+	}
+
+	ctx, cancel := opContext(c, config.Get().Timeouts.Other)
+	defer cancel()
+	cfg, err := client.GetBucketLifecycle(ctx, bucket)
+	if err != nil {
+		if backendError(c, err) {
+			return
+		}
+		code := minio.ToErrorResponse(err).Code
+		if code == "NoSuchLifecycleConfiguration" || code == "NoSuchBucket" || lifecycleNotSupported(err) {
+			c.IndentedJSON(http.StatusNotFound, models.ErrorResponse404{
+				Code:  http.StatusNotFound,
+				Error: "No lifecycle configuration for bucket " + bucket,
+			})
+			return
+		}
+		c.IndentedJSON(http.StatusInternalServerError, models.ErrorResponse500{
+			Code:  http.StatusInternalServerError,
+			Error: "Failed to get bucket lifecycle: " + err.Error(),
+		})
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, models.LifecycleResponse{
+		Bucket:    bucket,
+		Mode:      lifecycleModeNative,
+		Lifecycle: lifecycle.FromS3(cfg),
+	})
+}
+
+// Delete Bucket Lifecycle
+// @Summary Remove the lifecycle configuration of a bucket
+// @Tags buckets
+// @Produce json
+// @Param name path string true "Bucket name"
+// @Success 200 {object} models.LifecycleMessageResponse
+// @Failure 500 {object} models.ErrorResponse500
+// @Failure 503 {object} models.ErrorResponse503
+// @Failure 504 {object} models.ErrorResponse504
+// @Router /bucket/{name}/lifecycle [delete]
+func DeleteBucketLifecycle(c *gin.Context) {
+	bucket := c.Param("name")
+
+	err := lifecycle.Rules.Delete(bucket)
+	if err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, models.ErrorResponse500{
+			Code:  http.StatusInternalServerError,
+			Error: "Removing bucket lifecycle failed: " + err.Error(),
+		})
+		return
+	}
+
+	if config.Get().Lifecycle.Mode != lifecycleModeGateway {
+		client, err := getMinioClient(bucket)
+		if err != nil {
+			c.IndentedJSON(http.StatusInternalServerError, models.ErrorResponse500{
+				Code:  http.StatusInternalServerError,
+				Error: "Internal Server Error - Try again in sometime ",
+			})
+			return
+		}
+
+		// an empty configuration removes the lifecycle on the backend
+		ctx, cancel := opContext(c, config.Get().Timeouts.Other)
+		defer cancel()
+		err = client.SetBucketLifecycle(ctx, bucket, s3lifecycle.NewConfiguration())
+		if backendError(c, err) {
+			return
+		}
+		if err != nil && minio.ToErrorResponse(err).Code != "NoSuchLifecycleConfiguration" && !lifecycleNotSupported(err) {
+			c.IndentedJSON(http.StatusInternalServerError, models.ErrorResponse500{
+				Code:  http.StatusInternalServerError,
+				Error: "Removing bucket lifecycle failed: " + err.Error(),
+			})
+			return
+		}
+	}
+
+	c.IndentedJSON(http.StatusOK, models.LifecycleMessageResponse{
+		Message: "Lifecycle configuration removed",
+		Bucket:  bucket,
+		Mode:    config.Get().Lifecycle.Mode,
+	})
+}
+
+func lifecycleNotSupported(err error) bool {
+	resp := minio.ToErrorResponse(err)
+	return resp.StatusCode == http.StatusNotImplemented || resp.Code == "NotImplemented"
+}
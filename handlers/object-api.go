@@ -5,10 +5,18 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/encrypt"
+	"kluisz-object-storage/config"
+	"kluisz-object-storage/envelope"
+	"kluisz-object-storage/events"
+	"kluisz-object-storage/metrics"
 	"kluisz-object-storage/models"
-	"context"
+	"kluisz-object-storage/storage"
+	"errors"
 	"fmt"
 	"io"
+	"path"
+	"strconv"
 )
 
 
@@ -19,14 +27,34 @@ import (
 // @Produce plain
 // @Param bucket path string true "Bucket name"
 // @Param file formData file true "File to upload"
+// @Param retentionMode formData string false "Retention mode, GOVERNANCE or COMPLIANCE"
+// @Param retainUntilDate formData string false "Retention end, RFC 3339"
+// @Param legalHold formData string false "Legal hold, ON or OFF"
+// @Param X-Amz-Server-Side-Encryption header string false "AES256 (SSE-S3) or aws:kms (SSE-KMS)"
+// @Param X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id header string false "KMS key for aws:kms"
+// @Param X-Amz-Server-Side-Encryption-Customer-Algorithm header string false "AES256 for a customer provided key (SSE-C)"
+// @Param X-Amz-Server-Side-Encryption-Customer-Key header string false "Base64 encoded 256 bit SSE-C key"
+// @Param X-Amz-Server-Side-Encryption-Customer-Key-MD5 header string false "Base64 encoded MD5 of the SSE-C key"
 // @Success 200 {object} models.UploadFileResponse
 // @Failure 400 {object} models.ErrorResponse400
+// @Failure 403 {object} models.ErrorResponse403
+// @Failure 413 {object} models.ErrorResponse413
 // @Failure 500 {object} models.ErrorResponse500
+// @Failure 503 {object} models.ErrorResponse503
+// @Failure 504 {object} models.ErrorResponse504
 // @Router /upload/{bucket} [post]
 func UploadFile(c *gin.Context) {
 	bucket := c.Param("bucket")
 
 	file, header, err := c.Request.FormFile("file")
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		c.IndentedJSON(http.StatusRequestEntityTooLarge, models.ErrorResponse413{
+			Code:  http.StatusRequestEntityTooLarge,
+			Error: "Upload exceeds " + strconv.FormatInt(tooLarge.Limit, 10) + " bytes",
+		})
+		return
+	}
 	if err != nil {
 		c.IndentedJSON(http.StatusBadRequest, models.ErrorResponse400{
 			Code:  http.StatusBadRequest,
@@ -36,7 +64,39 @@ func UploadFile(c *gin.Context) {
 	}
 	defer file.Close()
 
-	client, err := getMinioClient()
+	opts := minio.PutObjectOptions{
+		ContentType: header.Header.Get("Content-Type"),
+	}
+	if err := setUploadLock(c, &opts); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, models.ErrorResponse400{
+			Code:  http.StatusBadRequest,
+			Error: "Bad Request- " + err.Error(),
+		})
+		return
+	}
+	opts.ServerSideEncryption, err = sseFromHeaders(c.Request.Header, true)
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, models.ErrorResponse400{
+			Code:  http.StatusBadRequest,
+			Error: "Bad Request- " + err.Error(),
+		})
+		return
+	}
+
+	var body io.Reader = file
+	size := header.Size
+	if envelope.Enabled() {
+		body, size, opts.UserMetadata, err = envelope.Key.Seal(file, header.Size, config.Get().Envelope.ChunkSize)
+		if err != nil {
+			c.IndentedJSON(http.StatusInternalServerError, models.ErrorResponse500{
+				Code:  http.StatusInternalServerError,
+				Error: "Upload Failed" + err.Error(),
+			})
+			return
+		}
+	}
+
+	client, err := getMinioClient(bucket)
 	if err != nil {
 		c.IndentedJSON(http.StatusInternalServerError, models.ErrorResponse500{
 			Code:  http.StatusInternalServerError,
@@ -45,10 +105,20 @@ func UploadFile(c *gin.Context) {
 		return
 	}
 
-	uploadInfo, err := client.PutObject(context.Background(), bucket, header.Filename, file, header.Size, minio.PutObjectOptions{
-		ContentType: header.Header.Get("Content-Type"),
-	})
+	ctx, cancel := opContext(c, config.Get().Timeouts.Put)
+	defer cancel()
+	uploadInfo, err := storage.Put(ctx, client, bucket, header.Filename, body, size, opts)
 	if err != nil {
+		if backendError(c, err) {
+			return
+		}
+		if objectLocked(err) {
+			c.IndentedJSON(http.StatusForbidden, models.ErrorResponse403{
+				Code:  http.StatusForbidden,
+				Error: "Object is protected by retention or legal hold and cannot be overwritten",
+			})
+			return
+		}
 		c.IndentedJSON(http.StatusInternalServerError, models.ErrorResponse500{
 			Code:  http.StatusInternalServerError,
 			Error: "Upload Failed" + err.Error(),
@@ -56,12 +126,24 @@ func UploadFile(c *gin.Context) {
 		return
 	}
 
+	metrics.BytesUploaded.WithLabelValues(bucket).Add(float64(header.Size))
+	metrics.UploadSize.Observe(float64(header.Size))
+	publish(c, events.Event{
+		Type:        events.ObjectCreated,
+		Bucket:      bucket,
+		Key:         header.Filename,
+		Size:        header.Size,
+		ETag:        uploadInfo.ETag,
+		VersionID:   uploadInfo.VersionID,
+		ContentType: opts.ContentType,
+	})
 	c.IndentedJSON(http.StatusOK, models.UploadFileResponse{
 		Message:   "File uploaded successfully",
 		File:      header.Filename,
-		Size:      uploadInfo.Size,
+		Size:      header.Size,
 		Bucket:    bucket,
 		ETag:      uploadInfo.ETag,
+		VersionID: uploadInfo.VersionID,
 	})
 }
 
@@ -71,35 +153,62 @@ func UploadFile(c *gin.Context) {
 // @Produce octet-stream
 // @Param bucket path string true "Bucket name"
 // @Param key path string true "Object key"
+// @Param X-Amz-Server-Side-Encryption-Customer-Algorithm header string false "AES256 when the object was uploaded with SSE-C"
+// @Param X-Amz-Server-Side-Encryption-Customer-Key header string false "Base64 encoded SSE-C key used for the upload"
+// @Param X-Amz-Server-Side-Encryption-Customer-Key-MD5 header string false "Base64 encoded MD5 of the SSE-C key"
+// @Param Range header string false "Single byte range, e.g. bytes=0-1023"
 // @Success 200 {file} file "File downloaded"
+// @Success 206 {file} file "Requested range of the file"
+// @Failure 400 {object} models.ErrorResponse400
 // @Failure 404 {object} models.ErrorResponse404
+// @Failure 416 {object} models.ErrorResponse416
 // @Failure 500 {object} models.ErrorResponse500
+// @Failure 503 {object} models.ErrorResponse503
+// @Failure 504 {object} models.ErrorResponse504
 // @Router /download/{bucket}/{key} [get]
 func DownloadFile(c *gin.Context) {
 	bucket := c.Param("bucket")
 	file := c.Param("file")
 
-	client, err := getMinioClient()
+	encryption, err := sseFromHeaders(c.Request.Header, false)
 	if err != nil {
-		c.IndentedJSON(http.StatusInternalServerError, models.ErrorResponse500{
-			Code:  http.StatusInternalServerError,
-			Error: "Internal Server Error - Try again in sometime ",
+		c.IndentedJSON(http.StatusBadRequest, models.ErrorResponse400{
+			Code:  http.StatusBadRequest,
+			Error: "Bad Request- " + err.Error(),
 		})
 		return
 	}
 
-	object, err := client.GetObject(context.Background(), bucket, file, minio.GetObjectOptions{})
+	client, err := getMinioClient(bucket)
 	if err != nil {
 		c.IndentedJSON(http.StatusInternalServerError, models.ErrorResponse500{
 			Code:  http.StatusInternalServerError,
-			Error: "Failed to get file ",
+			Error: "Internal Server Error - Try again in sometime ",
 		})
 		return
 	}
-	defer object.Close()
 
-	stat, err := object.Stat()
+	serveObject(c, client, bucket, file, encryption)
+}
+
+// serveObject -- streams an object to the client, honouring Range and decrypting
+// objects sealed by the gateway
+func serveObject(c *gin.Context, client *minio.Client, bucket, file string, encryption encrypt.ServerSide) {
+	ctx, cancel := opContext(c, config.Get().Timeouts.Stat)
+	defer cancel()
+	stat, err := client.StatObject(ctx, bucket, file, minio.StatObjectOptions{ServerSideEncryption: encryption})
 	if err != nil {
+		if backendError(c, err) {
+			return
+		}
+		// SSE-C objects cannot be read without (or with the wrong) customer key
+		if minio.ToErrorResponse(err).StatusCode == http.StatusBadRequest {
+			c.IndentedJSON(http.StatusBadRequest, models.ErrorResponse400{
+				Code:  http.StatusBadRequest,
+				Error: "Bad Request- " + err.Error(),
+			})
+			return
+		}
 		c.IndentedJSON(http.StatusNotFound,  models.ErrorResponse404{
 			Code:  http.StatusNotFound,
 			Error: "File not found ",
@@ -107,10 +216,83 @@ func DownloadFile(c *gin.Context) {
 		return
 	}
 
-	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", file))
+	// objects sealed by the gateway are decrypted here, the client sees the plaintext size
+	var sealed *envelope.Sealed
+	size := stat.Size
+	if envelope.IsSealed(stat.UserMetadata) {
+		if envelope.Key == nil {
+			c.IndentedJSON(http.StatusInternalServerError, models.ErrorResponse500{
+				Code:  http.StatusInternalServerError,
+				Error: "File is encrypted by the gateway but no master key is configured",
+			})
+			return
+		}
+		sealed, err = envelope.Key.Open(stat.UserMetadata)
+		if err != nil {
+			c.IndentedJSON(http.StatusInternalServerError, models.ErrorResponse500{
+				Code:  http.StatusInternalServerError,
+				Error: "Failed to decrypt file: " + err.Error(),
+			})
+			return
+		}
+		size = sealed.Size
+	}
+
+	start, end, partial, err := parseRange(c.GetHeader("Range"), size)
+	if err != nil {
+		c.Header("Content-Range", fmt.Sprintf("bytes */%d", size))
+		c.IndentedJSON(http.StatusRequestedRangeNotSatisfiable, models.ErrorResponse416{
+			Code:  http.StatusRequestedRangeNotSatisfiable,
+			Error: "Range Not Satisfiable - " + err.Error(),
+		})
+		return
+	}
+
+	opts := minio.GetObjectOptions{ServerSideEncryption: encryption}
+	if sealed != nil && size > 0 {
+		opts.SetRange(sealed.CipherRange(start, end))
+	} else if partial {
+		opts.SetRange(start, end)
+	}
+
+	// the stat timeout does not apply to the transfer
+	getCtx, cancelGet := opContext(c, config.Get().Timeouts.Get)
+	defer cancelGet()
+	object, err := client.GetObject(getCtx, bucket, file, opts)
+	if err != nil {
+		if backendError(c, err) {
+			return
+		}
+		c.IndentedJSON(http.StatusInternalServerError, models.ErrorResponse500{
+			Code:  http.StatusInternalServerError,
+			Error: "Failed to get file ",
+		})
+		return
+	}
+	defer object.Close()
+
+	var body io.Reader = object
+	if sealed != nil {
+		body = sealed.NewReader(object, start, end)
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", path.Base(file)))
 	c.Header("Content-Type", stat.ContentType)
+	c.Header("Content-Length", strconv.FormatInt(end-start+1, 10))
+	c.Header("Accept-Ranges", "bytes")
+	if alg := stat.Metadata.Get(encrypt.SseGenericHeader); alg != "" {
+		c.Header(encrypt.SseGenericHeader, alg)
+	}
+	if partial {
+		c.Header("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, size))
+		c.Status(http.StatusPartialContent)
+	}
 	c.Stream(func(w io.Writer) bool {
-		io.Copy(w, object)
+		n, err := io.Copy(w, body)
+		metrics.BytesDownloaded.WithLabelValues(bucket).Add(float64(n))
+		if err != nil {
+			c.Error(err)
+		}
 		return false
 	})
 }
@@ -123,11 +305,13 @@ func DownloadFile(c *gin.Context) {
 // @Param bucket path string true "Bucket name"
 // @Success 200 {object} models.ListObjectsResponse
 // @Failure 500 {object} models.ErrorResponse500
+// @Failure 503 {object} models.ErrorResponse503
+// @Failure 504 {object} models.ErrorResponse504
 // @Router /objects/{bucket} [get]
 func ListObjects(c *gin.Context) {
 	bucket := c.Param("bucket")
 
-	client, err := getMinioClient()
+	client, err := getMinioClient(bucket)
 	if err != nil {
 		c.IndentedJSON(http.StatusInternalServerError, models.ErrorResponse500{
 			Code:  http.StatusInternalServerError,
@@ -136,13 +320,18 @@ func ListObjects(c *gin.Context) {
 		return
 	}
 
-	objectCh := client.ListObjects(context.Background(), bucket, minio.ListObjectsOptions{
+	ctx, cancel := opContext(c, config.Get().Timeouts.List)
+	defer cancel()
+	objectCh := client.ListObjects(ctx, bucket, minio.ListObjectsOptions{
 		Recursive: true,
 	})
 
 	var objects []string
 	for object := range objectCh {
 		if object.Err != nil {
+			if backendError(c, object.Err) {
+				return
+			}
 			c.IndentedJSON(http.StatusInternalServerError, models.ErrorResponse500{
 				Code:  http.StatusInternalServerError,
 				Error: "Failed to list objects: " + object.Err.Error(),
@@ -161,14 +350,20 @@ func ListObjects(c *gin.Context) {
 // @Tags objects
 // @Param bucket path string true "Bucket name"
 // @Param file path string true "File name"
+// @Param versionId query string false "Version to delete permanently; without it a versioned bucket only gets a delete marker"
+// @Param bypassGovernance query bool false "Delete a version under governance-mode retention"
 // @Success 200 {object} models.DeleteObjectResponse
+// @Failure 403 {object} models.ErrorResponse403
 // @Failure 500 {object} models.ErrorResponse500
+// @Failure 503 {object} models.ErrorResponse503
+// @Failure 504 {object} models.ErrorResponse504
 // @Router /objects/{bucket}/{file} [delete]
 func DeleteObject(c *gin.Context) {
 	bucket := c.Param("bucket")
 	filename := c.Param("file")
+	versionID := c.Query("versionId")
 
-	client, err := getMinioClient()
+	client, err := getMinioClient(bucket)
 	if err != nil {
 		c.IndentedJSON(http.StatusInternalServerError, models.ErrorResponse500{
 			Code:  http.StatusInternalServerError,
@@ -177,8 +372,23 @@ func DeleteObject(c *gin.Context) {
 		return
 	}
 
-	err = client.RemoveObject(context.Background(), bucket, filename, minio.RemoveObjectOptions{})
+	ctx, cancel := opContext(c, config.Get().Timeouts.Other)
+	defer cancel()
+	err = client.RemoveObject(ctx, bucket, filename, minio.RemoveObjectOptions{
+		VersionID:        versionID,
+		GovernanceBypass: c.Query("bypassGovernance") == "true",
+	})
 	if err != nil {
+		if backendError(c, err) {
+			return
+		}
+		if objectLocked(err) {
+			c.IndentedJSON(http.StatusForbidden, models.ErrorResponse403{
+				Code:  http.StatusForbidden,
+				Error: "Object is protected by retention or legal hold and cannot be deleted",
+			})
+			return
+		}
 		c.IndentedJSON(http.StatusInternalServerError, models.ErrorResponse500{
 			Code:  http.StatusInternalServerError,
 			Error: "Object "+ err.Error(),
@@ -186,11 +396,36 @@ func DeleteObject(c *gin.Context) {
 		return
 	}
 
+	publish(c, events.Event{Type: events.ObjectRemoved, Bucket: bucket, Key: filename, VersionID: versionID})
 	c.IndentedJSON(http.StatusOK, models.DeleteObjectResponse{
-		Message: "File deleted",
-		Bucket:  bucket,
-		File:    filename,
+		Message:   "File deleted",
+		Bucket:    bucket,
+		File:      filename,
+		VersionID: versionID,
 	})
 }
 
+// DeleteObjects godoc
+// @Summary Delete every object under a prefix
+// @Description Runs as a background job, see GET /jobs/{id}; to empty a whole bucket submit a delete job through POST /jobs
+// @Tags objects
+// @Produce json
+// @Param bucket path string true "Bucket name"
+// @Param prefix query string true "Key prefix"
+// @Success 202 {object} models.Job
+// @Failure 400 {object} models.ErrorResponse400
+// @Failure 500 {object} models.ErrorResponse500
+// @Router /objects/{bucket} [delete]
+func DeleteObjects(c *gin.Context) {
+	prefix := c.Query("prefix")
+	if prefix == "" {
+		c.IndentedJSON(http.StatusBadRequest, models.ErrorResponse400{
+			Code:  http.StatusBadRequest,
+			Error: "Bad Request- prefix is required",
+		})
+		return
+	}
+	submitJob(c, "delete", models.DeleteJobParams{Bucket: c.Param("bucket"), Prefix: prefix})
+}
+
 
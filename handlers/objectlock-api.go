@@ -0,0 +1,471 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/minio/minio-go/v7"
+	"kluisz-object-storage/config"
+	"kluisz-object-storage/models"
+)
+
+// Get Bucket Object Lock
+// @Summary Get the object lock configuration and default retention of a bucket
+// @Tags buckets
+// @Produce json
+// @Param name path string true "Bucket name"
+// @Success 200 {object} models.ObjectLockConfigResponse
+// @Failure 500 {object} models.ErrorResponse500
+// @Failure 503 {object} models.ErrorResponse503
+// @Failure 504 {object} models.ErrorResponse504
+// @Router /bucket/{name}/object-lock [get]
+func GetBucketObjectLock(c *gin.Context) {
+	bucket := c.Param("name")
+
+	client, err := getMinioClient(bucket)
+	if err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, models.ErrorResponse500{
+			Code:  http.StatusInternalServerError,
+			Error: "Internal Server Error - Try again in sometime ",
+		})
+		return
+	}
+
+	ctx, cancel := opContext(c, config.Get().Timeouts.Other)
+	defer cancel()
+	enabled, mode, validity, unit, err := client.GetObjectLockConfig(ctx, bucket)
+	if err != nil {
+		if backendError(c, err) {
+			return
+		}
+		if minio.ToErrorResponse(err).Code == "ObjectLockConfigurationNotFoundError" {
+			c.IndentedJSON(http.StatusOK, models.ObjectLockConfigResponse{Bucket: bucket})
+			return
+		}
+		c.IndentedJSON(http.StatusInternalServerError, models.ErrorResponse500{
+			Code:  http.StatusInternalServerError,
+			Error: "Failed to get object lock configuration: " + err.Error(),
+		})
+		return
+	}
+
+	resp := models.ObjectLockConfigResponse{Bucket: bucket, Enabled: enabled == "Enabled"}
+	if mode != nil && validity != nil && unit != nil {
+		resp.DefaultRetention = &models.DefaultRetention{Mode: string(*mode)}
+		if *unit == minio.Years {
+			resp.DefaultRetention.Years = *validity
+		} else {
+			resp.DefaultRetention.Days = *validity
+		}
+	}
+	c.IndentedJSON(http.StatusOK, resp)
+}
+
+// Put Bucket Object Lock
+// @Summary Set or remove the default retention of a bucket created with object locking
+// @Tags buckets
+// @Accept json
+// @Produce json
+// @Param name path string true "Bucket name"
+// @Param request body models.ObjectLockConfigRequest true "Default retention, empty to remove it"
+// @Success 200 {object} models.ObjectLockConfigResponse
+// @Failure 400 {object} models.ErrorResponse400
+// @Failure 500 {object} models.ErrorResponse500
+// @Failure 503 {object} models.ErrorResponse503
+// @Failure 504 {object} models.ErrorResponse504
+// @Router /bucket/{name}/object-lock [put]
+func PutBucketObjectLock(c *gin.Context) {
+	bucket := c.Param("name")
+
+	var req models.ObjectLockConfigRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, models.ErrorResponse400{
+			Code:  http.StatusBadRequest,
+			Error: "Bad Request- Invalid object lock configuration: " + err.Error(),
+		})
+		return
+	}
+	if req.DefaultRetention != nil {
+		if err := validateDefaultRetention(*req.DefaultRetention); err != nil {
+			c.IndentedJSON(http.StatusBadRequest, models.ErrorResponse400{
+				Code:  http.StatusBadRequest,
+				Error: "Bad Request- Invalid object lock configuration: " + err.Error(),
+			})
+			return
+		}
+	}
+
+	client, err := getMinioClient(bucket)
+	if err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, models.ErrorResponse500{
+			Code:  http.StatusInternalServerError,
+			Error: "Internal Server Error - Try again in sometime ",
+		})
+		return
+	}
+
+	ctx, cancel := opContext(c, config.Get().Timeouts.Other)
+	defer cancel()
+	err = setDefaultRetention(ctx, client, bucket, req.DefaultRetention)
+	if err != nil {
+		if backendError(c, err) {
+			return
+		}
+		c.IndentedJSON(http.StatusInternalServerError, models.ErrorResponse500{
+			Code:  http.StatusInternalServerError,
+			Error: "Setting object lock configuration failed: " + err.Error(),
+		})
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, models.ObjectLockConfigResponse{
+		Bucket:           bucket,
+		Enabled:          true,
+		DefaultRetention: req.DefaultRetention,
+	})
+}
+
+// Get Object Retention
+// @Summary Get the retention of an object
+// @Tags objects
+// @Produce json
+// @Param bucket path string true "Bucket name"
+// @Param file path string true "File name"
+// @Param versionId query string false "Object version, latest when empty"
+// @Success 200 {object} models.ObjectRetentionResponse
+// @Failure 404 {object} models.ErrorResponse404
+// @Failure 500 {object} models.ErrorResponse500
+// @Failure 503 {object} models.ErrorResponse503
+// @Failure 504 {object} models.ErrorResponse504
+// @Router /objects/{bucket}/{file}/retention [get]
+func GetObjectRetention(c *gin.Context) {
+	bucket := c.Param("bucket")
+	filename := c.Param("file")
+	versionID := c.Query("versionId")
+
+	client, err := getMinioClient(bucket)
+	if err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, models.ErrorResponse500{
+			Code:  http.StatusInternalServerError,
+			Error: "Internal Server Error - Try again in sometime ",
+		})
+		return
+	}
+
+	ctx, cancel := opContext(c, config.Get().Timeouts.Stat)
+	defer cancel()
+	mode, until, err := client.GetObjectRetention(ctx, bucket, filename, versionID)
+	if err != nil {
+		if backendError(c, err) {
+			return
+		}
+		switch minio.ToErrorResponse(err).Code {
+		case "NoSuchObjectLockConfiguration":
+			c.IndentedJSON(http.StatusOK, models.ObjectRetentionResponse{Bucket: bucket, File: filename, VersionID: versionID})
+		case "NoSuchKey", "NoSuchVersion", "NoSuchBucket":
+			c.IndentedJSON(http.StatusNotFound, models.ErrorResponse404{
+				Code:  http.StatusNotFound,
+				Error: "File not found ",
+			})
+		default:
+			c.IndentedJSON(http.StatusInternalServerError, models.ErrorResponse500{
+				Code:  http.StatusInternalServerError,
+				Error: "Failed to get object retention: " + err.Error(),
+			})
+		}
+		return
+	}
+
+	resp := models.ObjectRetentionResponse{Bucket: bucket, File: filename, VersionID: versionID, RetainUntilDate: until}
+	if mode != nil {
+		resp.Mode = string(*mode)
+	}
+	c.IndentedJSON(http.StatusOK, resp)
+}
+
+// Put Object Retention
+// @Summary Set the retention of an object
+// @Description Compliance retention can only be extended; governance retention can be shortened with bypassGovernance
+// @Tags objects
+// @Accept json
+// @Produce json
+// @Param bucket path string true "Bucket name"
+// @Param file path string true "File name"
+// @Param request body models.ObjectRetentionRequest true "Retention mode and date"
+// @Success 200 {object} models.ObjectRetentionResponse
+// @Failure 400 {object} models.ErrorResponse400
+// @Failure 403 {object} models.ErrorResponse403
+// @Failure 500 {object} models.ErrorResponse500
+// @Failure 503 {object} models.ErrorResponse503
+// @Failure 504 {object} models.ErrorResponse504
+// @Router /objects/{bucket}/{file}/retention [put]
+func PutObjectRetention(c *gin.Context) {
+	bucket := c.Param("bucket")
+	filename := c.Param("file")
+
+	var req models.ObjectRetentionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, models.ErrorResponse400{
+			Code:  http.StatusBadRequest,
+			Error: "Bad Request- Invalid retention: " + err.Error(),
+		})
+		return
+	}
+	mode := minio.RetentionMode(strings.ToUpper(req.Mode))
+	if !mode.IsValid() {
+		c.IndentedJSON(http.StatusBadRequest, models.ErrorResponse400{
+			Code:  http.StatusBadRequest,
+			Error: "Bad Request- Retention mode must be GOVERNANCE or COMPLIANCE",
+		})
+		return
+	}
+	if !req.RetainUntilDate.After(time.Now()) {
+		c.IndentedJSON(http.StatusBadRequest, models.ErrorResponse400{
+			Code:  http.StatusBadRequest,
+			Error: "Bad Request- retainUntilDate must be in the future",
+		})
+		return
+	}
+
+	client, err := getMinioClient(bucket)
+	if err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, models.ErrorResponse500{
+			Code:  http.StatusInternalServerError,
+			Error: "Internal Server Error - Try again in sometime ",
+		})
+		return
+	}
+
+	until := req.RetainUntilDate.UTC()
+	ctx, cancel := opContext(c, config.Get().Timeouts.Other)
+	defer cancel()
+	err = client.PutObjectRetention(ctx, bucket, filename, minio.PutObjectRetentionOptions{
+		GovernanceBypass: req.BypassGovernance,
+		Mode:             &mode,
+		RetainUntilDate:  &until,
+		VersionID:        req.VersionID,
+	})
+	if err != nil {
+		if backendError(c, err) {
+			return
+		}
+		if objectLocked(err) || minio.ToErrorResponse(err).Code == "AccessDenied" {
+			c.IndentedJSON(http.StatusForbidden, models.ErrorResponse403{
+				Code:  http.StatusForbidden,
+				Error: "Retention cannot be changed: " + err.Error(),
+			})
+			return
+		}
+		c.IndentedJSON(http.StatusInternalServerError, models.ErrorResponse500{
+			Code:  http.StatusInternalServerError,
+			Error: "Setting object retention failed: " + err.Error(),
+		})
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, models.ObjectRetentionResponse{
+		Bucket:          bucket,
+		File:            filename,
+		VersionID:       req.VersionID,
+		Mode:            string(mode),
+		RetainUntilDate: &until,
+	})
+}
+
+// Get Object Legal Hold
+// @Summary Get the legal hold status of an object
+// @Tags objects
+// @Produce json
+// @Param bucket path string true "Bucket name"
+// @Param file path string true "File name"
+// @Param versionId query string false "Object version, latest when empty"
+// @Success 200 {object} models.LegalHoldResponse
+// @Failure 404 {object} models.ErrorResponse404
+// @Failure 500 {object} models.ErrorResponse500
+// @Failure 503 {object} models.ErrorResponse503
+// @Failure 504 {object} models.ErrorResponse504
+// @Router /objects/{bucket}/{file}/legal-hold [get]
+func GetObjectLegalHold(c *gin.Context) {
+	bucket := c.Param("bucket")
+	filename := c.Param("file")
+	versionID := c.Query("versionId")
+
+	client, err := getMinioClient(bucket)
+	if err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, models.ErrorResponse500{
+			Code:  http.StatusInternalServerError,
+			Error: "Internal Server Error - Try again in sometime ",
+		})
+		return
+	}
+
+	ctx, cancel := opContext(c, config.Get().Timeouts.Stat)
+	defer cancel()
+	status, err := client.GetObjectLegalHold(ctx, bucket, filename, minio.GetObjectLegalHoldOptions{VersionID: versionID})
+	if err != nil {
+		if backendError(c, err) {
+			return
+		}
+		switch minio.ToErrorResponse(err).Code {
+		case "NoSuchObjectLockConfiguration":
+			c.IndentedJSON(http.StatusOK, models.LegalHoldResponse{Bucket: bucket, File: filename, VersionID: versionID, Status: string(minio.LegalHoldDisabled)})
+		case "NoSuchKey", "NoSuchVersion", "NoSuchBucket":
+			c.IndentedJSON(http.StatusNotFound, models.ErrorResponse404{
+				Code:  http.StatusNotFound,
+				Error: "File not found ",
+			})
+		default:
+			c.IndentedJSON(http.StatusInternalServerError, models.ErrorResponse500{
+				Code:  http.StatusInternalServerError,
+				Error: "Failed to get legal hold: " + err.Error(),
+			})
+		}
+		return
+	}
+
+	resp := models.LegalHoldResponse{Bucket: bucket, File: filename, VersionID: versionID, Status: string(minio.LegalHoldDisabled)}
+	if status != nil {
+		resp.Status = string(*status)
+	}
+	c.IndentedJSON(http.StatusOK, resp)
+}
+
+// Put Object Legal Hold
+// @Summary Turn the legal hold of an object on or off
+// @Tags objects
+// @Accept json
+// @Produce json
+// @Param bucket path string true "Bucket name"
+// @Param file path string true "File name"
+// @Param request body models.LegalHoldRequest true "Legal hold status, ON or OFF"
+// @Success 200 {object} models.LegalHoldResponse
+// @Failure 400 {object} models.ErrorResponse400
+// @Failure 500 {object} models.ErrorResponse500
+// @Failure 503 {object} models.ErrorResponse503
+// @Failure 504 {object} models.ErrorResponse504
+// @Router /objects/{bucket}/{file}/legal-hold [put]
+func PutObjectLegalHold(c *gin.Context) {
+	bucket := c.Param("bucket")
+	filename := c.Param("file")
+
+	var req models.LegalHoldRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, models.ErrorResponse400{
+			Code:  http.StatusBadRequest,
+			Error: "Bad Request- Invalid legal hold: " + err.Error(),
+		})
+		return
+	}
+	status := minio.LegalHoldStatus(strings.ToUpper(req.Status))
+	if !status.IsValid() {
+		c.IndentedJSON(http.StatusBadRequest, models.ErrorResponse400{
+			Code:  http.StatusBadRequest,
+			Error: "Bad Request- Legal hold status must be ON or OFF",
+		})
+		return
+	}
+
+	client, err := getMinioClient(bucket)
+	if err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, models.ErrorResponse500{
+			Code:  http.StatusInternalServerError,
+			Error: "Internal Server Error - Try again in sometime ",
+		})
+		return
+	}
+
+	ctx, cancel := opContext(c, config.Get().Timeouts.Other)
+	defer cancel()
+	err = client.PutObjectLegalHold(ctx, bucket, filename, minio.PutObjectLegalHoldOptions{
+		VersionID: req.VersionID,
+		Status:    &status,
+	})
+	if err != nil {
+		if backendError(c, err) {
+			return
+		}
+		c.IndentedJSON(http.StatusInternalServerError, models.ErrorResponse500{
+			Code:  http.StatusInternalServerError,
+			Error: "Setting legal hold failed: " + err.Error(),
+		})
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, models.LegalHoldResponse{
+		Bucket:    bucket,
+		File:      filename,
+		VersionID: req.VersionID,
+		Status:    string(status),
+	})
+}
+
+func validateDefaultRetention(r models.DefaultRetention) error {
+	if !minio.RetentionMode(strings.ToUpper(r.Mode)).IsValid() {
+		return errors.New("retention mode must be GOVERNANCE or COMPLIANCE")
+	}
+	if (r.Days == 0) == (r.Years == 0) {
+		return errors.New("set either days or years for the default retention")
+	}
+	return nil
+}
+
+// setDefaultRetention -- nil removes the default retention, object locking itself stays enabled
+func setDefaultRetention(ctx context.Context, client *minio.Client, bucket string, r *models.DefaultRetention) error {
+	if r == nil {
+		return client.SetObjectLockConfig(ctx, bucket, nil, nil, nil)
+	}
+	mode := minio.RetentionMode(strings.ToUpper(r.Mode))
+	validity, unit := r.Days, minio.Days
+	if r.Years > 0 {
+		validity, unit = r.Years, minio.Years
+	}
+	return client.SetObjectLockConfig(ctx, bucket, &mode, &validity, &unit)
+}
+
+// objectLocked -- the backend refused a delete or overwrite because of retention or a legal hold
+func objectLocked(err error) bool {
+	resp := minio.ToErrorResponse(err)
+	if resp.Code == "ObjectLocked" {
+		return true
+	}
+	if resp.Code != "AccessDenied" && resp.Code != "InvalidRequest" {
+		return false
+	}
+	msg := strings.ToLower(resp.Message)
+	for _, hint := range []string{"worm", "retention", "legal hold", "object lock"} {
+		if strings.Contains(msg, hint) {
+			return true
+		}
+	}
+	return false
+}
+
+// setUploadLock -- per-object retention and legal hold taken from the upload form
+func setUploadLock(c *gin.Context, opts *minio.PutObjectOptions) error {
+	if mode := c.PostForm("retentionMode"); mode != "" {
+		opts.Mode = minio.RetentionMode(strings.ToUpper(mode))
+		if !opts.Mode.IsValid() {
+			return errors.New("retentionMode must be GOVERNANCE or COMPLIANCE")
+		}
+		until, err := time.Parse(time.RFC3339, c.PostForm("retainUntilDate"))
+		if err != nil || !until.After(time.Now()) {
+			return errors.New("retainUntilDate must be a future RFC 3339 date when retentionMode is set")
+		}
+		opts.RetainUntilDate = until.UTC()
+	}
+	if hold := c.PostForm("legalHold"); hold != "" {
+		opts.LegalHold = minio.LegalHoldStatus(strings.ToUpper(hold))
+		if !opts.LegalHold.IsValid() {
+			return errors.New("legalHold must be ON or OFF")
+		}
+	}
+	// locked uploads have to carry a Content-MD5 header
+	if opts.Mode != "" || opts.LegalHold != "" {
+		opts.SendContentMd5 = true
+	}
+	return nil
+}
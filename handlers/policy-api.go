@@ -0,0 +1,322 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/minio/minio-go/v7"
+	"kluisz-object-storage/config"
+	"kluisz-object-storage/models"
+	"kluisz-object-storage/policy"
+)
+
+// Get Bucket Policy
+// @Summary Get the policy of a bucket as raw JSON
+// @Tags buckets
+// @Produce json
+// @Param name path string true "Bucket name"
+// @Success 200 {object} policy.Document
+// @Failure 404 {object} models.ErrorResponse404
+// @Failure 500 {object} models.ErrorResponse500
+// @Failure 503 {object} models.ErrorResponse503
+// @Failure 504 {object} models.ErrorResponse504
+// @Router /bucket/{name}/policy [get]
+func GetBucketPolicy(c *gin.Context) {
+	bucket := c.Param("name")
+
+	client, err := getMinioClient(bucket)
+	if err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, models.ErrorResponse500{
+			Code:  http.StatusInternalServerError,
+			Error: "Internal Server Error - Try again in sometime ",
+		})
+		return
+	}
+
+	ctx, cancel := opContext(c, config.Get().Timeouts.Other)
+	defer cancel()
+	raw, err := client.GetBucketPolicy(ctx, bucket)
+	if err != nil {
+		if backendError(c, err) {
+			return
+		}
+		c.IndentedJSON(http.StatusInternalServerError, models.ErrorResponse500{
+			Code:  http.StatusInternalServerError,
+			Error: "Failed to get bucket policy: " + err.Error(),
+		})
+		return
+	}
+	if raw == "" {
+		c.IndentedJSON(http.StatusNotFound, models.ErrorResponse404{
+			Code:  http.StatusNotFound,
+			Error: "No policy for bucket " + bucket,
+		})
+		return
+	}
+	c.Data(http.StatusOK, "application/json; charset=utf-8", []byte(raw))
+}
+
+// Put Bucket Policy
+// @Summary Replace the policy of a bucket
+// @Description The policy is validated before it is sent to the backend; every problem found is reported
+// @Tags buckets
+// @Accept json
+// @Produce json
+// @Param name path string true "Bucket name"
+// @Param request body policy.Document true "Bucket policy"
+// @Success 200 {object} models.PublicReadResponse
+// @Failure 400 {object} models.ErrorResponse400
+// @Failure 500 {object} models.ErrorResponse500
+// @Failure 503 {object} models.ErrorResponse503
+// @Failure 504 {object} models.ErrorResponse504
+// @Router /bucket/{name}/policy [put]
+func PutBucketPolicy(c *gin.Context) {
+	bucket := c.Param("name")
+
+	raw, err := io.ReadAll(c.Request.Body)
+	if err == nil {
+		err = policy.Validate(bucket, raw)
+	}
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, models.ErrorResponse400{
+			Code:  http.StatusBadRequest,
+			Error: "Bad Request- Invalid bucket policy: " + err.Error(),
+		})
+		return
+	}
+
+	client, err := getMinioClient(bucket)
+	if err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, models.ErrorResponse500{
+			Code:  http.StatusInternalServerError,
+			Error: "Internal Server Error - Try again in sometime ",
+		})
+		return
+	}
+
+	ctx, cancel := opContext(c, config.Get().Timeouts.Other)
+	defer cancel()
+	err = client.SetBucketPolicy(ctx, bucket, string(raw))
+	if err != nil {
+		if backendError(c, err) {
+			return
+		}
+		c.IndentedJSON(http.StatusInternalServerError, models.ErrorResponse500{
+			Code:  http.StatusInternalServerError,
+			Error: "Setting bucket policy failed: " + err.Error(),
+		})
+		return
+	}
+
+	doc, _ := policy.Parse(raw)
+	c.IndentedJSON(http.StatusOK, models.PublicReadResponse{
+		Bucket:         bucket,
+		PublicPrefixes: policy.PublicPrefixes(doc, bucket),
+	})
+}
+
+// Delete Bucket Policy
+// @Summary Remove the policy of a bucket
+// @Tags buckets
+// @Produce json
+// @Param name path string true "Bucket name"
+// @Success 200 {object} models.BucketResponseD
+// @Failure 500 {object} models.ErrorResponse500
+// @Failure 503 {object} models.ErrorResponse503
+// @Failure 504 {object} models.ErrorResponse504
+// @Router /bucket/{name}/policy [delete]
+func DeleteBucketPolicy(c *gin.Context) {
+	bucket := c.Param("name")
+
+	client, err := getMinioClient(bucket)
+	if err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, models.ErrorResponse500{
+			Code:  http.StatusInternalServerError,
+			Error: "Internal Server Error - Try again in sometime ",
+		})
+		return
+	}
+
+	// an empty policy removes it on the backend
+	ctx, cancel := opContext(c, config.Get().Timeouts.Other)
+	defer cancel()
+	err = client.SetBucketPolicy(ctx, bucket, "")
+	if backendError(c, err) {
+		return
+	}
+	if err != nil && minio.ToErrorResponse(err).Code != minio.NoSuchBucketPolicy {
+		c.IndentedJSON(http.StatusInternalServerError, models.ErrorResponse500{
+			Code:  http.StatusInternalServerError,
+			Error: "Removing bucket policy failed: " + err.Error(),
+		})
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, models.BucketResponseD{
+		Message: "Bucket policy removed",
+		Bucket:  bucket,
+	})
+}
+
+// Add Public Read
+// @Summary Make objects below a prefix publicly readable
+// @Description Adds an anonymous s3:GetObject statement for the prefix to the bucket policy, other statements are kept
+// @Tags buckets
+// @Accept json
+// @Produce json
+// @Param name path string true "Bucket name"
+// @Param request body models.PublicReadRequest true "Prefix to publish"
+// @Success 200 {object} models.PublicReadResponse
+// @Failure 400 {object} models.ErrorResponse400
+// @Failure 500 {object} models.ErrorResponse500
+// @Failure 503 {object} models.ErrorResponse503
+// @Failure 504 {object} models.ErrorResponse504
+// @Router /bucket/{name}/policy/public-read [put]
+func AddPublicRead(c *gin.Context) {
+	bucket := c.Param("name")
+
+	var req models.PublicReadRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, models.ErrorResponse400{
+			Code:  http.StatusBadRequest,
+			Error: "Bad Request- " + err.Error(),
+		})
+		return
+	}
+
+	updatePublicRead(c, bucket, func(current string) (string, error) {
+		return policy.AddPublicRead(current, bucket, req.Prefix)
+	})
+}
+
+// Remove Public Read
+// @Summary Stop publishing objects below a prefix
+// @Tags buckets
+// @Produce json
+// @Param name path string true "Bucket name"
+// @Param prefix query string false "Prefix previously published"
+// @Success 200 {object} models.PublicReadResponse
+// @Failure 500 {object} models.ErrorResponse500
+// @Failure 503 {object} models.ErrorResponse503
+// @Failure 504 {object} models.ErrorResponse504
+// @Router /bucket/{name}/policy/public-read [delete]
+func RemovePublicRead(c *gin.Context) {
+	bucket := c.Param("name")
+	prefix := c.Query("prefix")
+
+	updatePublicRead(c, bucket, func(current string) (string, error) {
+		return policy.RemovePublicRead(current, prefix)
+	})
+}
+
+// updatePublicRead -- read-modify-write of the bucket policy
+func updatePublicRead(c *gin.Context, bucket string, update func(string) (string, error)) {
+	client, err := getMinioClient(bucket)
+	if err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, models.ErrorResponse500{
+			Code:  http.StatusInternalServerError,
+			Error: "Internal Server Error - Try again in sometime ",
+		})
+		return
+	}
+
+	ctx, cancel := opContext(c, config.Get().Timeouts.Other)
+	defer cancel()
+	current, err := client.GetBucketPolicy(ctx, bucket)
+	if err != nil {
+		if backendError(c, err) {
+			return
+		}
+		c.IndentedJSON(http.StatusInternalServerError, models.ErrorResponse500{
+			Code:  http.StatusInternalServerError,
+			Error: "Failed to get bucket policy: " + err.Error(),
+		})
+		return
+	}
+
+	updated, err := update(current)
+	if err == nil && updated != "" {
+		err = policy.Validate(bucket, []byte(updated))
+	}
+	if err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, models.ErrorResponse500{
+			Code:  http.StatusInternalServerError,
+			Error: "Current bucket policy cannot be updated: " + err.Error(),
+		})
+		return
+	}
+
+	err = client.SetBucketPolicy(ctx, bucket, updated)
+	if err != nil {
+		if backendError(c, err) {
+			return
+		}
+		c.IndentedJSON(http.StatusInternalServerError, models.ErrorResponse500{
+			Code:  http.StatusInternalServerError,
+			Error: "Setting bucket policy failed: " + err.Error(),
+		})
+		return
+	}
+
+	resp := models.PublicReadResponse{Bucket: bucket, PublicPrefixes: []string{}}
+	if doc, err := policy.Parse([]byte(updated)); updated != "" && err == nil {
+		resp.PublicPrefixes = policy.PublicPrefixes(doc, bucket)
+	}
+	c.IndentedJSON(http.StatusOK, resp)
+}
+
+// Public Download
+// @Summary Download an object without credentials
+// @Description Served only when the bucket policy grants anonymous s3:GetObject on the key
+// @Tags files
+// @Produce octet-stream
+// @Param bucket path string true "Bucket name"
+// @Param key path string true "Object key, may contain slashes"
+// @Param Range header string false "Single byte range, e.g. bytes=0-1023"
+// @Success 200 {file} file "File downloaded"
+// @Success 206 {file} file "Requested range of the file"
+// @Failure 403 {object} models.ErrorResponse403
+// @Failure 404 {object} models.ErrorResponse404
+// @Failure 500 {object} models.ErrorResponse500
+// @Failure 503 {object} models.ErrorResponse503
+// @Failure 504 {object} models.ErrorResponse504
+// @Router /public/{bucket}/{key} [get]
+func PublicDownload(c *gin.Context) {
+	bucket := c.Param("bucket")
+	key := c.Param("key")[1:]
+
+	client, err := getMinioClient(bucket)
+	if err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, models.ErrorResponse500{
+			Code:  http.StatusInternalServerError,
+			Error: "Internal Server Error - Try again in sometime ",
+		})
+		return
+	}
+
+	ctx, cancel := opContext(c, config.Get().Timeouts.Stat)
+	defer cancel()
+	raw, err := client.GetBucketPolicy(ctx, bucket)
+	if backendError(c, err) {
+		return
+	}
+	if err != nil && minio.ToErrorResponse(err).Code != "NoSuchBucket" {
+		c.IndentedJSON(http.StatusInternalServerError, models.ErrorResponse500{
+			Code:  http.StatusInternalServerError,
+			Error: "Failed to get bucket policy ",
+		})
+		return
+	}
+	// unknown buckets get the same answer as private ones, so bucket names cannot be probed
+	doc, perr := policy.Parse([]byte(raw))
+	if err != nil || raw == "" || perr != nil || !policy.AllowsAnonymousRead(doc, bucket, key) {
+		c.IndentedJSON(http.StatusForbidden, models.ErrorResponse403{
+			Code:  http.StatusForbidden,
+			Error: "Access Denied",
+		})
+		return
+	}
+
+	serveObject(c, client, bucket, key, nil)
+}
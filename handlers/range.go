@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// parseRange -- a single "bytes=" range of an object of the given size, both ends inclusive
+// an empty header selects the whole object and partial is false
+func parseRange(header string, size int64) (start, end int64, partial bool, err error) {
+	if header == "" {
+		return 0, size - 1, false, nil
+	}
+	spec, ok := strings.CutPrefix(header, "bytes=")
+	if !ok || strings.Contains(spec, ",") {
+		return 0, 0, false, errors.New("only a single bytes range is supported")
+	}
+	first, last, ok := strings.Cut(strings.TrimSpace(spec), "-")
+	if !ok {
+		return 0, 0, false, errors.New("malformed range")
+	}
+
+	if first == "" {
+		// suffix range, the last n bytes
+		n, err := strconv.ParseInt(last, 10, 64)
+		if err != nil || n <= 0 {
+			return 0, 0, false, errors.New("malformed range")
+		}
+		if n > size {
+			n = size
+		}
+		if n == 0 {
+			return 0, 0, false, errors.New("range outside of the object")
+		}
+		return size - n, size - 1, true, nil
+	}
+
+	start, err = strconv.ParseInt(first, 10, 64)
+	if err != nil || start < 0 {
+		return 0, 0, false, errors.New("malformed range")
+	}
+	end = size - 1
+	if last != "" {
+		end, err = strconv.ParseInt(last, 10, 64)
+		if err != nil || end < start {
+			return 0, 0, false, errors.New("malformed range")
+		}
+		if end > size-1 {
+			end = size - 1
+		}
+	}
+	if start >= size {
+		return 0, 0, false, errors.New("range outside of the object")
+	}
+	return start, end, true, nil
+}
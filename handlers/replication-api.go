@@ -0,0 +1,270 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"kluisz-object-storage/config"
+	"kluisz-object-storage/models"
+	"kluisz-object-storage/replication"
+	"kluisz-object-storage/storage"
+)
+
+// Create Replication Rule
+// @Summary Replicate a bucket's objects to another backend
+// @Description New and overwritten objects under the prefix are copied asynchronously after each upload; use the backfill endpoint for objects that already exist
+// @Tags replication
+// @Accept json
+// @Produce json
+// @Param request body models.ReplicationRuleRequest true "Replication rule"
+// @Success 200 {object} models.ReplicationRule
+// @Failure 400 {object} models.ErrorResponse400
+// @Failure 500 {object} models.ErrorResponse500
+// @Failure 503 {object} models.ErrorResponse503
+// @Failure 504 {object} models.ErrorResponse504
+// @Router /replication/rules [post]
+func CreateReplicationRule(c *gin.Context) {
+	var req models.ReplicationRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, models.ErrorResponse400{
+			Code:  http.StatusBadRequest,
+			Error: "Bad Request- Invalid replication rule: " + err.Error(),
+		})
+		return
+	}
+	if req.TargetBucket == "" {
+		req.TargetBucket = req.SourceBucket
+	}
+	cfg := config.Get()
+	var problem string
+	switch {
+	case req.SourceBucket == "":
+		problem = "sourceBucket is required"
+	case !isBackend(cfg, req.TargetBackend):
+		problem = "unknown targetBackend " + req.TargetBackend
+	case req.TargetBackend == cfg.Route(req.SourceBucket) && req.TargetBucket == req.SourceBucket:
+		problem = "target is the source bucket itself"
+	}
+	if problem != "" {
+		c.IndentedJSON(http.StatusBadRequest, models.ErrorResponse400{
+			Code:  http.StatusBadRequest,
+			Error: "Bad Request- " + problem,
+		})
+		return
+	}
+
+	target, err := storage.NewBackendClient(req.TargetBackend)
+	if err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, models.ErrorResponse500{
+			Code:  http.StatusInternalServerError,
+			Error: "Internal Server Error - Try again in sometime ",
+		})
+		return
+	}
+	ctx, cancel := opContext(c, cfg.Timeouts.Stat)
+	defer cancel()
+	exists, err := target.BucketExists(ctx, req.TargetBucket)
+	if err != nil {
+		if backendError(c, err) {
+			return
+		}
+		c.IndentedJSON(http.StatusInternalServerError, models.ErrorResponse500{
+			Code:  http.StatusInternalServerError,
+			Error: "Target bucket could not be checked: " + err.Error(),
+		})
+		return
+	}
+	if !exists {
+		c.IndentedJSON(http.StatusBadRequest, models.ErrorResponse400{
+			Code:  http.StatusBadRequest,
+			Error: "Bad Request- Target bucket " + req.TargetBucket + " does not exist on " + req.TargetBackend,
+		})
+		return
+	}
+
+	r := models.ReplicationRule{
+		ID:            uuid.New().String(),
+		SourceBucket:  req.SourceBucket,
+		Prefix:        req.Prefix,
+		TargetBackend: req.TargetBackend,
+		TargetBucket:  req.TargetBucket,
+		Deletes:       req.Deletes,
+		Created:       time.Now().UTC(),
+	}
+	if err := replication.Rules.Put(r.ID, r); err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, models.ErrorResponse500{
+			Code:  http.StatusInternalServerError,
+			Error: "Replication rule could not be saved: " + err.Error(),
+		})
+		return
+	}
+	c.IndentedJSON(http.StatusOK, r)
+}
+
+// List Replication Rules
+// @Summary List replication rules
+// @Tags replication
+// @Produce json
+// @Success 200 {object} models.ListReplicationRulesResponse
+// @Router /replication/rules [get]
+func ListReplicationRules(c *gin.Context) {
+	rules := []models.ReplicationRule{}
+	for _, r := range replication.Rules.All() {
+		rules = append(rules, r)
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].Created.Before(rules[j].Created) })
+	c.IndentedJSON(http.StatusOK, models.ListReplicationRulesResponse{Rules: rules})
+}
+
+// Get Replication Rule
+// @Summary Get a replication rule
+// @Tags replication
+// @Produce json
+// @Param id path string true "Rule ID"
+// @Success 200 {object} models.ReplicationRule
+// @Failure 404 {object} models.ErrorResponse404
+// @Router /replication/rules/{id} [get]
+func GetReplicationRule(c *gin.Context) {
+	r, ok := replication.Rules.Get(c.Param("id"))
+	if !ok {
+		c.IndentedJSON(http.StatusNotFound, models.ErrorResponse404{
+			Code:  http.StatusNotFound,
+			Error: "Replication rule not found",
+		})
+		return
+	}
+	c.IndentedJSON(http.StatusOK, r)
+}
+
+// Delete Replication Rule
+// @Summary Remove a replication rule, queued copies for it are dropped
+// @Tags replication
+// @Produce json
+// @Param id path string true "Rule ID"
+// @Success 200 {object} models.DeleteReplicationRuleResponse
+// @Failure 404 {object} models.ErrorResponse404
+// @Failure 500 {object} models.ErrorResponse500
+// @Router /replication/rules/{id} [delete]
+func DeleteReplicationRule(c *gin.Context) {
+	id := c.Param("id")
+	if _, ok := replication.Rules.Get(id); !ok {
+		c.IndentedJSON(http.StatusNotFound, models.ErrorResponse404{
+			Code:  http.StatusNotFound,
+			Error: "Replication rule not found",
+		})
+		return
+	}
+	if err := replication.Rules.Delete(id); err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, models.ErrorResponse500{
+			Code:  http.StatusInternalServerError,
+			Error: "Replication rule could not be deleted: " + err.Error(),
+		})
+		return
+	}
+	c.IndentedJSON(http.StatusOK, models.DeleteReplicationRuleResponse{
+		Message: "Replication rule deleted",
+		ID:      id,
+	})
+}
+
+// Backfill Replication Rule
+// @Summary Copy the objects that existed before the rule, or were missed
+// @Description Lists the source bucket under the rule's prefix and queues every object that is missing at the target or differs from it; progress is shown by GET /replication/status and by the job
+// @Tags replication
+// @Produce json
+// @Param id path string true "Rule ID"
+// @Success 202 {object} models.BackfillStartedResponse
+// @Failure 404 {object} models.ErrorResponse404
+// @Failure 409 {object} models.ErrorResponse409
+// @Failure 500 {object} models.ErrorResponse500
+// @Router /replication/rules/{id}/backfill [post]
+func BackfillReplicationRule(c *gin.Context) {
+	r, ok := replication.Rules.Get(c.Param("id"))
+	if !ok {
+		c.IndentedJSON(http.StatusNotFound, models.ErrorResponse404{
+			Code:  http.StatusNotFound,
+			Error: "Replication rule not found",
+		})
+		return
+	}
+	job, err := replication.Backfill(r)
+	if errors.Is(err, replication.ErrBackfillRunning) {
+		c.IndentedJSON(http.StatusConflict, models.ErrorResponse409{
+			Code:  http.StatusConflict,
+			Error: "Conflict- " + err.Error() + ", job " + job.ID,
+		})
+		return
+	}
+	if err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, models.ErrorResponse500{
+			Code:  http.StatusInternalServerError,
+			Error: "Backfill could not be started: " + err.Error(),
+		})
+		return
+	}
+	c.IndentedJSON(http.StatusAccepted, models.BackfillStartedResponse{
+		Message: "Backfill started",
+		ID:      r.ID,
+		Job:     job.ID,
+	})
+}
+
+// Replication Status
+// @Summary Lag, progress and failures of every replication rule
+// @Tags replication
+// @Produce json
+// @Success 200 {object} models.ReplicationStatus
+// @Failure 500 {object} models.ErrorResponse500
+// @Router /replication/status [get]
+func ReplicationStatus(c *gin.Context) {
+	status, err := replication.Status()
+	if err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, models.ErrorResponse500{
+			Code:  http.StatusInternalServerError,
+			Error: "Replication status unavailable: " + err.Error(),
+		})
+		return
+	}
+	c.IndentedJSON(http.StatusOK, status)
+}
+
+// Object Replication Status
+// @Summary Replication state of one object for each rule covering it
+// @Description pending and failed come from the queue, otherwise the target is checked: replicated or missing
+// @Tags replication
+// @Produce json
+// @Param bucket path string true "Source bucket"
+// @Param key path string true "Object key"
+// @Success 200 {object} models.ObjectReplicationStatus
+// @Failure 500 {object} models.ErrorResponse500
+// @Failure 503 {object} models.ErrorResponse503
+// @Failure 504 {object} models.ErrorResponse504
+// @Router /replication/status/{bucket}/{key} [get]
+func ObjectReplicationStatus(c *gin.Context) {
+	bucket := c.Param("bucket")
+	key := c.Param("key")[1:]
+
+	ctx, cancel := opContext(c, config.Get().Timeouts.Stat)
+	defer cancel()
+	status, err := replication.ObjectStatus(ctx, bucket, key)
+	if err != nil {
+		if backendError(c, err) {
+			return
+		}
+		c.IndentedJSON(http.StatusInternalServerError, models.ErrorResponse500{
+			Code:  http.StatusInternalServerError,
+			Error: "Replication status unavailable: " + err.Error(),
+		})
+		return
+	}
+	c.IndentedJSON(http.StatusOK, status)
+}
+
+func isBackend(cfg *config.Config, name string) bool {
+	_, ok := cfg.Backend(name)
+	return ok
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"kluisz-object-storage/config"
+	"kluisz-object-storage/jobs"
+	"kluisz-object-storage/models"
+	"kluisz-object-storage/syncer"
+)
+
+// Start Sync Job
+// @Summary Sync a local directory or a bucket prefix into a bucket
+// @Description Only new and changed objects are transferred, compared by size and modification time or, with checksum, by MD5; with delete, destination keys missing from the source are removed. Local directories must be under one of the configured sync.allowedDirs. Runs as a background job, whose progress and report are shown by GET /jobs/{id}
+// @Tags sync
+// @Accept json
+// @Produce json
+// @Param request body models.SyncRequest true "Sync job"
+// @Success 202 {object} models.Job
+// @Failure 400 {object} models.ErrorResponse400
+// @Failure 403 {object} models.ErrorResponse403
+// @Failure 500 {object} models.ErrorResponse500
+// @Failure 503 {object} models.ErrorResponse503
+// @Failure 504 {object} models.ErrorResponse504
+// @Router /sync/jobs [post]
+func StartSyncJob(c *gin.Context) {
+	var req models.SyncRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, models.ErrorResponse400{
+			Code:  http.StatusBadRequest,
+			Error: "Bad Request- Invalid sync job: " + err.Error(),
+		})
+		return
+	}
+	var buckets []string
+	for _, loc := range []string{req.Source, req.Destination} {
+		if l, err := syncer.ParseLocation(loc); err == nil && l.Bucket != "" {
+			buckets = append(buckets, l.Bucket)
+		}
+	}
+	for _, bucket := range buckets {
+		minioClient, err := getMinioClient(bucket)
+		if err != nil {
+			c.IndentedJSON(http.StatusInternalServerError, models.ErrorResponse500{
+				Code:  http.StatusInternalServerError,
+				Error: "Internal Server Error - Try again in sometime ",
+			})
+			return
+		}
+		ctx, cancel := opContext(c, config.Get().Timeouts.Stat)
+		exists, err := minioClient.BucketExists(ctx, bucket)
+		cancel()
+		if err != nil {
+			if backendError(c, err) {
+				return
+			}
+			c.IndentedJSON(http.StatusInternalServerError, models.ErrorResponse500{
+				Code:  http.StatusInternalServerError,
+				Error: "Bucket could not be checked: " + err.Error(),
+			})
+			return
+		}
+		if !exists {
+			c.IndentedJSON(http.StatusBadRequest, models.ErrorResponse400{
+				Code:  http.StatusBadRequest,
+				Error: "Bad Request- Bucket " + bucket + " does not exist",
+			})
+			return
+		}
+	}
+
+	job, err := jobs.Submit("sync", req)
+	switch {
+	case errors.Is(err, syncer.ErrNotAllowed):
+		c.IndentedJSON(http.StatusForbidden, models.ErrorResponse403{
+			Code:  http.StatusForbidden,
+			Error: "Forbidden- " + req.Source + " is not under a directory in sync.allowedDirs",
+		})
+	case errors.Is(err, jobs.ErrInvalid):
+		c.IndentedJSON(http.StatusBadRequest, models.ErrorResponse400{
+			Code:  http.StatusBadRequest,
+			Error: "Bad Request- " + err.Error(),
+		})
+	case err != nil:
+		c.IndentedJSON(http.StatusInternalServerError, models.ErrorResponse500{
+			Code:  http.StatusInternalServerError,
+			Error: "Sync job could not be saved: " + err.Error(),
+		})
+	default:
+		c.IndentedJSON(http.StatusAccepted, job)
+	}
+}
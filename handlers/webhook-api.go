@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"kluisz-object-storage/events"
+	"kluisz-object-storage/models"
+)
+
+// Create Webhook
+// @Summary Subscribe a URL to bucket and object events
+// @Description Deliveries are POSTed as JSON, signed with X-Gateway-Signature: sha256=HMAC(secret, timestamp + "." + body), and retried with backoff until acknowledged with a 2xx
+// @Tags events
+// @Accept json
+// @Produce json
+// @Param request body models.WebhookRequest true "Webhook subscription"
+// @Success 200 {object} models.Webhook
+// @Failure 400 {object} models.ErrorResponse400
+// @Failure 500 {object} models.ErrorResponse500
+// @Router /webhooks [post]
+func CreateWebhook(c *gin.Context) {
+	var req models.WebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, models.ErrorResponse400{
+			Code:  http.StatusBadRequest,
+			Error: "Bad Request- Invalid webhook: " + err.Error(),
+		})
+		return
+	}
+	u, err := url.Parse(req.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		c.IndentedJSON(http.StatusBadRequest, models.ErrorResponse400{
+			Code:  http.StatusBadRequest,
+			Error: "Bad Request- url must be an absolute http or https URL",
+		})
+		return
+	}
+	for _, t := range req.Events {
+		if !events.Type(t).Valid() {
+			c.IndentedJSON(http.StatusBadRequest, models.ErrorResponse400{
+				Code:  http.StatusBadRequest,
+				Error: "Bad Request- Unknown event type " + t,
+			})
+			return
+		}
+	}
+
+	secret := req.Secret
+	if secret == "" {
+		buf := make([]byte, 32)
+		rand.Read(buf)
+		secret = hex.EncodeToString(buf)
+	}
+	w := models.Webhook{
+		ID:      uuid.New().String(),
+		URL:     req.URL,
+		Secret:  secret,
+		Events:  req.Events,
+		Bucket:  req.Bucket,
+		Prefix:  req.Prefix,
+		Suffix:  req.Suffix,
+		Created: time.Now().UTC(),
+	}
+	err = events.Webhooks.Put(w.ID, w)
+	if err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, models.ErrorResponse500{
+			Code:  http.StatusInternalServerError,
+			Error: "Webhook could not be saved: " + err.Error(),
+		})
+		return
+	}
+	c.IndentedJSON(http.StatusOK, w)
+}
+
+// List Webhooks
+// @Summary List webhook subscriptions
+// @Tags events
+// @Produce json
+// @Success 200 {object} models.ListWebhooksResponse
+// @Router /webhooks [get]
+func ListWebhooks(c *gin.Context) {
+	hooks := []models.Webhook{}
+	for _, w := range events.Webhooks.All() {
+		w.Secret = ""
+		hooks = append(hooks, w)
+	}
+	sort.Slice(hooks, func(i, j int) bool { return hooks[i].Created.Before(hooks[j].Created) })
+	c.IndentedJSON(http.StatusOK, models.ListWebhooksResponse{Webhooks: hooks})
+}
+
+// Get Webhook
+// @Summary Get a webhook subscription
+// @Tags events
+// @Produce json
+// @Param id path string true "Webhook ID"
+// @Success 200 {object} models.Webhook
+// @Failure 404 {object} models.ErrorResponse404
+// @Router /webhooks/{id} [get]
+func GetWebhook(c *gin.Context) {
+	w, ok := events.Webhooks.Get(c.Param("id"))
+	if !ok {
+		c.IndentedJSON(http.StatusNotFound, models.ErrorResponse404{
+			Code:  http.StatusNotFound,
+			Error: "Webhook not found",
+		})
+		return
+	}
+	w.Secret = ""
+	c.IndentedJSON(http.StatusOK, w)
+}
+
+// Delete Webhook
+// @Summary Remove a webhook subscription, pending deliveries to it are dropped
+// @Tags events
+// @Produce json
+// @Param id path string true "Webhook ID"
+// @Success 200 {object} models.DeleteWebhookResponse
+// @Failure 404 {object} models.ErrorResponse404
+// @Failure 500 {object} models.ErrorResponse500
+// @Router /webhooks/{id} [delete]
+func DeleteWebhook(c *gin.Context) {
+	id := c.Param("id")
+	if _, ok := events.Webhooks.Get(id); !ok {
+		c.IndentedJSON(http.StatusNotFound, models.ErrorResponse404{
+			Code:  http.StatusNotFound,
+			Error: "Webhook not found",
+		})
+		return
+	}
+	err := events.Webhooks.Delete(id)
+	if err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, models.ErrorResponse500{
+			Code:  http.StatusInternalServerError,
+			Error: "Webhook could not be deleted: " + err.Error(),
+		})
+		return
+	}
+	c.IndentedJSON(http.StatusOK, models.DeleteWebhookResponse{
+		Message: "Webhook deleted",
+		ID:      id,
+	})
+}
+
+// publish -- emits an event for a change made through the API
+func publish(c *gin.Context, e events.Event) {
+	e.Source = events.SourceAPI
+	e.RequestID = c.GetString("RequestID")
+	events.Publish(e)
+}
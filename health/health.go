@@ -0,0 +1,161 @@
+// Package health runs the readiness checks behind /readyz and /health.
+package health
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"kluisz-object-storage/config"
+	"kluisz-object-storage/models"
+	"kluisz-object-storage/storage"
+)
+
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+	// StatusWarn -- a failed check that readiness does not depend on
+	StatusWarn = "warn"
+)
+
+var draining atomic.Bool
+
+// Drain -- marks the gateway as shutting down, readiness fails from now on so load
+// balancers stop sending new requests while in-flight ones finish
+func Drain() {
+	draining.Store(true)
+}
+
+func Draining() bool {
+	return draining.Load()
+}
+
+// Check -- returns a short detail on success
+type Check func(ctx context.Context) (detail string, err error)
+
+// Checks -- what the report covers, by name
+func Checks() map[string]Check {
+	checks := map[string]Check{
+		"config":   checkConfig,
+		"backend":  checkBackend(config.DefaultBackend),
+		"logDisk":  checkDisk(filepath.Dir(config.Get().Logging.File)),
+		"dataDisk": checkDisk(config.Get().DataDir),
+	}
+	// further backends each get their own check
+	for name := range config.Get().Backends {
+		checks["backend:"+name] = checkBackend(name)
+	}
+	return checks
+}
+
+// Required -- whether the named check failing fails readiness; a backend that is not
+// in health.requiredBackends only serves the buckets routed to it, so the gateway
+// stays ready while it is down
+func Required(cfg *config.Config, check string) bool {
+	backend, ok := strings.CutPrefix(check, "backend:")
+	if check == "backend" {
+		backend, ok = config.DefaultBackend, true
+	}
+	return !ok || slices.Contains(cfg.Health.RequiredBackends, backend)
+}
+
+// Run -- runs every check concurrently, each bounded by the configured timeout
+func Run(ctx context.Context) models.HealthResponse {
+	cfg := config.Get()
+	checks := Checks()
+	report := models.HealthResponse{Status: StatusOK, Checks: make(map[string]models.CheckResult, len(checks)+1)}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for name, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, cfg.Health.Timeout)
+			defer cancel()
+			start := time.Now()
+			detail, err := check(ctx)
+			result := models.CheckResult{
+				Status:    StatusOK,
+				LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+				Detail:    detail,
+			}
+			if err != nil {
+				result.Status = StatusFail
+				if !Required(cfg, name) {
+					result.Status = StatusWarn
+				}
+				result.Error = err.Error()
+			}
+			mu.Lock()
+			report.Checks[name] = result
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	if Draining() {
+		report.Checks["shutdown"] = models.CheckResult{Status: StatusFail, Error: "gateway is shutting down"}
+	}
+	for _, r := range report.Checks {
+		if r.Status == StatusFail {
+			report.Status = StatusFail
+		}
+	}
+	return report
+}
+
+func checkConfig(ctx context.Context) (string, error) {
+	cfg := config.Get()
+	for _, name := range cfg.BackendNames() {
+		if b, _ := cfg.Backend(name); b.Endpoint == "" {
+			return "", fmt.Errorf("no endpoint configured for backend %s", name)
+		}
+	}
+	return cfg.S3.Endpoint, nil
+}
+
+// checkBackend -- lists the named backend's buckets
+func checkBackend(name string) Check {
+	return func(ctx context.Context) (string, error) {
+		b, ok := config.Get().Backend(name)
+		if !ok {
+			return "", fmt.Errorf("backend %s is no longer configured", name)
+		}
+		client, err := storage.NewBackendClient(name)
+		if err != nil {
+			return "", err
+		}
+		circuit := "circuit " + storage.BreakerFor(b.Endpoint).State()
+		buckets, err := client.ListBuckets(ctx)
+		if err != nil {
+			return circuit, err
+		}
+		return fmt.Sprintf("%d buckets, %s", len(buckets), circuit), nil
+	}
+}
+
+func checkDisk(dir string) Check {
+	return func(ctx context.Context) (string, error) {
+		// the directory may not be created yet, its volume is what matters
+		at := filepath.Clean(dir)
+		for _, err := os.Stat(at); os.IsNotExist(err) && at != filepath.Dir(at); _, err = os.Stat(at) {
+			at = filepath.Dir(at)
+		}
+		free, err := freeBytes(at)
+		if err != nil {
+			return "", err
+		}
+		detail := fmt.Sprintf("%.1f GiB free", float64(free)/(1<<30))
+		if free < config.Get().Health.MinFreeBytes {
+			return detail, fmt.Errorf("only %d bytes free in %s", free, dir)
+		}
+		return detail, nil
+	}
+}
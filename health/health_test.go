@@ -0,0 +1,31 @@
+package health
+
+import (
+	"testing"
+
+	"kluisz-object-storage/config"
+)
+
+func TestRequired(t *testing.T) {
+	tests := []struct {
+		name     string
+		required []string
+		check    string
+		want     bool
+	}{
+		{"default backend", []string{"default"}, "backend", true},
+		{"further backend", []string{"default"}, "backend:dr", false},
+		{"listed backend", []string{"default", "dr"}, "backend:dr", true},
+		{"default not listed", []string{"dr"}, "backend", false},
+		{"disk", []string{"dr"}, "dataDisk", true},
+		{"config", nil, "config", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{Health: config.HealthConfig{RequiredBackends: tt.required}}
+			if got := Required(cfg, tt.check); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
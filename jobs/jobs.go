@@ -0,0 +1,332 @@
+// Package jobs runs bulk operations that outlast a request -- prefix copies and
+// deletes, bucket deletion, syncs, replication backfills -- on a pool of workers.
+// Jobs are kept in a JSON file with their progress and result until the retention
+// passes, and jobs interrupted by a restart are run again from the start.
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"kluisz-object-storage/config"
+	"kluisz-object-storage/jsonstore"
+	"kluisz-object-storage/models"
+)
+
+// Kind -- a type of job. Run may be started again after a restart, so it must be safe
+// to repeat; it stops when ctx is cancelled, by Cancel or at shutdown.
+type Kind struct {
+	// validates the parameters when the job is submitted, may be nil
+	Check func(params json.RawMessage) error
+	// does the work, with the parameters in job.Params; the result is stored with the job
+	Run func(ctx context.Context, job models.Job, p *Progress) (result any, err error)
+}
+
+var (
+	ErrUnknownType = errors.New("unknown job type")
+	ErrInvalid     = errors.New("invalid job parameters")
+	ErrNotFound    = errors.New("job not found")
+	ErrFinished    = errors.New("job already finished")
+)
+
+// running -- a job being worked on
+type running struct {
+	cancel    context.CancelFunc
+	progress  *Progress
+	cancelled bool
+}
+
+var (
+	mu      sync.Mutex
+	kinds   = make(map[string]Kind)
+	store   *jsonstore.Store[models.Job]
+	pending []string
+	active  = make(map[string]*running)
+	wake    = make(chan struct{}, 1)
+	// what jobs run under, both set by Start
+	background = context.Background()
+	logger     = zap.NewNop()
+)
+
+// Register -- makes jobs of type typ runnable; call before Start
+func Register(typ string, k Kind) {
+	mu.Lock()
+	defer mu.Unlock()
+	kinds[typ] = k
+}
+
+// Types -- the registered job types, sorted
+func Types() []string {
+	mu.Lock()
+	defer mu.Unlock()
+	types := make([]string, 0, len(kinds))
+	for t := range kinds {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// Start -- loads the kept jobs, queues those that had not finished and starts the
+// workers, which run until ctx is cancelled
+func Start(ctx context.Context, log *zap.Logger) error {
+	s, err := jsonstore.Open[models.Job](filepath.Join(config.Get().DataDir, "jobs.json"))
+	if err != nil {
+		return err
+	}
+	mu.Lock()
+	store, background, logger = s, ctx, log
+	var unfinished []models.Job
+	for _, j := range store.All() {
+		if j.Status == models.JobQueued || j.Status == models.JobRunning {
+			unfinished = append(unfinished, j)
+		}
+	}
+	sort.Slice(unfinished, func(a, b int) bool { return unfinished[a].Created.Before(unfinished[b].Created) })
+	for _, j := range unfinished {
+		if j.Status == models.JobRunning {
+			j.Status, j.Started, j.Restarts = models.JobQueued, nil, j.Restarts+1
+			j.Progress, j.Failures = models.JobProgress{}, nil
+			if err := store.Put(j.ID, j); err != nil {
+				mu.Unlock()
+				return err
+			}
+			logger.Info("Job interrupted by a restart, queued again", zap.String("job", j.ID), zap.String("type", j.Type))
+		}
+		pending = append(pending, j.ID)
+	}
+	mu.Unlock()
+
+	for i := 0; i < config.Get().Jobs.Workers; i++ {
+		go worker(ctx)
+	}
+	go retain(ctx)
+	signal()
+	return nil
+}
+
+// Submit -- queues a job of type typ; params is marshalled to JSON
+func Submit(typ string, params any) (models.Job, error) {
+	raw, err := json.Marshal(params)
+	if err != nil {
+		return models.Job{}, fmt.Errorf("%w: %w", ErrInvalid, err)
+	}
+	mu.Lock()
+	k, ok := kinds[typ]
+	mu.Unlock()
+	if !ok {
+		return models.Job{}, fmt.Errorf("%w %q, one of %s", ErrUnknownType, typ, strings.Join(Types(), ", "))
+	}
+	if k.Check != nil {
+		if err := k.Check(raw); err != nil {
+			return models.Job{}, fmt.Errorf("%w: %w", ErrInvalid, err)
+		}
+	}
+
+	j := models.Job{
+		ID:      uuid.New().String(),
+		Type:    typ,
+		Status:  models.JobQueued,
+		Params:  raw,
+		Created: time.Now().UTC(),
+	}
+	mu.Lock()
+	err = store.Put(j.ID, j)
+	if err == nil {
+		pending = append(pending, j.ID)
+	}
+	mu.Unlock()
+	if err != nil {
+		return models.Job{}, err
+	}
+	signal()
+	return j, nil
+}
+
+// Get -- the job with the progress of a running one as of now
+func Get(id string) (models.Job, bool) {
+	mu.Lock()
+	defer mu.Unlock()
+	j, ok := store.Get(id)
+	if ok {
+		j = live(j)
+	}
+	return j, ok
+}
+
+// List -- every kept job, the most recent first
+func List() []models.Job {
+	mu.Lock()
+	defer mu.Unlock()
+	all := store.All()
+	out := make([]models.Job, 0, len(all))
+	for _, j := range all {
+		out = append(out, live(j))
+	}
+	sort.Slice(out, func(a, b int) bool { return out[a].Created.After(out[b].Created) })
+	return out
+}
+
+// Cancel -- a queued job is cancelled at once, a running one stops at its next item;
+// work already done is not undone
+func Cancel(id string) (models.Job, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	j, ok := store.Get(id)
+	if !ok {
+		return j, ErrNotFound
+	}
+	switch j.Status {
+	case models.JobQueued:
+		finished := time.Now().UTC()
+		j.Status, j.Finished = models.JobCancelled, &finished
+		pending = slices.DeleteFunc(pending, func(p string) bool { return p == id })
+		if err := store.Put(id, j); err != nil {
+			return j, err
+		}
+		logger.Info("Job cancelled", zap.String("job", id), zap.String("type", j.Type))
+	case models.JobRunning:
+		if r, ok := active[id]; ok {
+			r.cancelled = true
+			r.cancel()
+		}
+		j = live(j)
+	default:
+		return j, ErrFinished
+	}
+	return j, nil
+}
+
+// live -- j with the current progress if it is running; mu must be held
+func live(j models.Job) models.Job {
+	if r, ok := active[j.ID]; ok {
+		j.Progress, j.Failures = r.progress.snapshot()
+	}
+	return j
+}
+
+func signal() {
+	select {
+	case wake <- struct{}{}:
+	default:
+	}
+}
+
+// worker -- runs queued jobs until ctx, the one it was started with, is cancelled
+func worker(ctx context.Context) {
+	for {
+		id, ok := next(ctx)
+		if ok {
+			execute(id)
+			continue
+		}
+		select {
+		case <-wake:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func next(ctx context.Context) (string, bool) {
+	mu.Lock()
+	defer mu.Unlock()
+	if len(pending) == 0 || ctx.Err() != nil {
+		return "", false
+	}
+	id := pending[0]
+	pending = pending[1:]
+	return id, true
+}
+
+func execute(id string) {
+	mu.Lock()
+	j, ok := store.Get(id)
+	if !ok || j.Status != models.JobQueued {
+		mu.Unlock()
+		return
+	}
+	k, known := kinds[j.Type]
+	ctx, cancel := context.WithCancel(background)
+	defer cancel()
+	r := &running{cancel: cancel, progress: &Progress{}}
+	active[id] = r
+	started := time.Now().UTC()
+	j.Status, j.Started = models.JobRunning, &started
+	if err := store.Put(id, j); err != nil {
+		logger.Error("Saving job failed", zap.String("job", id), zap.Error(err))
+	}
+	mu.Unlock()
+	logger.Info("Job started", zap.String("job", id), zap.String("type", j.Type))
+
+	var result any
+	err := fmt.Errorf("%w %q", ErrUnknownType, j.Type)
+	if known {
+		result, err = k.Run(ctx, j, r.progress)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	delete(active, id)
+	j.Progress, j.Failures = r.progress.snapshot()
+	if result != nil {
+		if data, merr := json.Marshal(result); merr == nil {
+			j.Result = data
+		}
+	}
+	switch {
+	case r.cancelled:
+		j.Status = models.JobCancelled
+	case background.Err() != nil:
+		// shutting down, the job stays running and is queued again at the next start
+		if err := store.Put(id, j); err != nil {
+			logger.Error("Saving job failed", zap.String("job", id), zap.Error(err))
+		}
+		return
+	case err != nil:
+		j.Status, j.Error = models.JobFailed, err.Error()
+	default:
+		j.Status = models.JobSucceeded
+	}
+	finished := time.Now().UTC()
+	j.Finished = &finished
+	if err := store.Put(id, j); err != nil {
+		logger.Error("Saving job failed", zap.String("job", id), zap.Error(err))
+	}
+	logger.Info("Job finished", zap.String("job", id), zap.String("type", j.Type), zap.String("status", j.Status),
+		zap.Int64("done", j.Progress.Done), zap.Int64("failed", j.Progress.Failed), zap.Int64("bytes", j.Progress.Bytes),
+		zap.Duration("took", finished.Sub(started)), zap.Error(err))
+}
+
+// retain -- forgets finished jobs once jobs.retention has passed
+func retain(ctx context.Context) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		cutoff := time.Now().Add(-config.Get().Jobs.Retention)
+		mu.Lock()
+		for id, j := range store.All() {
+			if j.Finished != nil && j.Finished.Before(cutoff) {
+				if err := store.Delete(id); err != nil {
+					logger.Error("Forgetting job failed", zap.String("job", id), zap.Error(err))
+				}
+			}
+		}
+		mu.Unlock()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
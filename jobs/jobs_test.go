@@ -0,0 +1,199 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"kluisz-object-storage/config"
+	"kluisz-object-storage/models"
+)
+
+// blocking -- a job kind that reports one item done and then waits for release or
+// for its context to end; started receives the ID of every run
+type blocking struct {
+	started chan string
+	release chan struct{}
+}
+
+func registerBlocking(t *testing.T) *blocking {
+	t.Helper()
+	b := &blocking{started: make(chan string, 10), release: make(chan struct{})}
+	Register("test", Kind{
+		Check: func(params json.RawMessage) error {
+			var p struct{ Fail bool }
+			json.Unmarshal(params, &p)
+			if p.Fail {
+				return errors.New("fail is set")
+			}
+			return nil
+		},
+		Run: func(ctx context.Context, job models.Job, p *Progress) (any, error) {
+			p.AddTotal(2)
+			p.Done(10)
+			b.started <- job.ID
+			select {
+			case <-b.release:
+				p.Done(10)
+				return "done", nil
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		},
+	})
+	return b
+}
+
+// startJobs -- starts the package on dir with one worker, from a clean state
+func startJobs(t *testing.T, dir string) context.CancelFunc {
+	t.Helper()
+	config.Use(config.Config{DataDir: dir, Jobs: config.JobsConfig{Workers: 1, Retention: time.Hour}})
+	mu.Lock()
+	pending, active = nil, make(map[string]*running)
+	mu.Unlock()
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := Start(ctx, zap.NewNop()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(cancel)
+	return cancel
+}
+
+func submit(t *testing.T) models.Job {
+	t.Helper()
+	j, err := Submit("test", map[string]bool{"fail": false})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return j
+}
+
+func waitStarted(t *testing.T, b *blocking, id string) {
+	t.Helper()
+	select {
+	case got := <-b.started:
+		if got != id {
+			t.Fatalf("job %s started, want %s", got, id)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("job %s did not start", id)
+	}
+}
+
+func waitStatus(t *testing.T, id, status string) models.Job {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		j, _ := Get(id)
+		if j.Status == status {
+			return j
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job %s is %s, want %s", id, j.Status, status)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestSubmit(t *testing.T) {
+	registerBlocking(t)
+	startJobs(t, t.TempDir())
+	if _, err := Submit("nope", nil); !errors.Is(err, ErrUnknownType) {
+		t.Errorf("unknown type: got %v", err)
+	}
+	if _, err := Submit("test", map[string]bool{"fail": true}); !errors.Is(err, ErrInvalid) {
+		t.Errorf("failing check: got %v", err)
+	}
+}
+
+func TestCancel(t *testing.T) {
+	b := registerBlocking(t)
+	startJobs(t, t.TempDir())
+	first, second := submit(t), submit(t)
+	waitStarted(t, b, first.ID)
+
+	// the single worker is busy, so the second job is still queued
+	j, err := Cancel(second.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if j.Status != models.JobCancelled || j.Finished == nil {
+		t.Errorf("queued job after Cancel: %s, finished %v", j.Status, j.Finished)
+	}
+
+	if j, err = Cancel(first.ID); err != nil {
+		t.Fatal(err)
+	}
+	if j.Progress.Done != 1 {
+		t.Errorf("running job reported %d items done, want 1", j.Progress.Done)
+	}
+	j = waitStatus(t, first.ID, models.JobCancelled)
+	if j.Progress.Done != 1 || j.Error != "" {
+		t.Errorf("cancelled job kept progress %+v, error %q", j.Progress, j.Error)
+	}
+
+	if _, err := Cancel(first.ID); !errors.Is(err, ErrFinished) {
+		t.Errorf("cancelling again: got %v, want ErrFinished", err)
+	}
+	if _, err := Cancel("missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("unknown job: got %v, want ErrNotFound", err)
+	}
+	select {
+	case id := <-b.started:
+		t.Errorf("cancelled job %s ran", id)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestRestart(t *testing.T) {
+	b := registerBlocking(t)
+	dir := t.TempDir()
+	shutdown := startJobs(t, dir)
+	running, queued := submit(t), submit(t)
+	waitStarted(t, b, running.ID)
+
+	// a shutdown leaves the running job running on disk
+	shutdown()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		mu.Lock()
+		idle := len(active) == 0
+		mu.Unlock()
+		if idle {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("job did not stop at shutdown")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if j, _ := Get(running.ID); j.Status != models.JobRunning {
+		t.Fatalf("interrupted job saved as %s, want running", j.Status)
+	}
+
+	// the next start runs it again from the start, before the job queued after it
+	startJobs(t, dir)
+	waitStarted(t, b, running.ID)
+	j, _ := Get(running.ID)
+	if j.Restarts != 1 {
+		t.Errorf("restarts %d, want 1", j.Restarts)
+	}
+	if j.Progress.Total != 2 || j.Progress.Done != 1 {
+		t.Errorf("progress not started afresh: %+v", j.Progress)
+	}
+	b.release <- struct{}{}
+	j = waitStatus(t, running.ID, models.JobSucceeded)
+	if string(j.Result) != `"done"` || j.Progress.Done != 2 {
+		t.Errorf("finished job: result %s, progress %+v", j.Result, j.Progress)
+	}
+
+	waitStarted(t, b, queued.ID)
+	if j, _ := Get(queued.ID); j.Restarts != 0 {
+		t.Errorf("queued job counted %d restarts", j.Restarts)
+	}
+	b.release <- struct{}{}
+	waitStatus(t, queued.ID, models.JobSucceeded)
+}
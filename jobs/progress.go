@@ -0,0 +1,55 @@
+package jobs
+
+import (
+	"sync"
+
+	"kluisz-object-storage/models"
+)
+
+// the failed items listed with a job, progress counts all of them
+const maxFailures = 100
+
+// Progress -- what a running job reports as it goes
+type Progress struct {
+	mu       sync.Mutex
+	counts   models.JobProgress
+	failures []models.JobFailure
+}
+
+// AddTotal -- n more items to do, for jobs that find their items as they go
+func (p *Progress) AddTotal(n int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.counts.Total += n
+}
+
+// Done -- one item done, of size bytes
+func (p *Progress) Done(bytes int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.counts.Done++
+	p.counts.Bytes += bytes
+}
+
+// Fail -- one item failed
+func (p *Progress) Fail(item string, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.counts.Failed++
+	if len(p.failures) < maxFailures {
+		p.failures = append(p.failures, models.JobFailure{Item: item, Error: err.Error()})
+	}
+}
+
+func (p *Progress) snapshot() (models.JobProgress, []models.JobFailure) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.counts, append([]models.JobFailure(nil), p.failures...)
+}
+
+// Failed -- the items failed so far
+func (p *Progress) Failed() int64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.counts.Failed
+}
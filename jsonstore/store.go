@@ -0,0 +1,87 @@
+// Package jsonstore keeps small keyed gateway state (per-bucket rules and the like)
+// in memory and persists it as a single JSON file.
+package jsonstore
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+type Store[T any] struct {
+	mu    sync.RWMutex
+	path  string
+	items map[string]T
+}
+
+// Open -- loads the file if it exists, a missing file is an empty store
+func Open[T any](path string) (*Store[T], error) {
+	s := &Store[T]{path: path, items: make(map[string]T)}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &s.items); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *Store[T]) Get(key string) (T, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	v, ok := s.items[key]
+	return v, ok
+}
+
+func (s *Store[T]) Put(key string, v T) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.items[key] = v
+	return s.save()
+}
+
+func (s *Store[T]) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.items[key]; !ok {
+		return nil
+	}
+	delete(s.items, key)
+	return s.save()
+}
+
+// All -- copy of every entry
+func (s *Store[T]) All() map[string]T {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make(map[string]T, len(s.items))
+	for k, v := range s.items {
+		out[k] = v
+	}
+	return out
+}
+
+// save writes to a temp file and renames it so a crash never leaves a half-written file;
+// only the gateway's user may read it, entries can hold secrets such as webhook keys
+func (s *Store[T]) save() error {
+	data, err := json.MarshalIndent(s.items, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	// a leftover temp file would keep its old permissions
+	os.Remove(tmp)
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
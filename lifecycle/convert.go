@@ -0,0 +1,173 @@
+package lifecycle
+
+import (
+	"sort"
+	"time"
+
+	s3lifecycle "github.com/minio/minio-go/v7/pkg/lifecycle"
+	"kluisz-object-storage/models"
+)
+
+const dateLayout = "2006-01-02"
+
+// ToS3 -- converts the gateway model into the backend lifecycle configuration
+// the model is expected to have passed Validate
+func ToS3(cfg models.LifecycleConfiguration) *s3lifecycle.Configuration {
+	out := s3lifecycle.NewConfiguration()
+	for _, r := range cfg.Rules {
+		rule := s3lifecycle.Rule{
+			ID:         r.ID,
+			Status:     r.Status,
+			RuleFilter: toS3Filter(r.Filter),
+		}
+		if r.Expiration != nil {
+			rule.Expiration.Days = s3lifecycle.ExpirationDays(r.Expiration.Days)
+			if r.Expiration.Date != "" {
+				rule.Expiration.Date = s3lifecycle.ExpirationDate{Time: dateOrZero(r.Expiration.Date)}
+			}
+		}
+		if r.NoncurrentVersionExpiration != nil {
+			rule.NoncurrentVersionExpiration.NoncurrentDays = s3lifecycle.ExpirationDays(r.NoncurrentVersionExpiration.NoncurrentDays)
+			rule.NoncurrentVersionExpiration.NewerNoncurrentVersions = r.NoncurrentVersionExpiration.NewerNoncurrentVersions
+		}
+		if r.AbortIncompleteMultipartUploadDays > 0 {
+			rule.AbortIncompleteMultipartUpload.DaysAfterInitiation = s3lifecycle.ExpirationDays(r.AbortIncompleteMultipartUploadDays)
+		}
+		if r.Transition != nil {
+			rule.Transition.StorageClass = r.Transition.StorageClass
+			rule.Transition.Days = s3lifecycle.ExpirationDays(r.Transition.Days)
+			if r.Transition.Date != "" {
+				rule.Transition.Date = s3lifecycle.ExpirationDate{Time: dateOrZero(r.Transition.Date)}
+			}
+		}
+		if r.NoncurrentVersionTransition != nil {
+			rule.NoncurrentVersionTransition.NoncurrentDays = s3lifecycle.ExpirationDays(r.NoncurrentVersionTransition.NoncurrentDays)
+			rule.NoncurrentVersionTransition.StorageClass = r.NoncurrentVersionTransition.StorageClass
+		}
+		out.Rules = append(out.Rules, rule)
+	}
+	return out
+}
+
+func toS3Filter(f models.LifecycleFilter) s3lifecycle.Filter {
+	var tags []s3lifecycle.Tag
+	for k, v := range f.Tags {
+		tags = append(tags, s3lifecycle.Tag{Key: k, Value: v})
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i].Key < tags[j].Key })
+
+	conditions := len(tags)
+	if f.Prefix != "" {
+		conditions++
+	}
+	if f.ObjectSizeGreaterThan > 0 {
+		conditions++
+	}
+	if f.ObjectSizeLessThan > 0 {
+		conditions++
+	}
+
+	// S3 only accepts a single bare condition, anything more has to be wrapped in <And>
+	if conditions > 1 {
+		return s3lifecycle.Filter{And: s3lifecycle.And{
+			Prefix:                f.Prefix,
+			Tags:                  tags,
+			ObjectSizeGreaterThan: f.ObjectSizeGreaterThan,
+			ObjectSizeLessThan:    f.ObjectSizeLessThan,
+		}}
+	}
+	filter := s3lifecycle.Filter{
+		Prefix:                f.Prefix,
+		ObjectSizeGreaterThan: f.ObjectSizeGreaterThan,
+		ObjectSizeLessThan:    f.ObjectSizeLessThan,
+	}
+	if len(tags) == 1 {
+		filter.Tag = tags[0]
+	}
+	return filter
+}
+
+// FromS3 -- converts a backend lifecycle configuration into the gateway model
+func FromS3(cfg *s3lifecycle.Configuration) models.LifecycleConfiguration {
+	out := models.LifecycleConfiguration{Rules: []models.LifecycleRule{}}
+	if cfg == nil {
+		return out
+	}
+	for _, r := range cfg.Rules {
+		rule := models.LifecycleRule{
+			ID:     r.ID,
+			Status: r.Status,
+			Filter: fromS3Filter(r),
+		}
+		if !r.Expiration.IsNull() {
+			rule.Expiration = &models.LifecycleExpiration{Days: int(r.Expiration.Days)}
+			if !r.Expiration.IsDateNull() {
+				rule.Expiration.Date = r.Expiration.Date.Format(dateLayout)
+			}
+		}
+		if r.NoncurrentVersionExpiration.NoncurrentDays > 0 || r.NoncurrentVersionExpiration.NewerNoncurrentVersions > 0 {
+			rule.NoncurrentVersionExpiration = &models.NoncurrentExpiration{
+				NoncurrentDays:          int(r.NoncurrentVersionExpiration.NoncurrentDays),
+				NewerNoncurrentVersions: r.NoncurrentVersionExpiration.NewerNoncurrentVersions,
+			}
+		}
+		if !r.AbortIncompleteMultipartUpload.IsDaysNull() {
+			rule.AbortIncompleteMultipartUploadDays = int(r.AbortIncompleteMultipartUpload.DaysAfterInitiation)
+		}
+		if !r.Transition.IsNull() {
+			rule.Transition = &models.LifecycleTransition{
+				Days:         int(r.Transition.Days),
+				StorageClass: r.Transition.StorageClass,
+			}
+			if !r.Transition.IsDateNull() {
+				rule.Transition.Date = r.Transition.Date.Format(dateLayout)
+			}
+		}
+		if !r.NoncurrentVersionTransition.IsStorageClassEmpty() {
+			rule.NoncurrentVersionTransition = &models.NoncurrentTransition{
+				NoncurrentDays: int(r.NoncurrentVersionTransition.NoncurrentDays),
+				StorageClass:   r.NoncurrentVersionTransition.StorageClass,
+			}
+		}
+		out.Rules = append(out.Rules, rule)
+	}
+	return out
+}
+
+func fromS3Filter(r s3lifecycle.Rule) models.LifecycleFilter {
+	f := r.RuleFilter
+	out := models.LifecycleFilter{
+		Prefix:                f.Prefix,
+		ObjectSizeGreaterThan: f.ObjectSizeGreaterThan,
+		ObjectSizeLessThan:    f.ObjectSizeLessThan,
+	}
+	if out.Prefix == "" {
+		// deprecated rule-level prefix, still returned by some backends
+		out.Prefix = r.Prefix
+	}
+	if !f.Tag.IsEmpty() {
+		out.Tags = map[string]string{f.Tag.Key: f.Tag.Value}
+	}
+	if !f.And.IsEmpty() {
+		out.Prefix = f.And.Prefix
+		out.ObjectSizeGreaterThan = f.And.ObjectSizeGreaterThan
+		out.ObjectSizeLessThan = f.And.ObjectSizeLessThan
+		if len(f.And.Tags) > 0 {
+			out.Tags = make(map[string]string, len(f.And.Tags))
+			for _, t := range f.And.Tags {
+				out.Tags[t.Key] = t.Value
+			}
+		}
+	}
+	return out
+}
+
+func parseDate(s string) (time.Time, error) {
+	return time.Parse(dateLayout, s)
+}
+
+// dateOrZero -- zero time for unparsable dates, Validate rejects those before conversion
+func dateOrZero(s string) time.Time {
+	t, _ := parseDate(s)
+	return t
+}
@@ -0,0 +1,212 @@
+package lifecycle
+
+import (
+	"context"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"go.uber.org/zap"
+	"kluisz-object-storage/events"
+	"kluisz-object-storage/models"
+	"kluisz-object-storage/storage"
+)
+
+// Engine -- periodically applies the gateway-side rules for backends without native lifecycle support
+// transitions are never enforced here, the handlers refuse to store rules containing them
+type Engine struct {
+	store    *Store
+	interval time.Duration
+	logger   *zap.Logger
+}
+
+func NewEngine(store *Store, interval time.Duration, logger *zap.Logger) *Engine {
+	return &Engine{store: store, interval: interval, logger: logger}
+}
+
+// Run -- enforces the rules every interval until ctx is cancelled
+func (e *Engine) Run(ctx context.Context) {
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+	for {
+		e.RunOnce(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce -- one enforcement pass over every bucket with gateway-side rules
+func (e *Engine) RunOnce(ctx context.Context) {
+	buckets := e.store.All()
+	if len(buckets) == 0 {
+		return
+	}
+	now := time.Now().UTC()
+	for bucket, cfg := range buckets {
+		client, err := storage.ClientFor(bucket)
+		if err != nil {
+			e.logger.Error("Lifecycle client init failed", zap.String("bucket", bucket), zap.Error(err))
+			continue
+		}
+		for _, rule := range cfg.Rules {
+			if ctx.Err() != nil {
+				return
+			}
+			if rule.Status != "Enabled" {
+				continue
+			}
+			e.applyRule(ctx, client, bucket, rule, now)
+		}
+	}
+}
+
+func (e *Engine) applyRule(ctx context.Context, client *minio.Client, bucket string, rule models.LifecycleRule, now time.Time) {
+	log := e.logger.With(zap.String("bucket", bucket), zap.String("rule", rule.ID))
+
+	if rule.Expiration != nil {
+		removed, err := e.expireCurrent(ctx, client, bucket, rule, now)
+		logPass(log, "expiration", removed, err)
+	}
+	if rule.NoncurrentVersionExpiration != nil {
+		removed, err := e.expireNoncurrent(ctx, client, bucket, rule, now)
+		logPass(log, "noncurrentVersionExpiration", removed, err)
+	}
+	if rule.AbortIncompleteMultipartUploadDays > 0 {
+		removed, err := e.abortIncomplete(ctx, client, bucket, rule, now)
+		logPass(log, "abortIncompleteMultipartUpload", removed, err)
+	}
+}
+
+func logPass(log *zap.Logger, action string, removed int, err error) {
+	if err != nil {
+		log.Error("Lifecycle action failed", zap.String("action", action), zap.Int("removed", removed), zap.Error(err))
+		return
+	}
+	if removed > 0 {
+		log.Info("Lifecycle action applied", zap.String("action", action), zap.Int("removed", removed))
+	}
+}
+
+func (e *Engine) expireCurrent(ctx context.Context, client *minio.Client, bucket string, rule models.LifecycleRule, now time.Time) (int, error) {
+	exp := rule.Expiration
+	if exp.Date != "" && now.Before(dateOrZero(exp.Date)) {
+		return 0, nil
+	}
+
+	removed := 0
+	for obj := range client.ListObjects(ctx, bucket, minio.ListObjectsOptions{Prefix: rule.Filter.Prefix, Recursive: true}) {
+		if obj.Err != nil {
+			return removed, obj.Err
+		}
+		if exp.Days > 0 && now.Sub(obj.LastModified) < days(exp.Days) {
+			continue
+		}
+		ok, err := matches(ctx, client, bucket, obj, rule.Filter)
+		if err != nil {
+			return removed, err
+		}
+		if !ok {
+			continue
+		}
+		// on a versioned bucket this only adds a delete marker, like native expiration does
+		if err := client.RemoveObject(ctx, bucket, obj.Key, minio.RemoveObjectOptions{}); err != nil {
+			return removed, err
+		}
+		events.Publish(events.Event{Type: events.ObjectRemoved, Bucket: bucket, Key: obj.Key, Source: events.SourceLifecycle})
+		removed++
+	}
+	return removed, nil
+}
+
+// expireNoncurrent -- a version becomes noncurrent when the next newer version is written,
+// the backend lists versions of a key newest first so that moment is the previous entry's LastModified
+func (e *Engine) expireNoncurrent(ctx context.Context, client *minio.Client, bucket string, rule models.LifecycleRule, now time.Time) (int, error) {
+	nc := rule.NoncurrentVersionExpiration
+	removed := 0
+
+	var key string
+	var successor time.Time
+	newer := 0
+	for obj := range client.ListObjects(ctx, bucket, minio.ListObjectsOptions{Prefix: rule.Filter.Prefix, Recursive: true, WithVersions: true}) {
+		if obj.Err != nil {
+			return removed, obj.Err
+		}
+		if obj.IsLatest || obj.Key != key {
+			key, successor, newer = obj.Key, obj.LastModified, 0
+			if obj.IsLatest {
+				continue
+			}
+		}
+
+		noncurrentSince := successor
+		successor = obj.LastModified
+		retained := newer < nc.NewerNoncurrentVersions
+		newer++
+		if retained || now.Sub(noncurrentSince) < days(nc.NoncurrentDays) {
+			continue
+		}
+		ok, err := matches(ctx, client, bucket, obj, rule.Filter)
+		if err != nil {
+			return removed, err
+		}
+		if !ok {
+			continue
+		}
+		if err := client.RemoveObject(ctx, bucket, obj.Key, minio.RemoveObjectOptions{VersionID: obj.VersionID}); err != nil {
+			return removed, err
+		}
+		events.Publish(events.Event{Type: events.ObjectRemoved, Bucket: bucket, Key: obj.Key, VersionID: obj.VersionID, Source: events.SourceLifecycle})
+		removed++
+	}
+	return removed, nil
+}
+
+func (e *Engine) abortIncomplete(ctx context.Context, client *minio.Client, bucket string, rule models.LifecycleRule, now time.Time) (int, error) {
+	removed := 0
+	aborted := make(map[string]bool)
+	for upload := range client.ListIncompleteUploads(ctx, bucket, rule.Filter.Prefix, true) {
+		if upload.Err != nil {
+			return removed, upload.Err
+		}
+		if aborted[upload.Key] || now.Sub(upload.Initiated) < days(rule.AbortIncompleteMultipartUploadDays) {
+			continue
+		}
+		// removes every pending upload of the key, not only the expired one
+		if err := client.RemoveIncompleteUpload(ctx, bucket, upload.Key); err != nil {
+			return removed, err
+		}
+		aborted[upload.Key] = true
+		removed++
+	}
+	return removed, nil
+}
+
+// matches -- checks the size and tag parts of a filter, the prefix is already applied by the listing
+func matches(ctx context.Context, client *minio.Client, bucket string, obj minio.ObjectInfo, f models.LifecycleFilter) (bool, error) {
+	if f.ObjectSizeGreaterThan > 0 && obj.Size <= f.ObjectSizeGreaterThan {
+		return false, nil
+	}
+	if f.ObjectSizeLessThan > 0 && obj.Size >= f.ObjectSizeLessThan {
+		return false, nil
+	}
+	if len(f.Tags) == 0 || obj.IsDeleteMarker {
+		return len(f.Tags) == 0, nil
+	}
+	t, err := client.GetObjectTagging(ctx, bucket, obj.Key, minio.GetObjectTaggingOptions{VersionID: obj.VersionID})
+	if err != nil {
+		return false, err
+	}
+	objTags := t.ToMap()
+	for k, v := range f.Tags {
+		if objTags[k] != v {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func days(n int) time.Duration {
+	return time.Duration(n) * 24 * time.Hour
+}
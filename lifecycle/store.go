@@ -0,0 +1,24 @@
+package lifecycle
+
+import (
+	"path/filepath"
+
+	"kluisz-object-storage/config"
+	"kluisz-object-storage/jsonstore"
+	"kluisz-object-storage/models"
+)
+
+// Store -- lifecycle rules enforced by the gateway, keyed by bucket
+type Store = jsonstore.Store[models.LifecycleConfiguration]
+
+// Rules -- gateway-side lifecycle rules, set up by LoadRules
+var Rules *Store
+
+func LoadRules() error {
+	s, err := jsonstore.Open[models.LifecycleConfiguration](filepath.Join(config.Get().DataDir, "lifecycle.json"))
+	if err != nil {
+		return err
+	}
+	Rules = s
+	return nil
+}
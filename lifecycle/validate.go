@@ -0,0 +1,127 @@
+package lifecycle
+
+import (
+	"errors"
+	"fmt"
+
+	"kluisz-object-storage/models"
+)
+
+const maxRules = 1000
+
+// Validate -- checks a lifecycle configuration before it is sent to the backend or stored
+// every problem found is reported, not only the first one
+func Validate(cfg models.LifecycleConfiguration) error {
+	var errs []error
+	if len(cfg.Rules) == 0 {
+		return errors.New("lifecycle configuration must contain at least one rule")
+	}
+	if len(cfg.Rules) > maxRules {
+		errs = append(errs, fmt.Errorf("lifecycle configuration has %d rules, at most %d are allowed", len(cfg.Rules), maxRules))
+	}
+
+	seen := make(map[string]bool)
+	for i, r := range cfg.Rules {
+		name := fmt.Sprintf("rule %d", i)
+		if r.ID != "" {
+			name = fmt.Sprintf("rule %q", r.ID)
+		}
+		fail := func(format string, args ...any) {
+			errs = append(errs, fmt.Errorf("%s: %s", name, fmt.Sprintf(format, args...)))
+		}
+
+		switch {
+		case r.ID == "":
+			fail("id is required")
+		case len(r.ID) > 255:
+			fail("id must be at most 255 characters")
+		case seen[r.ID]:
+			fail("duplicate id")
+		}
+		seen[r.ID] = true
+
+		if r.Status != "Enabled" && r.Status != "Disabled" {
+			fail("status must be Enabled or Disabled, got %q", r.Status)
+		}
+
+		if r.Expiration == nil && r.NoncurrentVersionExpiration == nil && r.AbortIncompleteMultipartUploadDays == 0 &&
+			r.Transition == nil && r.NoncurrentVersionTransition == nil {
+			fail("at least one action is required")
+		}
+
+		if f := r.Filter; f.ObjectSizeGreaterThan < 0 || f.ObjectSizeLessThan < 0 {
+			fail("object size filters must not be negative")
+		} else if f.ObjectSizeGreaterThan > 0 && f.ObjectSizeLessThan > 0 && f.ObjectSizeGreaterThan >= f.ObjectSizeLessThan {
+			fail("objectSizeGreaterThan must be smaller than objectSizeLessThan")
+		}
+		for k := range r.Filter.Tags {
+			if k == "" {
+				fail("tag keys must not be empty")
+			}
+		}
+
+		if e := r.Expiration; e != nil {
+			if err := checkDaysOrDate(e.Days, e.Date); err != nil {
+				fail("expiration: %v", err)
+			}
+		}
+		if n := r.NoncurrentVersionExpiration; n != nil {
+			if n.NoncurrentDays <= 0 {
+				fail("noncurrentVersionExpiration: noncurrentDays must be positive")
+			}
+			if n.NewerNoncurrentVersions < 0 {
+				fail("noncurrentVersionExpiration: newerNoncurrentVersions must not be negative")
+			}
+		}
+		if r.AbortIncompleteMultipartUploadDays < 0 {
+			fail("abortIncompleteMultipartUploadDays must be positive")
+		}
+		if t := r.Transition; t != nil {
+			if t.StorageClass == "" {
+				fail("transition: storageClass is required")
+			}
+			if err := checkDaysOrDate(t.Days, t.Date); err != nil {
+				fail("transition: %v", err)
+			}
+			if e := r.Expiration; e != nil && e.Days > 0 && t.Days > 0 && t.Days >= e.Days {
+				fail("transition days must be less than expiration days")
+			}
+		}
+		if t := r.NoncurrentVersionTransition; t != nil {
+			if t.StorageClass == "" {
+				fail("noncurrentVersionTransition: storageClass is required")
+			}
+			if t.NoncurrentDays <= 0 {
+				fail("noncurrentVersionTransition: noncurrentDays must be positive")
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func checkDaysOrDate(days int, date string) error {
+	switch {
+	case days != 0 && date != "":
+		return errors.New("set either days or date, not both")
+	case days < 0:
+		return errors.New("days must be positive")
+	case days == 0 && date == "":
+		return errors.New("days or date is required")
+	case date != "":
+		if _, err := parseDate(date); err != nil {
+			return fmt.Errorf("date must be formatted as YYYY-MM-DD, got %q", date)
+		}
+	}
+	return nil
+}
+
+// HasTransitions -- the gateway cannot move objects between storage classes itself,
+// so rules with transitions are only accepted when the backend enforces them
+func HasTransitions(cfg models.LifecycleConfiguration) bool {
+	for _, r := range cfg.Rules {
+		if r.Transition != nil || r.NoncurrentVersionTransition != nil {
+			return true
+		}
+	}
+	return false
+}
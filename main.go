@@ -1,13 +1,37 @@
 package main
 
 import (
+	"context"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
 	"github.com/gin-gonic/gin"
+	"kluisz-object-storage/batch"
+	"kluisz-object-storage/bulk"
 	"kluisz-object-storage/config"
+	"kluisz-object-storage/cors"
+	"kluisz-object-storage/envelope"
+	"kluisz-object-storage/events"
 	"kluisz-object-storage/handlers"
+	"kluisz-object-storage/health"
+	"kluisz-object-storage/jobs"
+	"kluisz-object-storage/lifecycle"
 	"kluisz-object-storage/middleware"
+	"kluisz-object-storage/replication"
+	"kluisz-object-storage/secrets"
+	"kluisz-object-storage/sinks"
+	"kluisz-object-storage/storage"
+	"kluisz-object-storage/syncer"
+	"kluisz-object-storage/tracing"
 	_ "kluisz-object-storage/docs"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	ginSwagger "github.com/swaggo/gin-swagger"
 	swaggerFiles "github.com/swaggo/files"
+	"go.uber.org/zap"
 )
 
 // @title           Object Storage API
@@ -18,15 +42,85 @@ import (
 // @contact.name    Ritu Priyadarshini
 // @contact.email   ritu.priyadarshini@kluisz.ai
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "sync" {
+		os.Exit(syncer.Command(os.Args[2:]))
+	}
 	config.LoadConfig()
+	if err := secrets.Start(context.Background()); err != nil {
+		log.Fatalf("Error resolving secrets: %v", err)
+	}
+	if err := lifecycle.LoadRules(); err != nil {
+		log.Fatalf("Error loading lifecycle rules: %v", err)
+	}
+	if err := cors.LoadRules(); err != nil {
+		log.Fatalf("Error loading CORS rules: %v", err)
+	}
+	if err := envelope.Load(); err != nil {
+		log.Fatalf("Error loading envelope master key: %v", err)
+	}
+	shutdownTracing, err := tracing.Setup(config.Get().Tracing)
+	if err != nil {
+		log.Fatalf("Error setting up tracing: %v", err)
+	}
 
 	r := gin.Default()
 
 	//logger middleware-with log rotation
-	zapLoggerR := middleware.NewZapLogger()
-	r.Use(middleware.ZapLogger(zapLoggerR, true))
+	zapLoggerR, err := middleware.NewZapLogger()
+	if err != nil {
+		log.Fatalf("Error setting up logging: %v", err)
+	}
+	zap.ReplaceGlobals(zapLoggerR)
+	r.Use(middleware.BodyLimit())
+	r.Use(middleware.ZapLogger(zapLoggerR, config.Get().Logging.LogBodies))
+	r.Use(middleware.Tracing())
+	r.Use(middleware.Metrics())
+	r.Use(middleware.CORS(config.Get().CORS))
+
+	//background workers run until the server has drained
+	background, stopBackground := context.WithCancel(context.Background())
+
+	//configuration reloads on SIGHUP and config file changes
+	if err := config.Watch(background, zapLoggerR); err != nil {
+		log.Fatalf("Error watching config: %v", err)
+	}
+
+	//webhook deliveries of bucket events, from the durable outbox
+	if err := events.StartWebhooks(background, zapLoggerR); err != nil {
+		log.Fatalf("Error starting webhooks: %v", err)
+	}
+	if err := sinks.Start(background, zapLoggerR); err != nil {
+		log.Fatalf("Error starting event sinks: %v", err)
+	}
+	if err := replication.Start(background, zapLoggerR); err != nil {
+		log.Fatalf("Error starting replication: %v", err)
+	}
+
+	//bulk operations as background jobs; job types are registered before the kept
+	//jobs are resumed
+	bulk.RegisterJobs()
+	batch.RegisterJobs()
+	syncer.RegisterJobs()
+	if err := jobs.Start(background, zapLoggerR); err != nil {
+		log.Fatalf("Error starting jobs: %v", err)
+	}
+
+	var listen events.ListenFunc
+	if config.Get().Events.Stream.BackendNotifications {
+		listen = storage.ListenObjectEvents
+	}
+	events.StartStream(listen, zapLoggerR)
+
+	//gateway-side lifecycle enforcement, for backends without native support
+	if config.Get().Lifecycle.Mode != "native" {
+		go lifecycle.NewEngine(lifecycle.Rules, config.Get().Lifecycle.Interval, zapLoggerR).Run(background)
+	}
 
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+	r.GET("/metrics", gin.WrapH(promhttp.Handler()))
+	r.GET("/healthz", handlers.Liveness)
+	r.GET("/readyz", handlers.Readiness)
+	r.GET("/health", handlers.Health)
 
 	r.POST("/bucket", handlers.CreateBucket)
 	r.DELETE("/bucket/:name", handlers.DeleteBucket)
@@ -35,7 +129,111 @@ func main() {
     r.GET("/download/:bucket/:file", handlers.DownloadFile)
 	r.GET("/objects/:bucket", handlers.ListObjects)
 	r.DELETE("/objects/:bucket/:file", handlers.DeleteObject)
+	r.DELETE("/objects/:bucket", handlers.DeleteObjects)
+	r.GET("/archive/:bucket", handlers.DownloadArchive)
+	r.POST("/archive/:bucket", handlers.DownloadArchiveOfKeys)
+
+	r.GET("/bucket/:name/lifecycle", handlers.GetBucketLifecycle)
+	r.PUT("/bucket/:name/lifecycle", handlers.PutBucketLifecycle)
+	r.DELETE("/bucket/:name/lifecycle", handlers.DeleteBucketLifecycle)
+	r.GET("/bucket/:name/object-lock", handlers.GetBucketObjectLock)
+	r.PUT("/bucket/:name/object-lock", handlers.PutBucketObjectLock)
+	r.GET("/objects/:bucket/:file/retention", handlers.GetObjectRetention)
+	r.PUT("/objects/:bucket/:file/retention", handlers.PutObjectRetention)
+	r.GET("/objects/:bucket/:file/legal-hold", handlers.GetObjectLegalHold)
+	r.PUT("/objects/:bucket/:file/legal-hold", handlers.PutObjectLegalHold)
+	r.GET("/bucket/:name/encryption", handlers.GetBucketEncryption)
+	r.PUT("/bucket/:name/encryption", handlers.PutBucketEncryption)
+	r.DELETE("/bucket/:name/encryption", handlers.DeleteBucketEncryption)
+	r.GET("/bucket/:name/policy", handlers.GetBucketPolicy)
+	r.PUT("/bucket/:name/policy", handlers.PutBucketPolicy)
+	r.DELETE("/bucket/:name/policy", handlers.DeleteBucketPolicy)
+	r.PUT("/bucket/:name/policy/public-read", handlers.AddPublicRead)
+	r.DELETE("/bucket/:name/policy/public-read", handlers.RemovePublicRead)
+	r.GET("/bucket/:name/cors", handlers.GetBucketCORS)
+	r.PUT("/bucket/:name/cors", handlers.PutBucketCORS)
+	r.DELETE("/bucket/:name/cors", handlers.DeleteBucketCORS)
+
+	r.POST("/webhooks", handlers.CreateWebhook)
+	r.GET("/webhooks", handlers.ListWebhooks)
+	r.GET("/webhooks/:id", handlers.GetWebhook)
+	r.DELETE("/webhooks/:id", handlers.DeleteWebhook)
+
+	r.POST("/replication/rules", handlers.CreateReplicationRule)
+	r.GET("/replication/rules", handlers.ListReplicationRules)
+	r.GET("/replication/rules/:id", handlers.GetReplicationRule)
+	r.DELETE("/replication/rules/:id", handlers.DeleteReplicationRule)
+	r.POST("/replication/rules/:id/backfill", handlers.BackfillReplicationRule)
+	r.GET("/replication/status", handlers.ReplicationStatus)
+	r.GET("/replication/status/:bucket/*key", handlers.ObjectReplicationStatus)
+	r.POST("/jobs", handlers.SubmitJob)
+	r.GET("/jobs", handlers.ListJobs)
+	r.GET("/jobs/:id", handlers.GetJob)
+	r.POST("/jobs/:id/cancel", handlers.CancelJob)
+	r.POST("/sync/jobs", handlers.StartSyncJob)
+	r.POST("/batch/jobs", handlers.StartBatchJob)
+	r.GET("/events/:bucket", handlers.StreamBucketEvents)
+
+	//unauthenticated reads, allowed by the bucket policy
+	r.GET("/public/:bucket/*key", handlers.PublicDownload)
+
+
+	srv := &http.Server{Addr: config.Get().Server.Address, Handler: r}
+	status := serve(srv, zapLoggerR)
+
+	stopBackground()
+	if err := shutdownTracing(context.Background()); err != nil {
+		zapLoggerR.Error("Flushing traces failed", zap.Error(err))
+	}
+	zapLoggerR.Sync()
+	os.Exit(status)
+}
+
+// serve -- runs srv until SIGTERM or SIGINT, then drains it. The exit status is 0 when
+// every in-flight request finished within the shutdown timeout; a second signal
+// during the drain kills the process.
+func serve(srv *http.Server, logger *zap.Logger) int {
+	failed := make(chan error, 1)
+	go func() {
+		failed <- srv.ListenAndServe()
+	}()
+	logger.Info("Listening", zap.String("address", srv.Addr))
+	log.Printf("Listening and serving HTTP on %s", srv.Addr)
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGTERM, syscall.SIGINT)
+	select {
+	case err := <-failed:
+		logger.Error("Server failed", zap.Error(err))
+		log.Printf("Server failed: %v", err)
+		return 1
+	case s := <-sig:
+		logger.Info("Shutting down", zap.String("signal", s.String()))
+		log.Printf("Received %s, shutting down", s)
+	}
+	signal.Stop(sig)
+
+	health.Drain()
+	time.Sleep(config.Get().Server.DrainDelay)
+	events.Stream.Close()
 
+	status := 0
+	ctx, cancel := context.WithTimeout(context.Background(), config.Get().Server.ShutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		logger.Warn("Shutdown timeout passed, cutting off in-flight requests", zap.Error(err))
+		log.Printf("Shutdown timeout passed, cutting off in-flight requests")
+		srv.Close()
+		status = 1
+	}
 
-	r.Run(":8080")
+	//uploads that were cut off abort their multipart parts on the backend
+	abortCtx, cancelAbort := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancelAbort()
+	if err := storage.WaitUploads(abortCtx); err != nil {
+		logger.Error("Interrupted uploads were not cleaned up", zap.Error(err))
+		status = 1
+	}
+	logger.Info("Shutdown complete", zap.Int("status", status))
+	return status
 }
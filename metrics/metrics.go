@@ -0,0 +1,68 @@
+// Package metrics holds the gateway's Prometheus collectors, served on /metrics.
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	Requests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "gateway_http_requests_total",
+		Help: "HTTP requests handled, by route and status.",
+	}, []string{"method", "route", "status"})
+
+	RequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "gateway_http_request_duration_seconds",
+		Help:    "Time to handle an HTTP request, by route and status.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	InFlight = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "gateway_http_requests_in_flight",
+		Help: "HTTP requests currently being handled, including open event streams.",
+	})
+
+	BytesUploaded = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "gateway_bytes_uploaded_total",
+		Help: "Object bytes received from clients, by bucket.",
+	}, []string{"bucket"})
+
+	BytesDownloaded = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "gateway_bytes_downloaded_total",
+		Help: "Object bytes sent to clients, by bucket.",
+	}, []string{"bucket"})
+
+	UploadSize = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name: "gateway_upload_size_bytes",
+		Help: "Size of uploaded objects.",
+		// 1KiB to 16GiB, by powers of 4
+		Buckets: prometheus.ExponentialBuckets(1<<10, 4, 13),
+	})
+
+	BackendDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "gateway_backend_request_duration_seconds",
+		Help:    "Latency of S3 calls to the backend, by operation.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"operation"})
+
+	BackendErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "gateway_backend_errors_total",
+		Help: "Failed S3 calls to the backend, by operation and S3 error code.",
+	}, []string{"operation", "code"})
+
+	BackendRetries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "gateway_backend_retries_total",
+		Help: "Backend calls repeated after a transient failure, by operation.",
+	}, []string{"operation"})
+
+	BreakerState = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "gateway_backend_circuit_state",
+		Help: "Circuit breaker state per backend: 0 closed, 1 half-open, 2 open.",
+	}, []string{"backend"})
+
+	BreakerRejections = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "gateway_backend_circuit_rejections_total",
+		Help: "Backend calls refused while the circuit breaker was open.",
+	}, []string{"backend"})
+)
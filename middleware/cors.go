@@ -0,0 +1,109 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"kluisz-object-storage/config"
+	"kluisz-object-storage/cors"
+	"kluisz-object-storage/models"
+)
+
+// routes whose second path segment is a bucket, these honour per-bucket CORS rules
+var objectRoutes = map[string]bool{
+	"upload":   true,
+	"download": true,
+	"objects":  true,
+	"public":   true,
+	"events":   true,
+	"archive":  true,
+}
+
+// CORS answers preflight requests and adds CORS headers to actual requests.
+// Preflights for paths without an OPTIONS route still pass through here, gin runs
+// global middleware ahead of its 404 handler.
+func CORS(cfg config.CORSConfig) gin.HandlerFunc {
+	var global []models.CORSRule
+	if len(cfg.AllowedOrigins) > 0 {
+		global = []models.CORSRule{{
+			AllowedOrigins:   cfg.AllowedOrigins,
+			AllowedMethods:   cfg.AllowedMethods,
+			AllowedHeaders:   cfg.AllowedHeaders,
+			ExposeHeaders:    cfg.ExposeHeaders,
+			MaxAgeSeconds:    int(cfg.MaxAge.Seconds()),
+			AllowCredentials: cfg.AllowCredentials,
+		}}
+	}
+
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		if origin == "" {
+			c.Next()
+			return
+		}
+		c.Writer.Header().Add("Vary", "Origin")
+
+		method := c.Request.Method
+		preflight := method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != ""
+		var requested []string
+		if preflight {
+			c.Writer.Header().Add("Vary", "Access-Control-Request-Method")
+			c.Writer.Header().Add("Vary", "Access-Control-Request-Headers")
+			method = c.GetHeader("Access-Control-Request-Method")
+			for _, h := range strings.Split(c.GetHeader("Access-Control-Request-Headers"), ",") {
+				if h = strings.TrimSpace(h); h != "" {
+					requested = append(requested, h)
+				}
+			}
+		}
+
+		rules := global
+		if bucket := bucketFromPath(c.Request.URL.Path); bucket != "" && cors.Rules != nil {
+			if bucketRules, ok := cors.Rules.Get(bucket); ok {
+				rules = bucketRules.Rules
+			}
+		}
+
+		rule := cors.Match(rules, origin, method, requested)
+		if rule == nil {
+			if preflight {
+				c.AbortWithStatus(http.StatusForbidden)
+				return
+			}
+			// the browser blocks the response without the allow headers
+			c.Next()
+			return
+		}
+
+		h := c.Writer.Header()
+		h.Set("Access-Control-Allow-Origin", origin)
+		if rule.AllowCredentials {
+			h.Set("Access-Control-Allow-Credentials", "true")
+		}
+		if preflight {
+			h.Set("Access-Control-Allow-Methods", strings.Join(rule.AllowedMethods, ", "))
+			if len(requested) > 0 {
+				h.Set("Access-Control-Allow-Headers", strings.Join(requested, ", "))
+			}
+			if rule.MaxAgeSeconds > 0 {
+				h.Set("Access-Control-Max-Age", strconv.Itoa(rule.MaxAgeSeconds))
+			}
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+		if len(rule.ExposeHeaders) > 0 {
+			h.Set("Access-Control-Expose-Headers", strings.Join(rule.ExposeHeaders, ", "))
+		}
+		c.Next()
+	}
+}
+
+func bucketFromPath(path string) string {
+	parts := strings.SplitN(strings.TrimPrefix(path, "/"), "/", 3)
+	if len(parts) < 2 || !objectRoutes[parts[0]] {
+		return ""
+	}
+	return parts[1]
+}
@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"kluisz-object-storage/config"
+	"kluisz-object-storage/models"
+)
+
+// BodyLimit -- rejects request bodies over limits.maxUploadBytes with 413; a declared
+// length is refused up front, otherwise reading fails with *http.MaxBytesError once the
+// limit is passed. 0 disables the limit.
+func BodyLimit() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		max := config.Get().Limits.MaxUploadBytes
+		if max <= 0 || c.Request.Body == nil {
+			c.Next()
+			return
+		}
+		if c.Request.ContentLength > max {
+			c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, models.ErrorResponse413{
+				Code:  http.StatusRequestEntityTooLarge,
+				Error: "Request body exceeds " + strconv.FormatInt(max, 10) + " bytes",
+			})
+			return
+		}
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, max)
+		c.Next()
+	}
+}
@@ -4,15 +4,28 @@ import (
 	"github.com/natefinch/lumberjack"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
+	"kluisz-object-storage/config"
 )
 
-func NewZapLogger() *zap.Logger {
+// NewZapLogger -- the rotating JSON log; its level follows logging.level across
+// config reloads, the other logging settings need a restart
+func NewZapLogger() (*zap.Logger, error) {
+	cfg := config.Get().Logging
+	level, err := zap.ParseAtomicLevel(cfg.Level)
+	if err != nil {
+		return nil, err
+	}
+	config.OnReload(func(old, new *config.Config) {
+		if l, err := zapcore.ParseLevel(new.Logging.Level); err == nil {
+			level.SetLevel(l)
+		}
+	})
 	logWriter := zapcore.AddSync(&lumberjack.Logger{
-		Filename:   "./logs/server.log", // log file path
-		MaxSize:    10,                  // megabytes
-		MaxBackups: 5,                   // number of old files to retain
-		MaxAge:     30,                  // days
-		Compress:   true,                // gzip old logs
+		Filename:   cfg.File,       // log file path
+		MaxSize:    cfg.MaxSizeMB,  // megabytes
+		MaxBackups: cfg.MaxBackups, // number of old files to retain
+		MaxAge:     cfg.MaxAgeDays, // days
+		Compress:   cfg.Compress,   // gzip old logs
 	})
 
 	encoderConfig := zap.NewProductionEncoderConfig()
@@ -22,9 +35,9 @@ func NewZapLogger() *zap.Logger {
 	core := zapcore.NewCore(
 		zapcore.NewJSONEncoder(encoderConfig), // structured logs
 		logWriter,
-		zap.InfoLevel,
+		level,
 	)
 
 	logger := zap.New(core, zap.AddCaller(), zap.AddCallerSkip(1))
-	return logger
+	return logger, nil
 }
@@ -2,16 +2,20 @@ package middleware
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
 	"mime"
 	"net/http"
+	"net/url"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
+	"kluisz-object-storage/config"
 )
 
 func ZapLogger(logger *zap.Logger, logBody bool) gin.HandlerFunc {
@@ -35,10 +39,10 @@ func ZapLogger(logger *zap.Logger, logBody bool) gin.HandlerFunc {
 			case isTextContent(contentType):
 				buf, _ := io.ReadAll(c.Request.Body)
 				c.Request.Body = io.NopCloser(bytes.NewBuffer(buf)) // Restore body
-				body = string(buf)
+				body = redactBody(contentType, buf)
 
 			case strings.HasPrefix(contentType, "multipart/form-data"):
-				if err := c.Request.ParseMultipartForm(10 << 20); err == nil {
+				if err := c.Request.ParseMultipartForm(config.Get().Limits.MultipartMemory); err == nil {
 					for key, headers := range c.Request.MultipartForm.File {
 						for _, hdr := range headers {
 							body += fmt.Sprintf("[field: %s, name: %s, size: %d, type: %s] ",
@@ -69,6 +73,10 @@ func ZapLogger(logger *zap.Logger, logBody bool) gin.HandlerFunc {
 			zap.String("userAgent", c.Request.UserAgent()),
 		}
 
+		if sc := trace.SpanContextFromContext(c.Request.Context()); sc.IsValid() {
+			fields = append(fields, zap.String("traceID", sc.TraceID().String()))
+		}
+
 		if logBody && body != "" {
 			fields = append(fields, zap.String("body", body))
 		}
@@ -86,6 +94,69 @@ func ZapLogger(logger *zap.Logger, logBody bool) gin.HandlerFunc {
 	}
 }
 
+// redacted -- replaces the values of sensitive fields in logged request bodies
+const redacted = "[redacted]"
+
+// sensitive -- a field name that holds a credential, like a webhook secret, an access
+// key or a token; a plain "key" is an object key and is kept
+func sensitive(name string) bool {
+	name = strings.ToLower(name)
+	for _, s := range []string{"secret", "password", "token", "credential"} {
+		if strings.Contains(name, s) {
+			return true
+		}
+	}
+	return name != "key" && strings.HasSuffix(name, "key")
+}
+
+// redactBody -- the body as logged, with the values of sensitive fields of JSON and
+// form bodies replaced; a body that cannot be parsed is logged as is
+func redactBody(contentType string, buf []byte) string {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case "application/json":
+		var v any
+		if err := json.Unmarshal(buf, &v); err != nil {
+			return string(buf)
+		}
+		out, err := json.Marshal(redactJSON(v))
+		if err != nil {
+			return string(buf)
+		}
+		return string(out)
+	case "application/x-www-form-urlencoded":
+		form, err := url.ParseQuery(string(buf))
+		if err != nil {
+			return string(buf)
+		}
+		for k := range form {
+			if sensitive(k) {
+				form[k] = []string{redacted}
+			}
+		}
+		return form.Encode()
+	}
+	return string(buf)
+}
+
+func redactJSON(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, val := range v {
+			if sensitive(k) {
+				v[k] = redacted
+			} else {
+				v[k] = redactJSON(val)
+			}
+		}
+	case []any:
+		for i, val := range v {
+			v[i] = redactJSON(val)
+		}
+	}
+	return v
+}
+
 func isTextContent(contentType string) bool {
 	mediaType, _, err := mime.ParseMediaType(contentType)
 	if err != nil {
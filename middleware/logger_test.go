@@ -0,0 +1,34 @@
+package middleware
+
+import "testing"
+
+func TestRedactBody(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        string
+	}{
+		{"webhook secret", "application/json",
+			`{"url":"http://hooks.example.com","secret":"s3cr3t"}`,
+			`{"secret":"[redacted]","url":"http://hooks.example.com"}`},
+		{"nested credentials", "application/json; charset=utf-8",
+			`{"backend":{"accessKey":"AK","secretKey":"SK","authToken":"T"},"key":"photos/a.jpg"}`,
+			`{"backend":{"accessKey":"[redacted]","authToken":"[redacted]","secretKey":"[redacted]"},"key":"photos/a.jpg"}`},
+		{"arrays", "application/json",
+			`[{"password":"p"},{"name":"n"}]`,
+			`[{"password":"[redacted]"},{"name":"n"}]`},
+		{"form", "application/x-www-form-urlencoded",
+			"token=abc&bucket=b",
+			"bucket=b&token=%5Bredacted%5D"},
+		{"invalid json kept", "application/json", `{"secret":`, `{"secret":`},
+		{"plain text kept", "text/plain", "secret=abc", "secret=abc"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := redactBody(tt.contentType, []byte(tt.body)); got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
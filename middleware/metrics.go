@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"kluisz-object-storage/metrics"
+)
+
+// Metrics -- request counts, latencies and in-flight requests per route; the route
+// is the registered pattern so object names do not become label values
+func Metrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		metrics.InFlight.Inc()
+		defer metrics.InFlight.Dec()
+
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		status := strconv.Itoa(c.Writer.Status())
+		metrics.Requests.WithLabelValues(c.Request.Method, route, status).Inc()
+		metrics.RequestDuration.WithLabelValues(c.Request.Method, route, status).Observe(time.Since(start).Seconds())
+	}
+}
@@ -1,5 +1,10 @@
 package models
 
+import (
+	"encoding/json"
+	"time"
+)
+
 
 // ErrorResponse -- error message with code
 type ErrorResponse500 struct {
@@ -14,9 +19,43 @@ type ErrorResponse400 struct {
 	Code  int    `json:"code" example:"400"`
 	Error string `json:"error" example:"Bad request Error message"`
 }
+type ErrorResponse409 struct {
+	Code  int    `json:"code" example:"409"`
+	Error string `json:"error" example:"Conflict Error message"`
+}
+type ErrorResponse413 struct {
+	Code  int    `json:"code" example:"413"`
+	Error string `json:"error" example:"Request Entity Too Large Error message"`
+}
+type ErrorResponse416 struct {
+	Code  int    `json:"code" example:"416"`
+	Error string `json:"error" example:"Range Not Satisfiable Error message"`
+}
+type ErrorResponse403 struct {
+	Code  int    `json:"code" example:"403"`
+	Error string `json:"error" example:"Forbidden Error message"`
+}
+type ErrorResponse503 struct {
+	Code  int    `json:"code" example:"503"`
+	Error string `json:"error" example:"Service Unavailable Error message"`
+}
+type ErrorResponse504 struct {
+	Code  int    `json:"code" example:"504"`
+	Error string `json:"error" example:"Gateway Timeout Error message"`
+}
 
 type CreateBucketRequest struct {
 	BucketName string `json:"bucketName" example:"mybucket"`
+	// object locking can only be enabled when the bucket is created, it also turns on versioning
+	ObjectLocking    bool              `json:"objectLocking" example:"false"`
+	DefaultRetention *DefaultRetention `json:"defaultRetention,omitempty"`
+}
+
+// DefaultRetention -- retention applied to every new object of a locked bucket, in days or years
+type DefaultRetention struct {
+	Mode  string `json:"mode" example:"COMPLIANCE"`
+	Days  uint   `json:"days,omitempty" example:"0"`
+	Years uint   `json:"years,omitempty" example:"7"`
 }
 
 // response to create bucket
@@ -33,6 +72,10 @@ type BucketResponseD struct {
 
 type ListBucketsResponse struct {
 	Buckets []string `json:"buckets"`
+	// bucket names by backend, when several are configured
+	Backends map[string][]string `json:"backends,omitempty"`
+	// backends that could not be listed, their buckets are missing from the list
+	Unavailable []string `json:"unavailable,omitempty"`
 }
 
 
@@ -42,6 +85,8 @@ type UploadFileResponse struct {
 	Size    int64  `json:"size" example:"1234"`
 	Bucket  string `json:"bucket" example:"mybucket"`
 	ETag    string `json:"etag" example:"abcd1234"`
+	// set when the bucket is versioned
+	VersionID string `json:"versionId,omitempty" example:"3HL4kqtJlcpXroDTDmjVBH40Nrjfkd"`
 }
 
 type ListObjectsResponse struct {
@@ -50,7 +95,491 @@ type ListObjectsResponse struct {
 }
 
 type DeleteObjectResponse struct {
-	Message string `json:"message" example:"File deleted"`
+	Message   string `json:"message" example:"File deleted"`
+	Bucket    string `json:"bucket" example:"mybucket"`
+	File      string `json:"file" example:"file.txt"`
+	VersionID string `json:"versionId,omitempty" example:"3HL4kqtJlcpXroDTDmjVBH40Nrjfkd"`
+}
+
+// LifecycleConfiguration -- set of lifecycle rules applied to a bucket
+type LifecycleConfiguration struct {
+	Rules []LifecycleRule `json:"rules"`
+}
+
+type LifecycleRule struct {
+	ID     string `json:"id" example:"expire-raw"`
+	Status string `json:"status" example:"Enabled"`
+	// objects the rule applies to; an empty filter matches the whole bucket
+	Filter LifecycleFilter `json:"filter"`
+
+	Expiration                         *LifecycleExpiration  `json:"expiration,omitempty"`
+	NoncurrentVersionExpiration        *NoncurrentExpiration `json:"noncurrentVersionExpiration,omitempty"`
+	AbortIncompleteMultipartUploadDays int                   `json:"abortIncompleteMultipartUploadDays,omitempty" example:"7"`
+	Transition                         *LifecycleTransition  `json:"transition,omitempty"`
+	NoncurrentVersionTransition        *NoncurrentTransition `json:"noncurrentVersionTransition,omitempty"`
+}
+
+type LifecycleFilter struct {
+	Prefix                string            `json:"prefix,omitempty" example:"raw/"`
+	Tags                  map[string]string `json:"tags,omitempty"`
+	ObjectSizeGreaterThan int64             `json:"objectSizeGreaterThan,omitempty"`
+	ObjectSizeLessThan    int64             `json:"objectSizeLessThan,omitempty"`
+}
+
+// expire after a number of days or on a date (YYYY-MM-DD), not both
+type LifecycleExpiration struct {
+	Days int    `json:"days,omitempty" example:"30"`
+	Date string `json:"date,omitempty" example:"2026-01-01"`
+}
+
+type NoncurrentExpiration struct {
+	NoncurrentDays          int `json:"noncurrentDays" example:"7"`
+	NewerNoncurrentVersions int `json:"newerNoncurrentVersions,omitempty" example:"0"`
+}
+
+type LifecycleTransition struct {
+	Days         int    `json:"days,omitempty" example:"30"`
+	Date         string `json:"date,omitempty" example:"2026-01-01"`
+	StorageClass string `json:"storageClass" example:"GLACIER"`
+}
+
+type NoncurrentTransition struct {
+	NoncurrentDays int    `json:"noncurrentDays" example:"7"`
+	StorageClass   string `json:"storageClass" example:"GLACIER"`
+}
+
+// response to get bucket lifecycle; mode tells whether the backend or the gateway enforces the rules
+type LifecycleResponse struct {
+	Bucket    string                 `json:"bucket" example:"mybucket"`
+	Mode      string                 `json:"mode" example:"native"`
+	Lifecycle LifecycleConfiguration `json:"lifecycle"`
+}
+
+type LifecycleMessageResponse struct {
+	Message string `json:"message" example:"Lifecycle configuration saved"`
 	Bucket  string `json:"bucket" example:"mybucket"`
-	File    string `json:"file" example:"file.txt"`
+	Mode    string `json:"mode" example:"native"`
+}
+
+type ObjectLockConfigRequest struct {
+	// leave empty to remove the default retention
+	DefaultRetention *DefaultRetention `json:"defaultRetention,omitempty"`
+}
+
+type ObjectLockConfigResponse struct {
+	Bucket           string            `json:"bucket" example:"mybucket"`
+	Enabled          bool              `json:"enabled" example:"true"`
+	DefaultRetention *DefaultRetention `json:"defaultRetention,omitempty"`
+}
+
+type ObjectRetentionRequest struct {
+	Mode            string    `json:"mode" example:"GOVERNANCE"`
+	RetainUntilDate time.Time `json:"retainUntilDate" example:"2032-01-01T00:00:00Z"`
+	VersionID       string    `json:"versionId,omitempty"`
+	// needed to shorten or remove a governance-mode retention
+	BypassGovernance bool `json:"bypassGovernance,omitempty" example:"false"`
+}
+
+type ObjectRetentionResponse struct {
+	Bucket          string     `json:"bucket" example:"mybucket"`
+	File            string     `json:"file" example:"file.txt"`
+	VersionID       string     `json:"versionId,omitempty"`
+	Mode            string     `json:"mode,omitempty" example:"GOVERNANCE"`
+	RetainUntilDate *time.Time `json:"retainUntilDate,omitempty" example:"2032-01-01T00:00:00Z"`
+}
+
+type LegalHoldRequest struct {
+	Status    string `json:"status" example:"ON"`
+	VersionID string `json:"versionId,omitempty"`
+}
+
+type LegalHoldResponse struct {
+	Bucket    string `json:"bucket" example:"mybucket"`
+	File      string `json:"file" example:"file.txt"`
+	VersionID string `json:"versionId,omitempty"`
+	Status    string `json:"status" example:"ON"`
+}
+
+// BucketEncryptionRequest -- default encryption for new objects, AES256 (SSE-S3) or aws:kms (SSE-KMS)
+type BucketEncryptionRequest struct {
+	Algorithm string `json:"algorithm" example:"AES256"`
+	KMSKeyID  string `json:"kmsKeyId,omitempty" example:"my-minio-key"`
+}
+
+type BucketEncryptionResponse struct {
+	Bucket    string `json:"bucket" example:"mybucket"`
+	Algorithm string `json:"algorithm" example:"AES256"`
+	KMSKeyID  string `json:"kmsKeyId,omitempty" example:"my-minio-key"`
+}
+
+type PublicReadRequest struct {
+	// objects below this prefix become readable without credentials, empty for the whole bucket
+	Prefix string `json:"prefix" example:"assets/"`
+}
+
+type PublicReadResponse struct {
+	Bucket         string   `json:"bucket" example:"mybucket"`
+	PublicPrefixes []string `json:"publicPrefixes"`
+}
+
+// CORSConfiguration -- browser access rules for the object routes of a bucket
+type CORSConfiguration struct {
+	Rules []CORSRule `json:"rules"`
+}
+
+// CORSRule -- the first rule matching the origin, method and requested headers applies
+type CORSRule struct {
+	ID               string   `json:"id,omitempty" example:"webapp"`
+	AllowedOrigins   []string `json:"allowedOrigins" example:"https://app.example.com"`
+	AllowedMethods   []string `json:"allowedMethods" example:"GET,POST"`
+	AllowedHeaders   []string `json:"allowedHeaders,omitempty" example:"Content-Type,Range"`
+	ExposeHeaders    []string `json:"exposeHeaders,omitempty" example:"ETag,Content-Range"`
+	MaxAgeSeconds    int      `json:"maxAgeSeconds,omitempty" example:"600"`
+	AllowCredentials bool     `json:"allowCredentials,omitempty" example:"false"`
+}
+
+type CORSResponse struct {
+	Bucket string            `json:"bucket" example:"mybucket"`
+	CORS   CORSConfiguration `json:"cors"`
+}
+
+// WebhookRequest -- subscribe a URL to bucket events; empty filters match everything
+type WebhookRequest struct {
+	URL string `json:"url" example:"https://hooks.example.com/storage"`
+	// HMAC-SHA256 key for the X-Gateway-Signature header, generated when empty
+	Secret string   `json:"secret,omitempty"`
+	Events []string `json:"events,omitempty" example:"ObjectCreated,ObjectRemoved"`
+	Bucket string   `json:"bucket,omitempty" example:"mybucket"`
+	Prefix string   `json:"prefix,omitempty" example:"raw/"`
+	Suffix string   `json:"suffix,omitempty" example:".csv"`
+}
+
+// Webhook -- a stored subscription; the secret is only returned when the webhook is created
+type Webhook struct {
+	ID      string    `json:"id" example:"4b1d7c0e-2f0a-4c55-a1e3-8d5e6f7a9b10"`
+	URL     string    `json:"url" example:"https://hooks.example.com/storage"`
+	Secret  string    `json:"secret,omitempty"`
+	Events  []string  `json:"events,omitempty" example:"ObjectCreated,ObjectRemoved"`
+	Bucket  string    `json:"bucket,omitempty" example:"mybucket"`
+	Prefix  string    `json:"prefix,omitempty" example:"raw/"`
+	Suffix  string    `json:"suffix,omitempty" example:".csv"`
+	Created time.Time `json:"created"`
+}
+
+type ListWebhooksResponse struct {
+	Webhooks []Webhook `json:"webhooks"`
+}
+
+type DeleteWebhookResponse struct {
+	Message string `json:"message" example:"Webhook deleted"`
+	ID      string `json:"id" example:"4b1d7c0e-2f0a-4c55-a1e3-8d5e6f7a9b10"`
+}
+
+// HealthResponse -- overall status and the result of each dependency check
+type HealthResponse struct {
+	Status string                 `json:"status" example:"ok"`
+	Checks map[string]CheckResult `json:"checks,omitempty"`
+}
+
+// CheckResult -- status is ok, fail, or warn for a failed check readiness does not
+// depend on
+type CheckResult struct {
+	Status    string  `json:"status" example:"ok"`
+	LatencyMs float64 `json:"latencyMs" example:"3.2"`
+	Detail    string  `json:"detail,omitempty" example:"12.4 GiB free"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// ReplicationRuleRequest -- mirror new and changed objects of a bucket to another
+// backend; the target bucket defaults to the source bucket's name
+type ReplicationRuleRequest struct {
+	SourceBucket  string `json:"sourceBucket" example:"prod-data"`
+	Prefix        string `json:"prefix,omitempty" example:"reports/"`
+	TargetBackend string `json:"targetBackend" example:"dr"`
+	TargetBucket  string `json:"targetBucket,omitempty" example:"prod-data"`
+	// also remove objects from the target when they are deleted at the source
+	Deletes bool `json:"deletes,omitempty" example:"false"`
+}
+
+type ReplicationRule struct {
+	ID            string    `json:"id" example:"0d9c8c1e-5b7a-4f0e-b7a5-1f2e3d4c5b6a"`
+	SourceBucket  string    `json:"sourceBucket" example:"prod-data"`
+	Prefix        string    `json:"prefix,omitempty" example:"reports/"`
+	TargetBackend string    `json:"targetBackend" example:"dr"`
+	TargetBucket  string    `json:"targetBucket" example:"prod-data"`
+	Deletes       bool      `json:"deletes,omitempty" example:"false"`
+	Created       time.Time `json:"created"`
+}
+
+type ListReplicationRulesResponse struct {
+	Rules []ReplicationRule `json:"rules"`
+}
+
+type DeleteReplicationRuleResponse struct {
+	Message string `json:"message" example:"Replication rule deleted"`
+	ID      string `json:"id" example:"0d9c8c1e-5b7a-4f0e-b7a5-1f2e3d4c5b6a"`
+}
+
+// ReplicationStatus -- progress of every rule; lag is the age of the oldest change
+// not yet replicated
+type ReplicationStatus struct {
+	Rules []RuleStatus `json:"rules"`
+}
+
+type RuleStatus struct {
+	Rule           ReplicationRule      `json:"rule"`
+	Pending        int                  `json:"pending" example:"3"`
+	LagSeconds     float64              `json:"lagSeconds" example:"1.5"`
+	Replicated     int64                `json:"replicated" example:"120"`
+	LastReplicated *time.Time           `json:"lastReplicated,omitempty"`
+	Failed         int                  `json:"failed" example:"0"`
+	Failures       []ReplicationFailure `json:"failures,omitempty"`
+	Backfill       *BackfillStatus      `json:"backfill,omitempty"`
+}
+
+// ReplicationFailure -- an object that could not be replicated; retrying, or given up
+// on when permanent, until a backfill or a later change copies it
+type ReplicationFailure struct {
+	Bucket    string    `json:"bucket" example:"prod-data"`
+	Key       string    `json:"key" example:"reports/q1.csv"`
+	Attempts  int       `json:"attempts" example:"10"`
+	Error     string    `json:"error" example:"The specified bucket does not exist"`
+	Permanent bool      `json:"permanent" example:"true"`
+	Since     time.Time `json:"since"`
+}
+
+// BackfillStatus -- the last backfill of a rule, which queues every source object
+// that is missing or different at the target
+type BackfillStatus struct {
+	Job      string     `json:"job" example:"9a7e5c3b-1d2f-4e6a-8b9c-0d1e2f3a4b5c"`
+	Running  bool       `json:"running" example:"false"`
+	Started  time.Time  `json:"started"`
+	Finished *time.Time `json:"finished,omitempty"`
+	Scanned  int        `json:"scanned" example:"1000"`
+	Queued   int        `json:"queued" example:"12"`
+	Error    string     `json:"error,omitempty"`
+}
+
+type BackfillStartedResponse struct {
+	Message string `json:"message" example:"Backfill started"`
+	ID      string `json:"id" example:"0d9c8c1e-5b7a-4f0e-b7a5-1f2e3d4c5b6a"`
+	Job     string `json:"job" example:"9a7e5c3b-1d2f-4e6a-8b9c-0d1e2f3a4b5c"`
+}
+
+// ObjectReplicationStatus -- pending, failed, replicated or missing at the target
+type ObjectReplicationStatus struct {
+	Bucket string                    `json:"bucket" example:"prod-data"`
+	Key    string                    `json:"key" example:"reports/q1.csv"`
+	Rules  []ObjectReplicationByRule `json:"rules"`
+}
+
+type ObjectReplicationByRule struct {
+	RuleID   string `json:"ruleId" example:"0d9c8c1e-5b7a-4f0e-b7a5-1f2e3d4c5b6a"`
+	Status   string `json:"status" example:"replicated"`
+	Attempts int    `json:"attempts,omitempty" example:"0"`
+	Error    string `json:"error,omitempty"`
+}
+
+// SyncRequest -- source is a local directory or s3://bucket/prefix, destination always
+// a bucket; only new and changed files are transferred
+type SyncRequest struct {
+	Source      string `json:"source" binding:"required" example:"s3://prod-data/reports/"`
+	Destination string `json:"destination" binding:"required" example:"s3://archive/reports/"`
+	// delete destination keys that are not in the source
+	Delete bool `json:"delete,omitempty" example:"false"`
+	// only report what would be done
+	DryRun bool `json:"dryRun,omitempty" example:"true"`
+	// compare checksums instead of modification times
+	Checksum bool `json:"checksum,omitempty" example:"false"`
+	// files transferred at a time, default sync.workers, at most jobs.maxConcurrency
+	Workers int `json:"workers,omitempty" example:"8"`
+}
+
+// Sync actions
+const (
+	SyncUpload = "upload"
+	SyncCopy   = "copy"
+	SyncDelete = "delete"
+)
+
+// SyncReport -- counters cover every object, actions and errors are capped at 1000
+type SyncReport struct {
+	Source      string       `json:"source" example:"s3://prod-data/reports/"`
+	Destination string       `json:"destination" example:"s3://archive/reports/"`
+	DryRun      bool         `json:"dryRun" example:"false"`
+	Started     time.Time    `json:"started"`
+	Finished    *time.Time   `json:"finished,omitempty"`
+	Scanned     int          `json:"scanned" example:"1000"`
+	Skipped     int          `json:"skipped" example:"980"`
+	Transferred int          `json:"transferred" example:"18"`
+	Deleted     int          `json:"deleted" example:"2"`
+	Failed      int          `json:"failed" example:"0"`
+	Bytes       int64        `json:"bytes" example:"1048576"`
+	Actions     []SyncAction `json:"actions"`
+	Errors      []SyncError  `json:"errors"`
+}
+
+type SyncAction struct {
+	Action string `json:"action" example:"upload"`
+	Key    string `json:"key" example:"reports/q1.csv"`
+	Size   int64  `json:"size,omitempty" example:"1234"`
+	// missing, size, checksum, newer or extraneous
+	Reason string `json:"reason" example:"newer"`
+}
+
+type SyncError struct {
+	Action string `json:"action" example:"upload"`
+	Key    string `json:"key" example:"reports/q1.csv"`
+	Error  string `json:"error" example:"Access Denied."`
+}
+
+// Job statuses
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+	JobCancelled = "cancelled"
+)
+
+// JobRequest -- params depend on the type: copy, delete, delete-bucket, sync,
+// replication-backfill or batch
+type JobRequest struct {
+	Type   string          `json:"type" binding:"required" example:"copy"`
+	Params json.RawMessage `json:"params" swaggertype:"object"`
+}
+
+// Job -- a long-running bulk operation; finished jobs are kept for jobs.retention
+type Job struct {
+	ID       string          `json:"id" example:"9a7e5c3b-1d2f-4e6a-8b9c-0d1e2f3a4b5c"`
+	Type     string          `json:"type" example:"copy"`
+	Status   string          `json:"status" example:"running"`
+	Params   json.RawMessage `json:"params" swaggertype:"object"`
+	Progress JobProgress     `json:"progress"`
+	// the first failed items, progress.failed counts all of them
+	Failures []JobFailure `json:"failures,omitempty"`
+	// type specific, e.g. the report of a sync
+	Result   json.RawMessage `json:"result,omitempty" swaggertype:"object"`
+	Error    string          `json:"error,omitempty"`
+	Created  time.Time       `json:"created"`
+	Started  *time.Time      `json:"started,omitempty"`
+	Finished *time.Time      `json:"finished,omitempty"`
+	// interrupted by a restart and run again from the start, this many times
+	Restarts int `json:"restarts,omitempty" example:"0"`
+}
+
+// JobProgress -- total is 0 while unknown
+type JobProgress struct {
+	Total  int64 `json:"total" example:"1000"`
+	Done   int64 `json:"done" example:"250"`
+	Failed int64 `json:"failed" example:"1"`
+	Bytes  int64 `json:"bytes" example:"1048576"`
+}
+
+type JobFailure struct {
+	Item  string `json:"item" example:"reports/q1.csv"`
+	Error string `json:"error" example:"Access Denied."`
+}
+
+type ListJobsResponse struct {
+	Jobs []Job `json:"jobs"`
+}
+
+// CopyJobParams -- copies every object under prefix, the prefix replaced by
+// destinationPrefix
+type CopyJobParams struct {
+	SourceBucket      string `json:"sourceBucket" example:"prod-data"`
+	Prefix            string `json:"prefix,omitempty" example:"reports/2024/"`
+	DestinationBucket string `json:"destinationBucket" example:"archive"`
+	DestinationPrefix string `json:"destinationPrefix,omitempty" example:"prod/reports/2024/"`
+}
+
+// DeleteJobParams -- deletes every object under prefix, an empty prefix empties the
+// bucket
+type DeleteJobParams struct {
+	Bucket string `json:"bucket" example:"scratch"`
+	Prefix string `json:"prefix,omitempty" example:"tmp/"`
+}
+
+// DeleteBucketJobParams -- deletes every object, version and incomplete upload, then
+// the bucket
+type DeleteBucketJobParams struct {
+	Bucket string `json:"bucket" example:"scratch"`
+}
+
+// BackfillJobParams -- a replication backfill, see POST /replication/rules/{id}/backfill
+type BackfillJobParams struct {
+	RuleID string `json:"ruleId" example:"0d9c8c1e-5b7a-4f0e-b7a5-1f2e3d4c5b6a"`
+}
+
+// BatchRequest -- applies one operation to every object listed in a manifest and
+// writes a CSV report of the outcome per object
+type BatchRequest struct {
+	Manifest  BatchManifest       `json:"manifest"`
+	Operation BatchOperation      `json:"operation"`
+	Report    BatchReportLocation `json:"report"`
+	// objects worked on at a time, default jobs.concurrency, at most jobs.maxConcurrency
+	Concurrency int `json:"concurrency,omitempty" example:"16"`
+}
+
+// BatchManifest -- an object holding CSV lines of bucket,key[,versionId], or JSON
+// lines like {"bucket":"b","key":"k","versionId":"v"}; format defaults from the key's
+// extension, .jsonl or .json for JSON lines
+type BatchManifest struct {
+	Bucket string `json:"bucket" example:"manifests"`
+	Key    string `json:"key" example:"retag-2024.csv"`
+	Format string `json:"format,omitempty" example:"csv"`
+}
+
+// BatchOperation -- type is copy, delete, tag, metadata or restore. restore makes the
+// listed version the current one again, so every manifest line needs a versionId.
+type BatchOperation struct {
+	Type string `json:"type" example:"tag"`
+	// copy: to this bucket, under destinationPrefix followed by the key
+	DestinationBucket string `json:"destinationBucket,omitempty" example:"archive"`
+	DestinationPrefix string `json:"destinationPrefix,omitempty" example:"2024/"`
+	// tag: replaces every object's tag set
+	Tags map[string]string `json:"tags,omitempty"`
+	// metadata: replaces every object's user metadata, and the content type if set
+	Metadata    map[string]string `json:"metadata,omitempty"`
+	ContentType string            `json:"contentType,omitempty" example:"text/csv"`
+}
+
+// BatchReportLocation -- the report is stored as <prefix>batch-<job id>.csv
+type BatchReportLocation struct {
+	Bucket string `json:"bucket" example:"manifests"`
+	Prefix string `json:"prefix,omitempty" example:"reports/"`
+	// only list the objects that failed
+	FailedOnly bool `json:"failedOnly,omitempty" example:"false"`
+}
+
+// BatchResult -- the result of a batch job
+type BatchResult struct {
+	ReportBucket string `json:"reportBucket" example:"manifests"`
+	ReportKey    string `json:"reportKey" example:"reports/batch-9a7e5c3b-1d2f-4e6a-8b9c-0d1e2f3a4b5c.csv"`
+	ReportError  string `json:"reportError,omitempty"`
+	Succeeded    int64  `json:"succeeded" example:"99998"`
+	Failed       int64  `json:"failed" example:"2"`
+}
+
+// ArchiveRequest -- the objects to download as one archive: the listed keys, or every
+// object under prefix when keys is empty; format is zip (default) or tar.gz
+type ArchiveRequest struct {
+	Keys   []string `json:"keys,omitempty" example:"reports/q1.csv,reports/q2.csv"`
+	Prefix string   `json:"prefix,omitempty" example:"reports/"`
+	Format string   `json:"format,omitempty" example:"zip"`
+}
+
+// ArchiveManifest -- written as the last entry of every archive, _manifest.json
+type ArchiveManifest struct {
+	Bucket string           `json:"bucket" example:"prod-data"`
+	Prefix string           `json:"prefix,omitempty" example:"reports/"`
+	Files  int              `json:"files" example:"42"`
+	Bytes  int64            `json:"bytes" example:"1048576"`
+	Failed []ArchiveFailure `json:"failed"`
+}
+
+type ArchiveFailure struct {
+	Key   string `json:"key" example:"reports/q3.csv"`
+	Error string `json:"error" example:"The specified key does not exist."`
 }
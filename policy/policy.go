@@ -0,0 +1,292 @@
+// Package policy validates S3 bucket policies and answers whether a policy grants
+// anonymous read access, which the gateway's public route relies on.
+package policy
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	maxSize     = 20 << 10
+	arnPrefix   = "arn:aws:s3:::"
+	publicSid   = "GatewayPublicRead"
+	versionNew  = "2012-10-17"
+	versionOld  = "2008-10-17"
+	getObject   = "s3:GetObject"
+	effectAllow = "Allow"
+	effectDeny  = "Deny"
+)
+
+type Document struct {
+	Version   string      `json:"Version"`
+	ID        string      `json:"Id,omitempty"`
+	Statement []Statement `json:"Statement"`
+}
+
+type Statement struct {
+	Sid          string          `json:"Sid,omitempty"`
+	Effect       string          `json:"Effect"`
+	Principal    json.RawMessage `json:"Principal,omitempty" swaggertype:"object"`
+	NotPrincipal json.RawMessage `json:"NotPrincipal,omitempty" swaggertype:"object"`
+	Action       Values          `json:"Action,omitempty" swaggertype:"array,string"`
+	NotAction    Values          `json:"NotAction,omitempty" swaggertype:"array,string"`
+	Resource     Values          `json:"Resource,omitempty" swaggertype:"array,string"`
+	NotResource  Values          `json:"NotResource,omitempty" swaggertype:"array,string"`
+	Condition    json.RawMessage `json:"Condition,omitempty" swaggertype:"object"`
+}
+
+// Values -- policy fields that may be written as a single string or a list
+type Values []string
+
+func (v *Values) UnmarshalJSON(b []byte) error {
+	var one string
+	if err := json.Unmarshal(b, &one); err == nil {
+		*v = Values{one}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(b, &many); err != nil {
+		return errors.New("expected a string or a list of strings")
+	}
+	*v = many
+	return nil
+}
+
+// Parse -- strict decoding, unknown fields are rejected
+func Parse(raw []byte) (*Document, error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	var doc Document
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("invalid policy JSON: %w", err)
+	}
+	return &doc, nil
+}
+
+// Validate -- checks a policy for the given bucket before it is sent to the backend
+func Validate(bucket string, raw []byte) error {
+	if len(raw) > maxSize {
+		return fmt.Errorf("policy is %d bytes, at most %d are allowed", len(raw), maxSize)
+	}
+	doc, err := Parse(raw)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	if doc.Version != versionNew && doc.Version != versionOld {
+		errs = append(errs, fmt.Errorf("Version must be %s or %s", versionNew, versionOld))
+	}
+	if len(doc.Statement) == 0 {
+		errs = append(errs, errors.New("policy needs at least one Statement"))
+	}
+	for i, st := range doc.Statement {
+		fail := func(format string, args ...any) {
+			errs = append(errs, fmt.Errorf("statement %d: %s", i, fmt.Sprintf(format, args...)))
+		}
+		if st.Effect != effectAllow && st.Effect != effectDeny {
+			fail("Effect must be Allow or Deny")
+		}
+		if (len(st.Principal) == 0) == (len(st.NotPrincipal) == 0) {
+			fail("exactly one of Principal or NotPrincipal is required")
+		}
+		if (len(st.Action) == 0) == (len(st.NotAction) == 0) {
+			fail("exactly one of Action or NotAction is required")
+		}
+		for _, a := range append(st.Action, st.NotAction...) {
+			if a != "*" && !strings.HasPrefix(a, "s3:") {
+				fail("action %q is not an s3 action", a)
+			}
+		}
+		if (len(st.Resource) == 0) == (len(st.NotResource) == 0) {
+			fail("exactly one of Resource or NotResource is required")
+		}
+		for _, r := range append(st.Resource, st.NotResource...) {
+			if err := checkResource(bucket, r); err != nil {
+				fail("%v", err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func checkResource(bucket, resource string) error {
+	name, ok := strings.CutPrefix(resource, arnPrefix)
+	if !ok {
+		return fmt.Errorf("resource %q must start with %s", resource, arnPrefix)
+	}
+	name, _, _ = strings.Cut(name, "/")
+	if !match(name, bucket) {
+		return fmt.Errorf("resource %q does not belong to bucket %s", resource, bucket)
+	}
+	return nil
+}
+
+// AllowsAnonymousRead -- whether the policy lets unauthenticated users get the object
+// statements with conditions cannot be evaluated here: such an Allow is ignored and
+// such a Deny is honoured, so the answer errs on the side of refusing
+func AllowsAnonymousRead(doc *Document, bucket, key string) bool {
+	resource := arnPrefix + bucket + "/" + key
+	allowed := false
+	for _, st := range doc.Statement {
+		if !st.appliesToAnonymous() || !st.matchesAction(getObject) || !st.matchesResource(resource) {
+			continue
+		}
+		switch {
+		case st.Effect == effectDeny:
+			return false
+		case st.Effect == effectAllow && len(st.Condition) == 0:
+			allowed = true
+		}
+	}
+	return allowed
+}
+
+func (st Statement) appliesToAnonymous() bool {
+	if len(st.NotPrincipal) > 0 {
+		return !isAnonymous(st.NotPrincipal)
+	}
+	return isAnonymous(st.Principal)
+}
+
+func (st Statement) matchesAction(action string) bool {
+	if len(st.NotAction) > 0 {
+		return !matchAny(st.NotAction, action)
+	}
+	return matchAny(st.Action, action)
+}
+
+func (st Statement) matchesResource(resource string) bool {
+	if len(st.NotResource) > 0 {
+		return !matchAny(st.NotResource, resource)
+	}
+	return matchAny(st.Resource, resource)
+}
+
+// isAnonymous -- "*" or {"AWS": "*"} / {"AWS": ["*"]}
+func isAnonymous(principal json.RawMessage) bool {
+	var s string
+	if json.Unmarshal(principal, &s) == nil {
+		return s == "*"
+	}
+	var m map[string]Values
+	if json.Unmarshal(principal, &m) != nil {
+		return false
+	}
+	for _, v := range m["AWS"] {
+		if v == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+func matchAny(patterns []string, s string) bool {
+	for _, p := range patterns {
+		if match(p, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// match -- policy wildcard matching, * for any run of characters and ? for one
+func match(pattern, s string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			for i := len(s); i >= 0; i-- {
+				if match(pattern[1:], s[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if len(s) == 0 {
+				return false
+			}
+		default:
+			if len(s) == 0 || s[0] != pattern[0] {
+				return false
+			}
+		}
+		pattern, s = pattern[1:], s[1:]
+	}
+	return len(s) == 0
+}
+
+// AddPublicRead -- adds (or replaces) a statement granting anonymous reads below prefix,
+// the rest of the policy is kept as it is
+func AddPublicRead(raw string, bucket, prefix string) (string, error) {
+	doc, err := parseOrNew(raw)
+	if err != nil {
+		return "", err
+	}
+	doc.Statement = withoutSid(doc.Statement, publicReadSid(prefix))
+	doc.Statement = append(doc.Statement, Statement{
+		Sid:       publicReadSid(prefix),
+		Effect:    effectAllow,
+		Principal: json.RawMessage(`{"AWS":["*"]}`),
+		Action:    Values{getObject},
+		Resource:  Values{arnPrefix + bucket + "/" + prefix + "*"},
+	})
+	out, err := json.Marshal(doc)
+	return string(out), err
+}
+
+// RemovePublicRead -- drops the statement added by AddPublicRead for prefix,
+// an empty result means the policy can be removed altogether
+func RemovePublicRead(raw string, prefix string) (string, error) {
+	doc, err := parseOrNew(raw)
+	if err != nil {
+		return "", err
+	}
+	doc.Statement = withoutSid(doc.Statement, publicReadSid(prefix))
+	if len(doc.Statement) == 0 {
+		return "", nil
+	}
+	out, err := json.Marshal(doc)
+	return string(out), err
+}
+
+// PublicPrefixes -- prefixes opened with AddPublicRead
+func PublicPrefixes(doc *Document, bucket string) []string {
+	prefixes := []string{}
+	for _, st := range doc.Statement {
+		if !strings.HasPrefix(st.Sid, publicSid) || len(st.Resource) != 1 {
+			continue
+		}
+		p := strings.TrimPrefix(st.Resource[0], arnPrefix+bucket+"/")
+		prefixes = append(prefixes, strings.TrimSuffix(p, "*"))
+	}
+	return prefixes
+}
+
+func parseOrNew(raw string) (*Document, error) {
+	if strings.TrimSpace(raw) == "" {
+		return &Document{Version: versionNew}, nil
+	}
+	return Parse([]byte(raw))
+}
+
+func withoutSid(statements []Statement, sid string) []Statement {
+	out := statements[:0]
+	for _, st := range statements {
+		if st.Sid != sid {
+			out = append(out, st)
+		}
+	}
+	return out
+}
+
+// publicReadSid -- Sids only allow alphanumerics, so the prefix is hashed
+func publicReadSid(prefix string) string {
+	sum := sha1.Sum([]byte(prefix))
+	return publicSid + hex.EncodeToString(sum[:6])
+}
@@ -0,0 +1,137 @@
+package policy
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern, s string
+		want       bool
+	}{
+		{"photos", "photos", true},
+		{"photos", "photos2", false},
+		{"photo?", "photos", true},
+		{"photo?", "photo", false},
+		{"*", "", true},
+		{"arn:aws:s3:::photos/*", "arn:aws:s3:::photos/2024/a.jpg", true},
+		{"arn:aws:s3:::photos/*", "arn:aws:s3:::photos2/a.jpg", false},
+		{"arn:aws:s3:::photos/*.jpg", "arn:aws:s3:::photos/a/b.jpg", true},
+		{"arn:aws:s3:::photos/*.jpg", "arn:aws:s3:::photos/a.png", false},
+		{"a*b*c", "aXXbYYc", true},
+		{"a*b*c", "aXXcYYb", false},
+	}
+	for _, tt := range tests {
+		if got := match(tt.pattern, tt.s); got != tt.want {
+			t.Errorf("match(%q, %q) = %v, want %v", tt.pattern, tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestAllowsAnonymousRead(t *testing.T) {
+	const allowPub = `{"Effect":"Allow","Principal":"*","Action":"s3:GetObject","Resource":"arn:aws:s3:::photos/pub/*"}`
+	tests := []struct {
+		name       string
+		statements string
+		key        string
+		want       bool
+	}{
+		{"allowed prefix", allowPub, "pub/a.jpg", true},
+		{"other prefix", allowPub, "private/a.jpg", false},
+		{"principal AWS list", `{"Effect":"Allow","Principal":{"AWS":["*"]},"Action":["s3:GetObject"],"Resource":["arn:aws:s3:::photos/*"]}`, "a.jpg", true},
+		{"named principal", `{"Effect":"Allow","Principal":{"AWS":"arn:aws:iam::1:root"},"Action":"s3:GetObject","Resource":"arn:aws:s3:::photos/*"}`, "a.jpg", false},
+		{"action wildcard", `{"Effect":"Allow","Principal":"*","Action":"s3:Get*","Resource":"arn:aws:s3:::photos/*"}`, "a.jpg", true},
+		{"other action", `{"Effect":"Allow","Principal":"*","Action":"s3:PutObject","Resource":"arn:aws:s3:::photos/*"}`, "a.jpg", false},
+		{"NotAction", `{"Effect":"Allow","Principal":"*","NotAction":"s3:PutObject","Resource":"arn:aws:s3:::photos/*"}`, "a.jpg", true},
+		{"NotResource", `{"Effect":"Allow","Principal":"*","Action":"s3:GetObject","NotResource":"arn:aws:s3:::photos/private/*"}`, "private/a.jpg", false},
+		{"deny wins", allowPub + `,{"Effect":"Deny","Principal":"*","Action":"s3:GetObject","Resource":"arn:aws:s3:::photos/pub/secret*"}`, "pub/secret.txt", false},
+		{"conditional allow ignored", `{"Effect":"Allow","Principal":"*","Action":"s3:GetObject","Resource":"arn:aws:s3:::photos/*","Condition":{"IpAddress":{"aws:SourceIp":"10.0.0.0/8"}}}`, "a.jpg", false},
+		{"conditional deny honoured", allowPub + `,{"Effect":"Deny","Principal":"*","Action":"s3:GetObject","Resource":"arn:aws:s3:::photos/*","Condition":{"Bool":{"aws:SecureTransport":"false"}}}`, "pub/a.jpg", false},
+		{"NotPrincipal excluding anonymous", `{"Effect":"Allow","NotPrincipal":"*","Action":"s3:GetObject","Resource":"arn:aws:s3:::photos/*"}`, "a.jpg", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := Parse([]byte(`{"Version":"2012-10-17","Statement":[` + tt.statements + `]}`))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := AllowsAnonymousRead(doc, "photos", tt.key); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	doc := func(statement string) string {
+		return `{"Version":"2012-10-17","Statement":[` + statement + `]}`
+	}
+	tests := []struct {
+		name   string
+		policy string
+		want   string
+	}{
+		{"valid", doc(`{"Effect":"Allow","Principal":"*","Action":"s3:GetObject","Resource":"arn:aws:s3:::photos/*"}`), ""},
+		{"bucket wildcard", doc(`{"Effect":"Allow","Principal":"*","Action":"s3:ListBucket","Resource":"arn:aws:s3:::phot*"}`), ""},
+		{"unknown field", `{"Version":"2012-10-17","Statements":[]}`, "unknown field"},
+		{"version", `{"Version":"2020-01-01","Statement":[{"Effect":"Allow","Principal":"*","Action":"s3:GetObject","Resource":"arn:aws:s3:::photos/*"}]}`, "Version must be"},
+		{"no statements", doc(``), "at least one Statement"},
+		{"effect", doc(`{"Effect":"Maybe","Principal":"*","Action":"s3:GetObject","Resource":"arn:aws:s3:::photos/*"}`), "Effect must be"},
+		{"principal and NotPrincipal", doc(`{"Effect":"Allow","Principal":"*","NotPrincipal":"*","Action":"s3:GetObject","Resource":"arn:aws:s3:::photos/*"}`), "Principal or NotPrincipal"},
+		{"non s3 action", doc(`{"Effect":"Allow","Principal":"*","Action":"iam:PassRole","Resource":"arn:aws:s3:::photos/*"}`), "not an s3 action"},
+		{"no resource", doc(`{"Effect":"Allow","Principal":"*","Action":"s3:GetObject"}`), "Resource or NotResource"},
+		{"other bucket", doc(`{"Effect":"Allow","Principal":"*","Action":"s3:GetObject","Resource":"arn:aws:s3:::videos/*"}`), "does not belong to bucket photos"},
+		{"not an arn", doc(`{"Effect":"Allow","Principal":"*","Action":"s3:GetObject","Resource":"photos/*"}`), "must start with"},
+		{"too large", `{"Version":"` + strings.Repeat("x", maxSize) + `"}`, "at most"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate("photos", []byte(tt.policy))
+			if tt.want == "" {
+				if err != nil {
+					t.Errorf("unexpected error %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("got %v, want an error containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestPublicRead(t *testing.T) {
+	raw, err := AddPublicRead("", "photos", "pub/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// adding the same prefix again replaces its statement
+	if raw, err = AddPublicRead(raw, "photos", "pub/"); err != nil {
+		t.Fatal(err)
+	}
+	if raw, err = AddPublicRead(raw, "photos", "shared/"); err != nil {
+		t.Fatal(err)
+	}
+	if err := Validate("photos", []byte(raw)); err != nil {
+		t.Fatalf("generated policy is invalid: %v", err)
+	}
+	doc, _ := Parse([]byte(raw))
+	if got := PublicPrefixes(doc, "photos"); len(got) != 2 || got[0] != "pub/" || got[1] != "shared/" {
+		t.Errorf("public prefixes %v", got)
+	}
+	if !AllowsAnonymousRead(doc, "photos", "pub/a.jpg") || AllowsAnonymousRead(doc, "photos", "private/a.jpg") {
+		t.Error("published prefixes not applied")
+	}
+
+	if raw, err = RemovePublicRead(raw, "pub/"); err != nil {
+		t.Fatal(err)
+	}
+	doc, _ = Parse([]byte(raw))
+	if AllowsAnonymousRead(doc, "photos", "pub/a.jpg") || !AllowsAnonymousRead(doc, "photos", "shared/a.jpg") {
+		t.Error("removing one prefix changed the other")
+	}
+	if raw, err = RemovePublicRead(raw, "shared/"); err != nil || raw != "" {
+		t.Errorf("removing the last prefix left %q, %v", raw, err)
+	}
+}
@@ -0,0 +1,310 @@
+// Package queue is a small durable work queue: every pending item is a JSON file in
+// a directory, so items survive restarts and are retried with exponential backoff.
+// Items that keep failing are moved to a "dead" subdirectory for inspection.
+//
+// Items are handed out in the order they were pushed. Items pushed with the same key
+// are handed out one at a time: a later one waits while an earlier one is in flight
+// or waiting for its retry, so they are handled in order by any number of workers.
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+type Item struct {
+	ID          string          `json:"id"`
+	Payload     json.RawMessage `json:"payload"`
+	Attempts    int             `json:"attempts"`
+	Created     time.Time       `json:"created"`
+	NextAttempt time.Time       `json:"nextAttempt"`
+	LastError   string          `json:"lastError,omitempty"`
+	// Key -- items with the same key are handled in order, empty for none
+	Key string `json:"key,omitempty"`
+	// Seq -- push order, kept across restarts
+	Seq uint64 `json:"seq"`
+}
+
+type Options struct {
+	Name        string
+	Workers     int
+	MaxAttempts int
+	Backoff     time.Duration
+	MaxBackoff  time.Duration
+	Logger      *zap.Logger
+}
+
+type Queue struct {
+	dir      string
+	mu       sync.Mutex
+	items    map[string]*Item
+	inflight map[string]bool
+	seq      uint64
+	wake     chan struct{}
+}
+
+// Open -- loads every pending item found in dir
+func Open(dir string) (*Queue, error) {
+	if err := os.MkdirAll(filepath.Join(dir, "dead"), 0o755); err != nil {
+		return nil, err
+	}
+	q := &Queue{
+		dir:      dir,
+		items:    make(map[string]*Item),
+		inflight: make(map[string]bool),
+		wake:     make(chan struct{}, 1),
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range files {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		var it Item
+		if err := json.Unmarshal(data, &it); err != nil {
+			// a torn write from a crash, nothing to deliver
+			os.Remove(f)
+			continue
+		}
+		q.items[it.ID] = &it
+		q.seq = max(q.seq, it.Seq)
+	}
+	return q, nil
+}
+
+// Push -- stores payload durably before returning, it is delivered by Run after the
+// items pushed before it with the same key
+func (q *Queue) Push(key string, payload any) (string, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+	now := time.Now().UTC()
+	it := &Item{ID: uuid.New().String(), Key: key, Payload: data, Created: now, NextAttempt: now}
+	// the lock is held across the write so sequence numbers reach items in order
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.seq++
+	it.Seq = q.seq
+	if err := q.write(it); err != nil {
+		return "", err
+	}
+	q.items[it.ID] = it
+	q.notify()
+	return it.ID, nil
+}
+
+// Stats -- pending items and the age of the oldest one
+func (q *Queue) Stats() (pending int, oldest time.Time) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, it := range q.items {
+		if oldest.IsZero() || it.Created.Before(oldest) {
+			oldest = it.Created
+		}
+	}
+	return len(q.items), oldest
+}
+
+// Items -- copies of the pending items, including those waiting for a retry
+func (q *Queue) Items() []Item {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	out := make([]Item, 0, len(q.items))
+	for _, it := range q.items {
+		out = append(out, *it)
+	}
+	return out
+}
+
+// Dead -- the items that failed permanently
+func (q *Queue) Dead() ([]Item, error) {
+	files, err := filepath.Glob(filepath.Join(q.dir, "dead", "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Item, 0, len(files))
+	for _, f := range files {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		var it Item
+		if json.Unmarshal(data, &it) == nil {
+			out = append(out, it)
+		}
+	}
+	return out, nil
+}
+
+// RemoveDead -- forgets a failed item, e.g. once it was redone another way
+func (q *Queue) RemoveDead(id string) error {
+	err := os.Remove(filepath.Join(q.dir, "dead", filepath.Base(q.path(id))))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// Run -- hands due items to handle on opts.Workers goroutines until ctx is cancelled
+// an item is removed once handle returns nil, otherwise it is retried later
+func (q *Queue) Run(ctx context.Context, opts Options, handle func(context.Context, Item) error) {
+	if opts.Workers <= 0 {
+		opts.Workers = 1
+	}
+	jobs := make(chan Item)
+	var wg sync.WaitGroup
+	for i := 0; i < opts.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for it := range jobs {
+				q.finish(it, handle(ctx, it), opts)
+			}
+		}()
+	}
+	defer func() {
+		close(jobs)
+		wg.Wait()
+	}()
+
+	for {
+		for _, it := range q.claimDue(time.Now()) {
+			select {
+			case jobs <- it:
+			case <-ctx.Done():
+				return
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-q.wake:
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// claimDue -- the due items in push order, leaving out those whose key has an
+// earlier item still pending
+func (q *Queue) claimDue(now time.Time) []Item {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	pending := make([]*Item, 0, len(q.items))
+	for _, it := range q.items {
+		pending = append(pending, it)
+	}
+	sort.Slice(pending, func(i, j int) bool {
+		a, b := pending[i], pending[j]
+		if a.Seq != b.Seq {
+			return a.Seq < b.Seq
+		}
+		if !a.Created.Equal(b.Created) {
+			return a.Created.Before(b.Created)
+		}
+		return a.ID < b.ID
+	})
+
+	var due []Item
+	blocked := make(map[string]bool)
+	for _, it := range pending {
+		if it.Key != "" {
+			if blocked[it.Key] {
+				continue
+			}
+			blocked[it.Key] = true
+		}
+		if !q.inflight[it.ID] && !it.NextAttempt.After(now) {
+			q.inflight[it.ID] = true
+			due = append(due, *it)
+		}
+	}
+	return due
+}
+
+func (q *Queue) finish(it Item, err error, opts Options) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	delete(q.inflight, it.ID)
+
+	if err == nil {
+		delete(q.items, it.ID)
+		os.Remove(q.path(it.ID))
+		// a later item with the same key may be due now
+		q.notify()
+		return
+	}
+
+	it.Attempts++
+	it.LastError = err.Error()
+	log := opts.Logger.With(zap.String("queue", opts.Name), zap.String("item", it.ID), zap.Int("attempts", it.Attempts), zap.Error(err))
+	if opts.MaxAttempts > 0 && it.Attempts >= opts.MaxAttempts {
+		delete(q.items, it.ID)
+		if werr := q.write(&it); werr == nil {
+			os.Rename(q.path(it.ID), filepath.Join(q.dir, "dead", filepath.Base(q.path(it.ID))))
+		}
+		log.Error("Queue item failed permanently")
+		q.notify()
+		return
+	}
+
+	it.NextAttempt = time.Now().UTC().Add(backoff(it.Attempts, opts.Backoff, opts.MaxBackoff))
+	q.items[it.ID] = &it
+	if werr := q.write(&it); werr != nil {
+		log.Error("Queue item state could not be saved", zap.NamedError("writeError", werr))
+	}
+	log.Warn("Queue item failed, will retry", zap.Time("nextAttempt", it.NextAttempt))
+}
+
+// backoff -- exponential with +-20% jitter so failed items do not retry in lockstep
+func backoff(attempt int, base, limit time.Duration) time.Duration {
+	if base <= 0 {
+		base = time.Second
+	}
+	d := base
+	for i := 1; i < attempt && (limit <= 0 || d < limit); i++ {
+		d *= 2
+	}
+	if limit > 0 && d > limit {
+		d = limit
+	}
+	jitter := 0.8 + 0.4*rand.Float64()
+	return time.Duration(float64(d) * jitter)
+}
+
+func (q *Queue) notify() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (q *Queue) path(id string) string {
+	return filepath.Join(q.dir, strings.ReplaceAll(id, string(filepath.Separator), "_")+".json")
+}
+
+func (q *Queue) write(it *Item) error {
+	data, err := json.Marshal(it)
+	if err != nil {
+		return err
+	}
+	tmp := q.path(it.ID) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, q.path(it.ID))
+}
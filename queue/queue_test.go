@@ -0,0 +1,237 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func testOptions() Options {
+	return Options{Name: "test", Workers: 1, MaxAttempts: 3, Backoff: time.Hour, MaxBackoff: time.Hour, Logger: zap.NewNop()}
+}
+
+func openQueue(t *testing.T, dir string) *Queue {
+	t.Helper()
+	q, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return q
+}
+
+func push(t *testing.T, q *Queue, key string, payload any) string {
+	t.Helper()
+	id, err := q.Push(key, payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+func ids(items []Item) []string {
+	out := make([]string, len(items))
+	for i, it := range items {
+		out[i] = it.ID
+	}
+	return out
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestClaimOrder(t *testing.T) {
+	q := openQueue(t, t.TempDir())
+	opts := testOptions()
+	a1 := push(t, q, "a", 1)
+	b1 := push(t, q, "b", 1)
+	a2 := push(t, q, "a", 2)
+	n1 := push(t, q, "", 1)
+	n2 := push(t, q, "", 2)
+
+	if got, want := ids(q.claimDue(time.Now())), []string{a1, b1, n1, n2}; !equal(got, want) {
+		t.Fatalf("first claim: got %v, want %v", got, want)
+	}
+	if got := q.claimDue(time.Now()); len(got) != 0 {
+		t.Fatalf("a2 was claimed while a1 is in flight: %v", ids(got))
+	}
+
+	// a failed a1 waits for its retry and holds a2 back
+	items := q.Items()
+	var first Item
+	for _, it := range items {
+		if it.ID == a1 {
+			first = it
+		}
+	}
+	q.finish(first, errors.New("broker down"), opts)
+	if got := q.claimDue(time.Now()); len(got) != 0 {
+		t.Fatalf("a2 was claimed while a1 backs off: %v", ids(got))
+	}
+
+	// once a1 succeeds a2 is next
+	q.claimDue(time.Now().Add(2 * time.Hour))
+	q.finish(first, nil, opts)
+	if got, want := ids(q.claimDue(time.Now())), []string{a2}; !equal(got, want) {
+		t.Fatalf("after a1: got %v, want %v", got, want)
+	}
+}
+
+func TestOrderSurvivesRestart(t *testing.T) {
+	dir := t.TempDir()
+	q := openQueue(t, dir)
+	var want []string
+	for i := 0; i < 20; i++ {
+		want = append(want, push(t, q, "", i))
+	}
+
+	q = openQueue(t, dir)
+	want = append(want, push(t, q, "", 20))
+	if got := ids(q.claimDue(time.Now())); !equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestRunKeepsKeyOrder(t *testing.T) {
+	q := openQueue(t, t.TempDir())
+	const n = 50
+	for i := 0; i < n; i++ {
+		push(t, q, "k", i)
+	}
+
+	var (
+		mu       sync.Mutex
+		got      []int
+		inflight atomic.Int32
+		overlap  atomic.Bool
+	)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	opts := testOptions()
+	opts.Workers = 4
+	go func() {
+		defer close(done)
+		q.Run(ctx, opts, func(_ context.Context, it Item) error {
+			if inflight.Add(1) > 1 {
+				overlap.Store(true)
+			}
+			defer inflight.Add(-1)
+			var i int
+			json.Unmarshal(it.Payload, &i)
+			mu.Lock()
+			got = append(got, i)
+			if len(got) == n {
+				cancel()
+			}
+			mu.Unlock()
+			return nil
+		})
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("queue did not drain")
+	}
+
+	if overlap.Load() {
+		t.Error("items with the same key were handled concurrently")
+	}
+	for i, v := range got {
+		if v != i {
+			t.Fatalf("handled out of order: %v", got)
+		}
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		name    string
+		attempt int
+		base    time.Duration
+		limit   time.Duration
+		want    time.Duration
+	}{
+		{"first retry", 1, time.Second, time.Minute, time.Second},
+		{"doubles", 3, time.Second, time.Minute, 4 * time.Second},
+		{"capped", 10, time.Second, time.Minute, time.Minute},
+		{"no cap", 10, time.Second, 0, 512 * time.Second},
+		{"default base", 2, 0, time.Minute, 2 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := 0; i < 20; i++ {
+				got := backoff(tt.attempt, tt.base, tt.limit)
+				if lo, hi := tt.want*8/10, tt.want*12/10; got < lo || got > hi {
+					t.Fatalf("got %v, want %v +-20%%", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestRetryAndDeadLetter(t *testing.T) {
+	dir := t.TempDir()
+	q := openQueue(t, dir)
+	opts := testOptions()
+	id := push(t, q, "k", "payload")
+	next := push(t, q, "k", "next")
+
+	// every failure but the last schedules a retry, kept across restarts
+	for attempt := 1; attempt < opts.MaxAttempts; attempt++ {
+		claimed := q.claimDue(time.Now().Add(time.Duration(attempt) * 2 * time.Hour))
+		if got := ids(claimed); !equal(got, []string{id}) {
+			t.Fatalf("attempt %d: claimed %v, want %v", attempt, got, id)
+		}
+		before := time.Now()
+		q.finish(claimed[0], errors.New("broker down"), opts)
+
+		q = openQueue(t, dir)
+		var it Item
+		for _, i := range q.Items() {
+			if i.ID == id {
+				it = i
+			}
+		}
+		if it.Attempts != attempt || it.LastError != "broker down" {
+			t.Fatalf("attempt %d: saved %d attempts, error %q", attempt, it.Attempts, it.LastError)
+		}
+		if !it.NextAttempt.After(before) {
+			t.Fatalf("attempt %d: next attempt %v is not in the future", attempt, it.NextAttempt)
+		}
+	}
+
+	claimed := q.claimDue(time.Now().Add(24 * time.Hour))
+	q.finish(claimed[0], errors.New("broker down"), opts)
+	dead, err := q.Dead()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dead) != 1 || dead[0].ID != id || dead[0].Attempts != opts.MaxAttempts {
+		t.Fatalf("dead letters: %+v", dead)
+	}
+	// the dead item no longer holds back its key
+	if got := ids(q.claimDue(time.Now())); !equal(got, []string{next}) {
+		t.Errorf("after dead-lettering claimed %v, want %v", got, next)
+	}
+
+	if err := q.RemoveDead(id); err != nil {
+		t.Fatal(err)
+	}
+	if dead, _ := q.Dead(); len(dead) != 0 {
+		t.Errorf("RemoveDead left %+v", dead)
+	}
+}
@@ -0,0 +1,142 @@
+package replication
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"go.uber.org/zap"
+	"kluisz-object-storage/events"
+	"kluisz-object-storage/jobs"
+	"kluisz-object-storage/models"
+	"kluisz-object-storage/storage"
+)
+
+// backfillJob -- the job type backfills run as
+const backfillJob = "replication-backfill"
+
+var backfillMu sync.Mutex
+
+// Backfill -- submits a job queueing every object under the rule's prefix that is
+// missing at the target or differs from it; progress is reported by Status and by the
+// job
+func Backfill(r models.ReplicationRule) (models.Job, error) {
+	backfillMu.Lock()
+	defer backfillMu.Unlock()
+	for _, j := range jobs.List() {
+		if j.Type != backfillJob || (j.Status != models.JobQueued && j.Status != models.JobRunning) {
+			continue
+		}
+		var p models.BackfillJobParams
+		if json.Unmarshal(j.Params, &p) == nil && p.RuleID == r.ID {
+			return j, ErrBackfillRunning
+		}
+	}
+	job, err := jobs.Submit(backfillJob, models.BackfillJobParams{RuleID: r.ID})
+	if err != nil {
+		return job, err
+	}
+	statsMu.Lock()
+	statsFor(r.ID).backfill = &models.BackfillStatus{Running: true, Job: job.ID, Started: job.Created}
+	statsMu.Unlock()
+	return job, nil
+}
+
+func checkBackfill(params json.RawMessage) error {
+	var p models.BackfillJobParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return err
+	}
+	if _, ok := Rules.Get(p.RuleID); !ok {
+		return fmt.Errorf("no replication rule %q", p.RuleID)
+	}
+	return nil
+}
+
+func runBackfill(ctx context.Context, job models.Job, p *jobs.Progress) (any, error) {
+	var params models.BackfillJobParams
+	if err := json.Unmarshal(job.Params, &params); err != nil {
+		return nil, err
+	}
+	r, ok := Rules.Get(params.RuleID)
+	if !ok {
+		return nil, errors.New("the replication rule was deleted")
+	}
+	statsMu.Lock()
+	s := statsFor(r.ID)
+	s.backfill = &models.BackfillStatus{Running: true, Job: job.ID, Started: time.Now().UTC()}
+	statsMu.Unlock()
+
+	err := backfill(ctx, r, p)
+	statsMu.Lock()
+	defer statsMu.Unlock()
+	b := s.backfill
+	b.Running = false
+	finished := time.Now().UTC()
+	b.Finished = &finished
+	if err != nil {
+		b.Error = err.Error()
+	}
+	logger.Info("Replication backfill finished", zap.String("rule", r.ID),
+		zap.Int("scanned", b.Scanned), zap.Int("queued", b.Queued), zap.Error(err))
+	return *b, err
+}
+
+func backfill(ctx context.Context, r models.ReplicationRule, p *jobs.Progress) error {
+	source, err := storage.ClientFor(r.SourceBucket)
+	if err != nil {
+		return err
+	}
+	target, err := storage.NewBackendClient(r.TargetBackend)
+	if err != nil {
+		return err
+	}
+
+	for obj := range source.ListObjects(ctx, r.SourceBucket, minio.ListObjectsOptions{Prefix: r.Prefix, Recursive: true}) {
+		if obj.Err != nil {
+			return obj.Err
+		}
+		queue := false
+		existing, err := target.StatObject(ctx, r.TargetBucket, obj.Key, minio.StatObjectOptions{})
+		switch {
+		case minio.ToErrorResponse(err).Code == "NoSuchKey":
+			queue = true
+		case err != nil:
+			return err
+		default:
+			queue = !same(obj, existing)
+		}
+		if queue {
+			if err := enqueue(r.ID, events.ObjectCreated, r.SourceBucket, obj.Key); err != nil {
+				return err
+			}
+		}
+
+		statsMu.Lock()
+		b := stats[r.ID].backfill
+		b.Scanned++
+		if queue {
+			b.Queued++
+		}
+		statsMu.Unlock()
+		p.Done(0)
+	}
+	return ctx.Err()
+}
+
+// same -- a copy matches its source; multipart ETags depend on the part size, so for
+// those only the size is compared
+func same(source, target minio.ObjectInfo) bool {
+	if source.Size != target.Size {
+		return false
+	}
+	if strings.Contains(source.ETag, "-") || strings.Contains(target.ETag, "-") {
+		return true
+	}
+	return source.ETag == target.ETag
+}
@@ -0,0 +1,227 @@
+// Package replication mirrors objects to other backends. Gateway write events matching
+// a rule are queued on disk and copied asynchronously with retries; a backfill queues
+// the objects that existed before the rule or were missed.
+package replication
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"go.uber.org/zap"
+	"kluisz-object-storage/config"
+	"kluisz-object-storage/events"
+	"kluisz-object-storage/jobs"
+	"kluisz-object-storage/jsonstore"
+	"kluisz-object-storage/models"
+	"kluisz-object-storage/queue"
+	"kluisz-object-storage/storage"
+)
+
+// Rules -- replication rules by ID, set up by Start
+var Rules *jsonstore.Store[models.ReplicationRule]
+
+var (
+	outbox *queue.Queue
+	logger *zap.Logger
+)
+
+// task -- what the queue keeps per object change
+type task struct {
+	RuleID string      `json:"ruleId"`
+	Type   events.Type `json:"type"`
+	Bucket string      `json:"bucket"`
+	Key    string      `json:"key"`
+}
+
+// progress since start, per rule
+type ruleStats struct {
+	replicated     int64
+	lastReplicated time.Time
+	backfill       *models.BackfillStatus
+}
+
+var (
+	statsMu sync.Mutex
+	stats   = make(map[string]*ruleStats)
+)
+
+func statsFor(ruleID string) *ruleStats {
+	s, ok := stats[ruleID]
+	if !ok {
+		s = &ruleStats{}
+		stats[ruleID] = s
+	}
+	return s
+}
+
+// Start -- loads the rules and the queue, then replicates matching changes until ctx
+// is cancelled. Changes are queued before the publishing request returns.
+func Start(ctx context.Context, log *zap.Logger) error {
+	store, err := jsonstore.Open[models.ReplicationRule](filepath.Join(config.Get().DataDir, "replication.json"))
+	if err != nil {
+		return err
+	}
+	q, err := queue.Open(filepath.Join(config.Get().DataDir, "outbox", "replication"))
+	if err != nil {
+		return err
+	}
+	Rules, outbox, logger = store, q, log
+	jobs.Register(backfillJob, jobs.Kind{Check: checkBackfill, Run: runBackfill})
+
+	events.Subscribe(func(e events.Event) {
+		if e.Type == events.BucketRemoved {
+			forgetBucket(e.Bucket)
+			return
+		}
+		if e.Type != events.ObjectCreated && e.Type != events.ObjectRemoved {
+			return
+		}
+		for _, r := range Rules.All() {
+			if !Matches(r, e.Bucket, e.Key) || (e.Type == events.ObjectRemoved && !r.Deletes) {
+				continue
+			}
+			if err := enqueue(r.ID, e.Type, e.Bucket, e.Key); err != nil {
+				logger.Error("Replication could not be queued", zap.String("rule", r.ID), zap.String("key", e.Key), zap.Error(err))
+			}
+		}
+	})
+
+	cfg := config.Get().Replication
+	go outbox.Run(ctx, queue.Options{
+		Name:        "replication",
+		Workers:     cfg.Workers,
+		MaxAttempts: cfg.MaxAttempts,
+		Backoff:     cfg.Backoff,
+		MaxBackoff:  cfg.MaxBackoff,
+		Logger:      logger,
+	}, replicate)
+	return nil
+}
+
+// forgetBucket -- drops the rules of a removed source bucket, they must not apply to
+// a future bucket with the same name
+func forgetBucket(bucket string) {
+	for id, r := range Rules.All() {
+		if r.SourceBucket != bucket {
+			continue
+		}
+		if err := Rules.Delete(id); err != nil {
+			logger.Error("Replication rule of a removed bucket could not be deleted", zap.String("rule", id), zap.Error(err))
+		}
+	}
+}
+
+// Matches -- the object is in the rule's source bucket under its prefix
+func Matches(r models.ReplicationRule, bucket, key string) bool {
+	return r.SourceBucket == bucket && strings.HasPrefix(key, r.Prefix)
+}
+
+func enqueue(ruleID string, typ events.Type, bucket, key string) error {
+	_, err := outbox.Push(ruleID+"/"+key, task{RuleID: ruleID, Type: typ, Bucket: bucket, Key: key})
+	return err
+}
+
+func replicate(ctx context.Context, it queue.Item) error {
+	var t task
+	if err := json.Unmarshal(it.Payload, &t); err != nil {
+		return nil // undecodable, retrying cannot help
+	}
+	r, ok := Rules.Get(t.RuleID)
+	if !ok {
+		return nil // rule removed since the change was queued
+	}
+	target, err := storage.NewBackendClient(r.TargetBackend)
+	if err != nil {
+		return err
+	}
+
+	if t.Type == events.ObjectRemoved {
+		err = removeObject(ctx, r, t.Key, target)
+	} else {
+		err = copyObject(ctx, r, t.Key, target)
+	}
+	if err != nil {
+		return err
+	}
+
+	statsMu.Lock()
+	s := statsFor(r.ID)
+	s.replicated++
+	s.lastReplicated = time.Now().UTC()
+	statsMu.Unlock()
+	forgetFailures(t.RuleID, t.Key)
+	return nil
+}
+
+// removeObject -- removes the object from the target, unless it exists at the source
+// again: then it was uploaded after the removal and is copied instead, so a late or
+// retried removal never loses an object the source still has
+func removeObject(ctx context.Context, r models.ReplicationRule, key string, target *minio.Client) error {
+	source, err := storage.ClientFor(r.SourceBucket)
+	if err != nil {
+		return err
+	}
+	statCtx, cancel := context.WithTimeout(ctx, config.Get().Timeouts.Other)
+	defer cancel()
+	_, err = source.StatObject(statCtx, r.SourceBucket, key, minio.StatObjectOptions{})
+	switch {
+	case err == nil:
+		return copyObject(ctx, r, key, target)
+	case minio.ToErrorResponse(err).Code != "NoSuchKey":
+		return err
+	}
+	return target.RemoveObject(statCtx, r.TargetBucket, key, minio.RemoveObjectOptions{})
+}
+
+// copyObject -- streams the source object to the target with its content type and
+// metadata, so envelope-encrypted objects stay readable through the gateway
+func copyObject(ctx context.Context, r models.ReplicationRule, key string, target *minio.Client) error {
+	source, err := storage.ClientFor(r.SourceBucket)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, config.Get().Timeouts.Put)
+	defer cancel()
+
+	obj, err := source.GetObject(ctx, r.SourceBucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		return err
+	}
+	defer obj.Close()
+	info, err := obj.Stat()
+	if err != nil {
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			// removed again before it was copied, the removal is replicated on its own
+			return nil
+		}
+		return err
+	}
+	_, err = storage.Put(ctx, target, r.TargetBucket, key, obj, info.Size, minio.PutObjectOptions{
+		ContentType:  info.ContentType,
+		UserMetadata: info.UserMetadata,
+	})
+	return err
+}
+
+// forgetFailures -- drops permanent failures of a key once it was replicated after all
+func forgetFailures(ruleID, key string) {
+	dead, err := outbox.Dead()
+	if err != nil {
+		return
+	}
+	for _, it := range dead {
+		var t task
+		if json.Unmarshal(it.Payload, &t) == nil && t.RuleID == ruleID && t.Key == key {
+			outbox.RemoveDead(it.ID)
+		}
+	}
+}
+
+// ErrBackfillRunning -- a rule has at most one backfill queued or running at a time
+var ErrBackfillRunning = errors.New("a backfill of this rule is already running")
@@ -0,0 +1,146 @@
+package replication
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"kluisz-object-storage/models"
+	"kluisz-object-storage/queue"
+	"kluisz-object-storage/storage"
+)
+
+// most failures listed per rule, the count covers all of them
+const maxFailures = 20
+
+// Status -- lag, progress and failures of every rule
+func Status() (models.ReplicationStatus, error) {
+	dead, err := outbox.Dead()
+	if err != nil {
+		return models.ReplicationStatus{}, err
+	}
+	byRule := make(map[string]*models.RuleStatus)
+	out := models.ReplicationStatus{Rules: []models.RuleStatus{}}
+	rules := Rules.All()
+	for _, r := range rules {
+		byRule[r.ID] = &models.RuleStatus{Rule: r}
+	}
+
+	now := time.Now()
+	oldest := make(map[string]time.Time)
+	for _, it := range outbox.Items() {
+		t, ok := decode(it)
+		rs := byRule[t.RuleID]
+		if !ok || rs == nil {
+			continue
+		}
+		rs.Pending++
+		if o, ok := oldest[t.RuleID]; !ok || it.Created.Before(o) {
+			oldest[t.RuleID] = it.Created
+		}
+		if it.Attempts > 0 {
+			rs.Failures = append(rs.Failures, failure(t, it, false))
+		}
+	}
+	for _, it := range dead {
+		t, ok := decode(it)
+		rs := byRule[t.RuleID]
+		if !ok || rs == nil {
+			continue
+		}
+		rs.Failed++
+		rs.Failures = append(rs.Failures, failure(t, it, true))
+	}
+
+	statsMu.Lock()
+	for id, rs := range byRule {
+		if o, ok := oldest[id]; ok {
+			rs.LagSeconds = now.Sub(o).Seconds()
+		}
+		if s, ok := stats[id]; ok {
+			rs.Replicated = s.replicated
+			if !s.lastReplicated.IsZero() {
+				last := s.lastReplicated
+				rs.LastReplicated = &last
+			}
+			if s.backfill != nil {
+				b := *s.backfill
+				rs.Backfill = &b
+			}
+		}
+		sort.Slice(rs.Failures, func(i, j int) bool { return rs.Failures[i].Since.Before(rs.Failures[j].Since) })
+		if len(rs.Failures) > maxFailures {
+			rs.Failures = rs.Failures[:maxFailures]
+		}
+		out.Rules = append(out.Rules, *rs)
+	}
+	statsMu.Unlock()
+
+	sort.Slice(out.Rules, func(i, j int) bool { return out.Rules[i].Rule.Created.Before(out.Rules[j].Rule.Created) })
+	return out, nil
+}
+
+// ObjectStatus -- per matching rule, whether the object is still pending, failed, or
+// found at the target ("replicated") or not ("missing")
+func ObjectStatus(ctx context.Context, bucket, key string) (models.ObjectReplicationStatus, error) {
+	out := models.ObjectReplicationStatus{Bucket: bucket, Key: key, Rules: []models.ObjectReplicationByRule{}}
+	dead, err := outbox.Dead()
+	if err != nil {
+		return out, err
+	}
+	queued := func(ruleID string) (models.ObjectReplicationByRule, bool) {
+		for _, it := range outbox.Items() {
+			if t, ok := decode(it); ok && t.RuleID == ruleID && t.Bucket == bucket && t.Key == key {
+				return models.ObjectReplicationByRule{RuleID: ruleID, Status: "pending", Attempts: it.Attempts, Error: it.LastError}, true
+			}
+		}
+		for _, it := range dead {
+			if t, ok := decode(it); ok && t.RuleID == ruleID && t.Bucket == bucket && t.Key == key {
+				return models.ObjectReplicationByRule{RuleID: ruleID, Status: "failed", Attempts: it.Attempts, Error: it.LastError}, true
+			}
+		}
+		return models.ObjectReplicationByRule{}, false
+	}
+
+	for _, r := range Rules.All() {
+		if !Matches(r, bucket, key) {
+			continue
+		}
+		if s, ok := queued(r.ID); ok {
+			out.Rules = append(out.Rules, s)
+			continue
+		}
+		s := models.ObjectReplicationByRule{RuleID: r.ID, Status: "replicated"}
+		target, err := storage.NewBackendClient(r.TargetBackend)
+		if err == nil {
+			_, err = target.StatObject(ctx, r.TargetBucket, key, minio.StatObjectOptions{})
+		}
+		switch {
+		case minio.ToErrorResponse(err).Code == "NoSuchKey":
+			s.Status = "missing"
+		case err != nil:
+			return out, err
+		}
+		out.Rules = append(out.Rules, s)
+	}
+	sort.Slice(out.Rules, func(i, j int) bool { return out.Rules[i].RuleID < out.Rules[j].RuleID })
+	return out, nil
+}
+
+func decode(it queue.Item) (task, bool) {
+	var t task
+	return t, json.Unmarshal(it.Payload, &t) == nil
+}
+
+func failure(t task, it queue.Item, permanent bool) models.ReplicationFailure {
+	return models.ReplicationFailure{
+		Bucket:    t.Bucket,
+		Key:       t.Key,
+		Attempts:  it.Attempts,
+		Error:     it.LastError,
+		Permanent: permanent,
+		Since:     it.Created,
+	}
+}
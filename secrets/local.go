@@ -0,0 +1,31 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+)
+
+// fileProvider -- file:///run/secrets/name, the whole file minus trailing newlines,
+// as mounted by Kubernetes and Docker secrets
+type fileProvider struct{}
+
+func (fileProvider) Fetch(_ context.Context, path string) (string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(b), "\r\n"), nil
+}
+
+// envProvider -- env://NAME, another environment variable
+type envProvider struct{}
+
+func (envProvider) Fetch(_ context.Context, name string) (string, error) {
+	v, ok := os.LookupEnv(name)
+	if !ok {
+		return "", errors.New("environment variable not set")
+	}
+	return v, nil
+}
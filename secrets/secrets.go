@@ -0,0 +1,138 @@
+// Package secrets resolves secret references in the configuration. A value like
+// file:///run/secrets/s3-secret-key, env://S3_SECRET_KEY or vault://kv/data/objstore#secretKey
+// is fetched from its provider and cached for the refresh interval, so rotated
+// secrets are picked up without a restart. Values without a scheme are used as is.
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"kluisz-object-storage/config"
+)
+
+// Provider -- fetches the secret named by ref, the part after "<scheme>://"
+type Provider interface {
+	Fetch(ctx context.Context, ref string) (string, error)
+}
+
+var (
+	mu        sync.RWMutex
+	providers = map[string]Provider{
+		"file": fileProvider{},
+		"env":  envProvider{},
+	}
+)
+
+// Register -- makes scheme:// references resolve through p
+func Register(scheme string, p Provider) {
+	mu.Lock()
+	defer mu.Unlock()
+	providers[scheme] = p
+}
+
+// Split -- the scheme and reference of value; ok is false for plain values and
+// unknown schemes, so a secret that happens to contain "://" is still used as is
+func Split(value string) (scheme, ref string, ok bool) {
+	scheme, ref, found := strings.Cut(value, "://")
+	if !found {
+		return "", "", false
+	}
+	mu.RLock()
+	defer mu.RUnlock()
+	_, ok = providers[scheme]
+	return scheme, ref, ok
+}
+
+type cached struct {
+	value   string
+	expires time.Time
+}
+
+var (
+	cacheMu sync.Mutex
+	cache   = make(map[string]cached)
+)
+
+// Value -- value itself, or the secret it refers to. Fetched secrets are kept for
+// secrets.refresh; when a later fetch fails the last good value is kept and the
+// failure logged, so a provider outage does not take the gateway down.
+func Value(ctx context.Context, value string) (string, error) {
+	v, _, err := Lookup(ctx, value)
+	return v, err
+}
+
+// Lookup -- Value, and when it is due to be fetched again; zero for plain values
+func Lookup(ctx context.Context, value string) (string, time.Time, error) {
+	scheme, ref, ok := Split(value)
+	if !ok {
+		return value, time.Time{}, nil
+	}
+
+	cacheMu.Lock()
+	c, hit := cache[value]
+	cacheMu.Unlock()
+	now := time.Now()
+	if hit && now.Before(c.expires) {
+		return c.value, c.expires, nil
+	}
+
+	mu.RLock()
+	p := providers[scheme]
+	mu.RUnlock()
+	v, err := p.Fetch(ctx, ref)
+	if err != nil {
+		err = fmt.Errorf("secret %s://%s: %w", scheme, ref, err)
+		if !hit {
+			return "", time.Time{}, err
+		}
+		zap.L().Warn("Secret refresh failed, keeping the previous value", zap.Error(err))
+		v = c.value
+	}
+	c = cached{value: v, expires: now.Add(config.Get().Secrets.Refresh)}
+	cacheMu.Lock()
+	cache[value] = c
+	cacheMu.Unlock()
+	return c.value, c.expires, nil
+}
+
+// Start -- registers the configured external providers and resolves the backends'
+// credentials once, so a bad reference fails at startup instead of on first use.
+// After a config reload the providers are set up again and every cached secret is
+// fetched anew on next use.
+func Start(ctx context.Context) error {
+	registerVault(config.Get().Secrets.Vault)
+	config.OnReload(func(old, new *config.Config) {
+		registerVault(new.Secrets.Vault)
+		cacheMu.Lock()
+		for k, c := range cache {
+			c.expires = time.Time{}
+			cache[k] = c
+		}
+		cacheMu.Unlock()
+	})
+	cfg := config.Get()
+	for _, name := range cfg.BackendNames() {
+		b, _ := cfg.Backend(name)
+		for _, value := range []string{b.AccessKey, b.SecretKey} {
+			if _, err := Value(ctx, value); err != nil {
+				return fmt.Errorf("backend %s: %w", name, err)
+			}
+		}
+	}
+	return nil
+}
+
+func registerVault(cfg config.VaultConfig) {
+	if cfg.Address == "" {
+		mu.Lock()
+		delete(providers, "vault")
+		mu.Unlock()
+		return
+	}
+	Register("vault", NewVault(cfg))
+}
@@ -0,0 +1,82 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"kluisz-object-storage/config"
+)
+
+// Vault -- secrets from a Vault compatible HTTP API. References are
+// vault://<mount>/<path>#<field>; KV version 2 paths include "data/", as in
+// vault://secret/data/objstore#secretKey. The token may itself be a file:// or env://
+// reference, so agent-renewed tokens are picked up as well; not a vault:// one, which
+// would need the token to fetch itself.
+type Vault struct {
+	cfg    config.VaultConfig
+	client *http.Client
+}
+
+func NewVault(cfg config.VaultConfig) *Vault {
+	return &Vault{cfg: cfg, client: &http.Client{Timeout: cfg.Timeout}}
+}
+
+func (v *Vault) Fetch(ctx context.Context, ref string) (string, error) {
+	path, field, ok := strings.Cut(ref, "#")
+	if !ok || field == "" {
+		return "", fmt.Errorf("missing #field")
+	}
+	if strings.HasPrefix(v.cfg.Token, "vault://") {
+		return "", errors.New("the vault token cannot be a vault:// reference")
+	}
+	token, err := Value(ctx, v.cfg.Token)
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		strings.TrimRight(v.cfg.Address, "/")+"/v1/"+strings.TrimLeft(path, "/"), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Vault-Token", token)
+	if v.cfg.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", v.cfg.Namespace)
+	}
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return "", fmt.Errorf("vault answered %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	var body struct {
+		Data map[string]any `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", err
+	}
+	data := body.Data
+	// KV version 2 nests the secret under data.data, next to data.metadata
+	if inner, ok := data["data"].(map[string]any); ok {
+		if _, ok := data["metadata"]; ok {
+			data = inner
+		}
+	}
+	value, ok := data[field]
+	if !ok {
+		return "", fmt.Errorf("field %q not found", field)
+	}
+	if s, ok := value.(string); ok {
+		return s, nil
+	}
+	return fmt.Sprint(value), nil
+}
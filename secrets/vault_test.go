@@ -0,0 +1,150 @@
+package secrets
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"kluisz-object-storage/config"
+)
+
+// fakeVault -- answers GET /v1/<path> with the JSON body stored for path, 404 for
+// unknown paths and 403 for a wrong token
+type fakeVault struct {
+	mu     sync.Mutex
+	token  string
+	bodies map[string]string
+	status int
+}
+
+func (f *fakeVault) set(path, body string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.bodies[path] = body
+}
+
+func (f *fakeVault) fail(status int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.status = status
+}
+
+func (f *fakeVault) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if r.Header.Get("X-Vault-Token") != f.token {
+		http.Error(w, `{"errors":["permission denied"]}`, http.StatusForbidden)
+		return
+	}
+	if f.status != 0 {
+		http.Error(w, `{"errors":["vault is sealed"]}`, f.status)
+		return
+	}
+	body, ok := f.bodies[strings.TrimPrefix(r.URL.Path, "/v1/")]
+	if !ok {
+		http.Error(w, `{"errors":[]}`, http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(body))
+}
+
+func startVault(t *testing.T) (*fakeVault, config.VaultConfig) {
+	t.Helper()
+	f := &fakeVault{token: "s.test", bodies: map[string]string{
+		"kv/objstore":          `{"data":{"secretKey":"from-v1"}}`,
+		"secret/data/objstore": `{"data":{"data":{"secretKey":"from-v2","port":9000},"metadata":{"version":3}}}`,
+		// a KV v1 secret that happens to have a field called data
+		"kv/nested": `{"data":{"data":{"secretKey":"inner"},"secretKey":"outer"}}`,
+	}}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	t.Setenv("VAULT_TEST_TOKEN", f.token)
+	return f, config.VaultConfig{Address: srv.URL + "/", Token: "env://VAULT_TEST_TOKEN", Timeout: 5 * time.Second}
+}
+
+func TestVaultFetch(t *testing.T) {
+	_, cfg := startVault(t)
+	config.Use(config.Config{Secrets: config.SecretsConfig{Refresh: time.Minute}})
+
+	tests := []struct {
+		name    string
+		token   string
+		ref     string
+		want    string
+		wantErr string
+	}{
+		{"kv v1", "", "kv/objstore#secretKey", "from-v1", ""},
+		{"kv v2", "", "secret/data/objstore#secretKey", "from-v2", ""},
+		{"non-string field", "", "secret/data/objstore#port", "9000", ""},
+		{"v1 field named data", "", "kv/nested#secretKey", "outer", ""},
+		{"leading slash", "", "/kv/objstore#secretKey", "from-v1", ""},
+		{"plain token", "s.test", "kv/objstore#secretKey", "from-v1", ""},
+		{"missing field", "", "kv/objstore#accessKey", "", `field "accessKey" not found`},
+		{"no field", "", "kv/objstore", "", "missing #field"},
+		{"unknown path", "", "kv/other#secretKey", "", "404 Not Found"},
+		{"wrong token", "s.wrong", "kv/objstore#secretKey", "", "403 Forbidden: {\"errors\":[\"permission denied\"]}"},
+		{"unset token variable", "env://VAULT_TEST_MISSING", "kv/objstore#secretKey", "", "environment variable not set"},
+		{"token from vault", "vault://kv/objstore#token", "kv/objstore#secretKey", "", "cannot be a vault:// reference"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := cfg
+			if tt.token != "" {
+				cfg.Token = tt.token
+			}
+			got, err := NewVault(cfg).Fetch(context.Background(), tt.ref)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got %q, %v; want an error containing %q", got, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVaultRefresh(t *testing.T) {
+	f, cfg := startVault(t)
+	// every lookup is due for a refresh
+	config.Use(config.Config{Secrets: config.SecretsConfig{Refresh: time.Nanosecond}})
+	Register("vault", NewVault(cfg))
+	t.Cleanup(func() { registerVault(config.VaultConfig{}) })
+	const ref = "vault://secret/data/rotating#secretKey"
+
+	if _, err := Value(context.Background(), ref); err == nil {
+		t.Fatal("a secret that was never fetched resolved without an error")
+	}
+	f.set("secret/data/rotating", `{"data":{"data":{"secretKey":"one"},"metadata":{}}}`)
+	lookup := func(want string) {
+		t.Helper()
+		got, _, err := Lookup(context.Background(), ref)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	}
+	lookup("one")
+
+	f.set("secret/data/rotating", `{"data":{"data":{"secretKey":"two"},"metadata":{}}}`)
+	lookup("two")
+
+	// an outage keeps the last good value
+	f.fail(http.StatusServiceUnavailable)
+	lookup("two")
+	f.set("secret/data/rotating", `{"data":{"data":{},"metadata":{}}}`)
+	f.fail(0)
+	lookup("two")
+}
@@ -0,0 +1,118 @@
+package sinks
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"kluisz-object-storage/config"
+)
+
+// amqpChannel -- a channel in confirm mode on its own connection
+type amqpChannel interface {
+	// Publish -- returns once the broker confirmed the message, acked is false when
+	// it was rejected
+	Publish(ctx context.Context, exchange, key string, msg amqp.Publishing) (acked bool, err error)
+	IsClosed() bool
+	Close() error
+}
+
+// amqpSink -- publishes persistent messages with publisher confirms; the connection
+// is opened on first use and again after the broker drops it
+type amqpSink struct {
+	url      string
+	exchange string
+	dial     func(url string) (amqpChannel, error)
+
+	mu sync.Mutex
+	ch amqpChannel
+}
+
+func newAMQP(cfg config.SinkConfig) (Sink, error) {
+	return &amqpSink{url: cfg.URL, exchange: cfg.Exchange, dial: dialAMQP}, nil
+}
+
+func (s *amqpSink) Send(ctx context.Context, m Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.ch == nil || s.ch.IsClosed() {
+		s.reset()
+		ch, err := s.dial(s.url)
+		if err != nil {
+			return err
+		}
+		s.ch = ch
+	}
+	acked, err := s.ch.Publish(ctx, s.exchange, m.Topic, amqp.Publishing{
+		ContentType:  "application/json",
+		DeliveryMode: amqp.Persistent,
+		MessageId:    m.ID,
+		Type:         m.Type,
+		Timestamp:    time.Now(),
+		Body:         m.Body,
+	})
+	if err != nil {
+		s.reset()
+		return err
+	}
+	if !acked {
+		return fmt.Errorf("broker rejected event %s", m.ID)
+	}
+	return nil
+}
+
+// reset -- drops the connection so the next send reconnects; s.mu must be held
+func (s *amqpSink) reset() {
+	if s.ch != nil {
+		s.ch.Close()
+	}
+	s.ch = nil
+}
+
+func (s *amqpSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reset()
+	return nil
+}
+
+type amqpConn struct {
+	conn *amqp.Connection
+	ch   *amqp.Channel
+}
+
+func dialAMQP(url string) (amqpChannel, error) {
+	conn, err := amqp.Dial(url)
+	if err != nil {
+		return nil, err
+	}
+	ch, err := conn.Channel()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if err := ch.Confirm(false); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &amqpConn{conn: conn, ch: ch}, nil
+}
+
+func (c *amqpConn) Publish(ctx context.Context, exchange, key string, msg amqp.Publishing) (bool, error) {
+	confirm, err := c.ch.PublishWithDeferredConfirmWithContext(ctx, exchange, key, false, false, msg)
+	if err != nil {
+		return false, err
+	}
+	return confirm.WaitContext(ctx)
+}
+
+func (c *amqpConn) IsClosed() bool {
+	return c.ch.IsClosed()
+}
+
+func (c *amqpConn) Close() error {
+	return c.conn.Close()
+}
@@ -0,0 +1,96 @@
+package sinks
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+type fakeChannel struct {
+	published []amqp.Publishing
+	keys      []string
+	err       error
+	nack      bool
+	closed    bool
+}
+
+func (c *fakeChannel) Publish(ctx context.Context, exchange, key string, msg amqp.Publishing) (bool, error) {
+	if c.err != nil {
+		return false, c.err
+	}
+	c.published = append(c.published, msg)
+	c.keys = append(c.keys, exchange+"/"+key)
+	return !c.nack, nil
+}
+
+func (c *fakeChannel) IsClosed() bool { return c.closed }
+
+func (c *fakeChannel) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestAMQPSend(t *testing.T) {
+	var channels []*fakeChannel
+	dialErr := errors.New("connection refused")
+	s := &amqpSink{url: "amqp://broker", exchange: "events", dial: func(url string) (amqpChannel, error) {
+		if dialErr != nil {
+			return nil, dialErr
+		}
+		ch := &fakeChannel{}
+		channels = append(channels, ch)
+		return ch, nil
+	}}
+	m := Message{Topic: "storage.photos", ID: "ev-1", Type: "ObjectCreated", Body: []byte(`{}`)}
+	ctx := context.Background()
+
+	// broker down: the send fails and the next one dials again
+	if err := s.Send(ctx, m); !errors.Is(err, dialErr) {
+		t.Fatalf("got %v, want the dial error", err)
+	}
+	dialErr = nil
+	if err := s.Send(ctx, m); err != nil {
+		t.Fatal(err)
+	}
+	ch := channels[0]
+	if len(ch.published) != 1 || ch.keys[0] != "events/storage.photos" {
+		t.Fatalf("published %v to %v", ch.published, ch.keys)
+	}
+	if p := ch.published[0]; p.MessageId != "ev-1" || p.Type != "ObjectCreated" || p.DeliveryMode != amqp.Persistent {
+		t.Errorf("unexpected publishing %+v", p)
+	}
+
+	// a rejected message is an error, the connection is kept
+	ch.nack = true
+	if err := s.Send(ctx, m); err == nil {
+		t.Error("nacked message reported as delivered")
+	}
+	ch.nack = false
+	if len(channels) != 1 || ch.closed {
+		t.Error("a nack dropped the connection")
+	}
+
+	// a publish error drops the connection, the next send reconnects
+	ch.err = errors.New("channel closed")
+	if err := s.Send(ctx, m); err == nil {
+		t.Error("publish error not returned")
+	}
+	if !ch.closed {
+		t.Error("failed connection was not closed")
+	}
+	if err := s.Send(ctx, m); err != nil || len(channels) != 2 {
+		t.Fatalf("no reconnect after a publish error: %v", err)
+	}
+
+	// a connection the broker closed is replaced as well
+	channels[1].closed = true
+	if err := s.Send(ctx, m); err != nil || len(channels) != 3 {
+		t.Fatalf("no reconnect after the broker closed the channel: %v", err)
+	}
+
+	if err := s.Close(); err != nil || !channels[2].closed {
+		t.Error("Close did not close the connection")
+	}
+}
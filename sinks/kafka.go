@@ -0,0 +1,54 @@
+package sinks
+
+import (
+	"context"
+	"strings"
+
+	"github.com/segmentio/kafka-go"
+	"kluisz-object-storage/config"
+)
+
+// kafkaWriter -- the part of *kafka.Writer the sink uses
+type kafkaWriter interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
+type kafkaSink struct {
+	w kafkaWriter
+}
+
+// newKafka -- url is a comma separated broker list; messages are keyed by object so
+// each object's events land on one partition, in order. Send writes one message at a
+// time, so the writer flushes every message instead of waiting to fill a batch.
+func newKafka(cfg config.SinkConfig) (Sink, error) {
+	var brokers []string
+	for _, b := range strings.Split(cfg.URL, ",") {
+		if b = strings.TrimSpace(b); b != "" {
+			brokers = append(brokers, b)
+		}
+	}
+	return &kafkaSink{w: &kafka.Writer{
+		Addr:                   kafka.TCP(brokers...),
+		Balancer:               &kafka.Hash{},
+		RequiredAcks:           kafka.RequireAll,
+		BatchSize:              1,
+		AllowAutoTopicCreation: true,
+	}}, nil
+}
+
+func (s *kafkaSink) Send(ctx context.Context, m Message) error {
+	return s.w.WriteMessages(ctx, kafka.Message{
+		Topic: m.Topic,
+		Key:   []byte(m.Key),
+		Value: m.Body,
+		Headers: []kafka.Header{
+			{Key: "X-Gateway-Event", Value: []byte(m.Type)},
+			{Key: "X-Gateway-Event-Id", Value: []byte(m.ID)},
+		},
+	})
+}
+
+func (s *kafkaSink) Close() error {
+	return s.w.Close()
+}
@@ -0,0 +1,78 @@
+package sinks
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/segmentio/kafka-go"
+	"kluisz-object-storage/config"
+)
+
+type fakeWriter struct {
+	msgs   []kafka.Message
+	err    error
+	closed bool
+}
+
+func (w *fakeWriter) WriteMessages(ctx context.Context, msgs ...kafka.Message) error {
+	if w.err != nil {
+		return w.err
+	}
+	w.msgs = append(w.msgs, msgs...)
+	return nil
+}
+
+func (w *fakeWriter) Close() error {
+	w.closed = true
+	return nil
+}
+
+func TestNewKafka(t *testing.T) {
+	s, err := newKafka(config.SinkConfig{URL: " kafka-1:9092, ,kafka-2:9092 "})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := s.(*kafkaSink).w.(*kafka.Writer)
+	if got := w.Addr.String(); got != "kafka-1:9092,kafka-2:9092" {
+		t.Errorf("brokers %q", got)
+	}
+	if w.BatchSize != 1 {
+		t.Errorf("BatchSize %d, each message must be flushed on its own", w.BatchSize)
+	}
+	if _, ok := w.Balancer.(*kafka.Hash); !ok {
+		t.Errorf("balancer %T, messages must be partitioned by key", w.Balancer)
+	}
+}
+
+func TestKafkaSend(t *testing.T) {
+	w := &fakeWriter{}
+	s := &kafkaSink{w: w}
+	m := Message{Topic: "storage", Key: "photos/a.jpg", ID: "ev-1", Type: "ObjectCreated", Body: []byte(`{}`)}
+	if err := s.Send(context.Background(), m); err != nil {
+		t.Fatal(err)
+	}
+	if len(w.msgs) != 1 {
+		t.Fatalf("%d messages written", len(w.msgs))
+	}
+	got := w.msgs[0]
+	if got.Topic != "storage" || string(got.Key) != "photos/a.jpg" || string(got.Value) != `{}` {
+		t.Errorf("unexpected message %+v", got)
+	}
+	headers := map[string]string{}
+	for _, h := range got.Headers {
+		headers[h.Key] = string(h.Value)
+	}
+	if headers["X-Gateway-Event"] != "ObjectCreated" || headers["X-Gateway-Event-Id"] != "ev-1" {
+		t.Errorf("headers %v", headers)
+	}
+
+	// a broker error is returned so the event stays queued
+	w.err = errors.New("leader not available")
+	if err := s.Send(context.Background(), m); !errors.Is(err, w.err) {
+		t.Errorf("got %v, want the writer's error", err)
+	}
+	if err := s.Close(); err != nil || !w.closed {
+		t.Error("Close did not close the writer")
+	}
+}
@@ -0,0 +1,66 @@
+package sinks
+
+import (
+	"context"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"kluisz-object-storage/config"
+)
+
+// flushTimeout -- how long core NATS waits for the server to confirm a publish when
+// ctx has no deadline of its own
+const flushTimeout = 10 * time.Second
+
+type natsSink struct {
+	conn *nats.Conn
+	js   jetstream.JetStream
+}
+
+// newNATS -- the connection retries in the background, so a server that is down at
+// startup only delays delivery
+func newNATS(cfg config.SinkConfig) (Sink, error) {
+	conn, err := nats.Connect(cfg.URL,
+		nats.Name("kluisz-object-storage"),
+		nats.RetryOnFailedConnect(true),
+		nats.MaxReconnects(-1),
+	)
+	if err != nil {
+		return nil, err
+	}
+	s := &natsSink{conn: conn}
+	if cfg.JetStream {
+		if s.js, err = jetstream.New(conn); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// Send -- with JetStream the stream acknowledges the message and drops duplicates
+// by event ID; core NATS only confirms the server received it
+func (s *natsSink) Send(ctx context.Context, m Message) error {
+	msg := nats.NewMsg(m.Topic)
+	msg.Data = m.Body
+	msg.Header.Set(nats.MsgIdHdr, m.ID)
+	msg.Header.Set("X-Gateway-Event", m.Type)
+	if s.js != nil {
+		_, err := s.js.PublishMsg(ctx, msg)
+		return err
+	}
+	if err := s.conn.PublishMsg(msg); err != nil {
+		return err
+	}
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, flushTimeout)
+		defer cancel()
+	}
+	return s.conn.FlushWithContext(ctx)
+}
+
+func (s *natsSink) Close() error {
+	return s.conn.Drain()
+}
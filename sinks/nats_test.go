@@ -0,0 +1,160 @@
+package sinks
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"kluisz-object-storage/config"
+)
+
+func runNATS(t *testing.T, port int) *server.Server {
+	t.Helper()
+	s, err := server.NewServer(&server.Options{
+		Host:      "127.0.0.1",
+		Port:      port,
+		JetStream: true,
+		StoreDir:  t.TempDir(),
+		NoLog:     true,
+		NoSigs:    true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go s.Start()
+	if !s.ReadyForConnections(5 * time.Second) {
+		t.Fatal("nats server did not start")
+	}
+	t.Cleanup(s.Shutdown)
+	return s
+}
+
+func connect(t *testing.T, url string) *nats.Conn {
+	t.Helper()
+	nc, err := nats.Connect(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(nc.Close)
+	return nc
+}
+
+func TestNATSSend(t *testing.T) {
+	srv := runNATS(t, -1)
+	sub, err := connect(t, srv.ClientURL()).SubscribeSync("storage.>")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := newNATS(config.SinkConfig{URL: srv.ClientURL()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := Message{Topic: "storage.photos", ID: "ev-1", Type: "ObjectCreated", Body: []byte(`{"key":"a.jpg"}`)}
+	if err := s.Send(context.Background(), m); err != nil {
+		t.Fatal(err)
+	}
+	msg, err := sub.NextMsg(5 * time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(msg.Data) != `{"key":"a.jpg"}` || msg.Header.Get(nats.MsgIdHdr) != "ev-1" || msg.Header.Get("X-Gateway-Event") != "ObjectCreated" {
+		t.Errorf("unexpected message %s %v", msg.Data, msg.Header)
+	}
+
+	// Close drains the connection and then closes it
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "the connection to close", s.(*natsSink).conn.IsClosed)
+}
+
+func TestNATSReconnect(t *testing.T) {
+	srv := runNATS(t, -1)
+	addr := srv.Addr().(*net.TCPAddr)
+	url := fmt.Sprintf("nats://127.0.0.1:%d", addr.Port)
+
+	s, err := newNATS(config.SinkConfig{URL: url})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	m := Message{Topic: "storage.photos", ID: "ev-1", Type: "ObjectCreated", Body: []byte(`{}`)}
+
+	// while the server is down the send is not confirmed, the event stays queued
+	srv.Shutdown()
+	srv.WaitForShutdown()
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	if err := s.Send(ctx, m); err == nil {
+		t.Fatal("send without a server was reported as delivered")
+	}
+
+	// the retry goes through once the server is back on the same address
+	runNATS(t, addr.Port)
+	sub, err := connect(t, url).SubscribeSync("storage.>")
+	if err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		err := s.Send(ctx, m)
+		cancel()
+		if err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("no delivery after the server came back: %v", err)
+		}
+	}
+	if _, err := sub.NextMsg(5 * time.Second); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNATSJetStream(t *testing.T) {
+	srv := runNATS(t, -1)
+	js, err := jetstream.New(connect(t, srv.ClientURL()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	stream, err := js.CreateStream(ctx, jetstream.StreamConfig{Name: "EVENTS", Subjects: []string{"storage.>"}, Duplicates: time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := newNATS(config.SinkConfig{URL: srv.ClientURL(), JetStream: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	m := Message{Topic: "storage.photos", ID: "ev-1", Type: "ObjectCreated", Body: []byte(`{}`)}
+	// a retried delivery of the same event is stored once
+	for i := 0; i < 2; i++ {
+		if err := s.Send(ctx, m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	info, err := stream.Info(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.State.Msgs != 1 {
+		t.Errorf("stream holds %d messages, want 1", info.State.Msgs)
+	}
+
+	// without a stream for the subject nothing acknowledges, the event is retried
+	m.Topic = "other.photos"
+	ctx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	if err := s.Send(ctx, m); err == nil {
+		t.Error("unacknowledged message reported as delivered")
+	}
+}
@@ -0,0 +1,63 @@
+package sinks
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"kluisz-object-storage/config"
+	"kluisz-object-storage/secrets"
+)
+
+// rotatingSink -- a sink whose URL is a secret reference. Once the secret is due for
+// a refresh the next Send looks it up again and, when it changed, connects to the new
+// URL before closing the old connection; sends still running on the old one may fail
+// and are retried.
+type rotatingSink struct {
+	cfg  config.SinkConfig
+	dial func(cfg config.SinkConfig, url string) (Sink, error)
+
+	mu      sync.Mutex
+	sink    Sink
+	url     string
+	expires time.Time
+}
+
+func (s *rotatingSink) Send(ctx context.Context, m Message) error {
+	sink, err := s.current(ctx)
+	if err != nil {
+		return err
+	}
+	return sink.Send(ctx, m)
+}
+
+// current -- the connection for the URL the secret holds now
+func (s *rotatingSink) current(ctx context.Context) (Sink, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if time.Now().Before(s.expires) {
+		return s.sink, nil
+	}
+	url, expires, err := secrets.Lookup(ctx, s.cfg.URL)
+	if err != nil {
+		return nil, fmt.Errorf("sink %q: %w", s.cfg.Name, err)
+	}
+	if url != s.url {
+		// expires stays behind until the new URL connects, so the next Send tries again
+		sink, err := s.dial(s.cfg, url)
+		if err != nil {
+			return nil, fmt.Errorf("sink %q: reconnecting with the rotated URL: %w", s.cfg.Name, err)
+		}
+		s.sink.Close()
+		s.sink, s.url = sink, url
+	}
+	s.expires = expires
+	return s.sink, nil
+}
+
+func (s *rotatingSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sink.Close()
+}
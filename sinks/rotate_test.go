@@ -0,0 +1,80 @@
+package sinks
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"kluisz-object-storage/config"
+)
+
+func TestRotatingSink(t *testing.T) {
+	// every send looks the URL up again
+	config.Use(config.Config{Secrets: config.SecretsConfig{Refresh: time.Nanosecond}})
+	t.Setenv("SINK_TEST_URL", "nats://one:4222")
+	cfg := config.SinkConfig{Name: "events", Type: "nats", URL: "env://SINK_TEST_URL"}
+
+	dialed := map[string]*fakeSink{}
+	var dialErr error
+	dial := func(cfg config.SinkConfig, url string) (Sink, error) {
+		if dialErr != nil {
+			return nil, dialErr
+		}
+		s := &fakeSink{}
+		dialed[url] = s
+		return s, nil
+	}
+	first, _ := dial(cfg, "nats://one:4222")
+	s := &rotatingSink{cfg: cfg, dial: dial, sink: first, url: "nats://one:4222"}
+	ctx := context.Background()
+
+	if err := s.Send(ctx, Message{ID: "1"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(dialed) != 1 {
+		t.Fatalf("an unchanged URL was dialed again: %v", dialed)
+	}
+
+	// a rotated URL that cannot be reached yet fails the send and keeps the old
+	// connection until the next attempt
+	t.Setenv("SINK_TEST_URL", "nats://two:4222")
+	dialErr = errors.New("connection refused")
+	if err := s.Send(ctx, Message{ID: "2"}); err == nil {
+		t.Fatal("send succeeded without a connection to the rotated URL")
+	}
+	if _, _, closed := dialed["nats://one:4222"].state(); closed {
+		t.Fatal("old connection closed before the new one was up")
+	}
+
+	dialErr = nil
+	if err := s.Send(ctx, Message{ID: "2"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, closed := dialed["nats://one:4222"].state(); !closed {
+		t.Error("old connection left open after rotation")
+	}
+	sent, _, _ := dialed["nats://two:4222"].state()
+	if len(sent) != 1 || sent[0].ID != "2" {
+		t.Errorf("rotated connection got %v", sent)
+	}
+
+	s.Close()
+	if _, _, closed := dialed["nats://two:4222"].state(); !closed {
+		t.Error("Close left the connection open")
+	}
+}
+
+func TestNewPlainURL(t *testing.T) {
+	s, err := New(config.SinkConfig{Name: "events", Type: "kafka", URL: "broker-1:9092", Topic: "t"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if _, ok := s.(*kafkaSink); !ok {
+		t.Errorf("a plain URL got a %T, want the broker's sink", s)
+	}
+	if _, err := New(config.SinkConfig{Name: "events", Type: "nats", URL: "env://SINK_TEST_UNSET"}); err == nil {
+		t.Error("an unresolvable URL reference was accepted")
+	}
+}
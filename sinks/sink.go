@@ -0,0 +1,132 @@
+// Package sinks delivers bucket events to message queues. Every configured sink gets
+// its own durable outbox, events are queued before the publishing request returns
+// and retried until the broker acknowledges them, so delivery is at least once and
+// consumers should deduplicate on the event ID.
+package sinks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"go.uber.org/zap"
+	"kluisz-object-storage/config"
+	"kluisz-object-storage/events"
+	"kluisz-object-storage/queue"
+	"kluisz-object-storage/secrets"
+)
+
+// Message -- one event as handed to a broker
+type Message struct {
+	Topic string
+	// bucket/key, brokers that partition keep each object's events in order
+	Key  string
+	ID   string
+	Type string
+	Body []byte
+}
+
+// Sink -- a broker connection; Send returns once the broker has accepted the
+// message, an error leaves the event queued for a retry
+type Sink interface {
+	Send(ctx context.Context, m Message) error
+	Close() error
+}
+
+// New -- the sink for cfg.Type. A URL that is a secret reference is resolved now, so
+// a bad one fails at startup, and again when the secrets cache expires; the sink
+// reconnects when the rotated URL differs.
+func New(cfg config.SinkConfig) (Sink, error) {
+	url, expires, err := secrets.Lookup(context.Background(), cfg.URL)
+	if err != nil {
+		return nil, fmt.Errorf("sink %q: %w", cfg.Name, err)
+	}
+	sink, err := open(cfg, url)
+	if err != nil || expires.IsZero() {
+		return sink, err
+	}
+	return &rotatingSink{cfg: cfg, dial: open, sink: sink, url: url, expires: expires}, nil
+}
+
+// open -- connects the sink for cfg.Type to url
+func open(cfg config.SinkConfig, url string) (Sink, error) {
+	cfg.URL = url
+	switch cfg.Type {
+	case "nats":
+		return newNATS(cfg)
+	case "kafka":
+		return newKafka(cfg)
+	case "amqp":
+		return newAMQP(cfg)
+	default:
+		return nil, fmt.Errorf("sink %q: unknown type %q, expected nats, kafka or amqp", cfg.Name, cfg.Type)
+	}
+}
+
+// Start -- connects the configured sinks and delivers matching events until ctx is
+// cancelled; brokers that are down only delay delivery
+func Start(ctx context.Context, logger *zap.Logger) error {
+	seen := make(map[string]bool)
+	for _, cfg := range config.Get().Events.Sinks {
+		if cfg.Name == "" || strings.ContainsAny(cfg.Name, `/\`) {
+			return fmt.Errorf("sink %q: name must be set and cannot contain path separators", cfg.Name)
+		}
+		if seen[cfg.Name] {
+			return fmt.Errorf("sink %q: duplicate name", cfg.Name)
+		}
+		seen[cfg.Name] = true
+
+		sink, err := New(cfg)
+		if err != nil {
+			return err
+		}
+		outbox, err := queue.Open(filepath.Join(config.Get().DataDir, "outbox", "sinks", cfg.Name))
+		if err != nil {
+			return err
+		}
+
+		events.Subscribe(func(e events.Event) {
+			if !events.Match(cfg.Events, cfg.Buckets, cfg.Prefix, cfg.Suffix, e) {
+				return
+			}
+			if _, err := outbox.Push(e.Bucket+"/"+e.Key, e); err != nil {
+				logger.Error("Sink delivery could not be queued", zap.String("sink", cfg.Name), zap.String("event", e.ID), zap.Error(err))
+			}
+		})
+
+		go deliver(ctx, cfg, sink, outbox, logger)
+	}
+	return nil
+}
+
+// deliver -- sends the queued events to sink until ctx is cancelled, then closes it
+func deliver(ctx context.Context, cfg config.SinkConfig, sink Sink, outbox *queue.Queue, logger *zap.Logger) {
+	outbox.Run(ctx, queue.Options{
+		Name:        "sink " + cfg.Name,
+		Workers:     cfg.Workers,
+		MaxAttempts: cfg.MaxAttempts,
+		Backoff:     cfg.Backoff,
+		MaxBackoff:  cfg.MaxBackoff,
+		Logger:      logger,
+	}, func(ctx context.Context, it queue.Item) error {
+		var e events.Event
+		if err := json.Unmarshal(it.Payload, &e); err != nil {
+			return nil // undecodable, retrying cannot help
+		}
+		return sink.Send(ctx, Message{
+			Topic: Topic(cfg.Topic, e),
+			Key:   e.Bucket + "/" + e.Key,
+			ID:    e.ID,
+			Type:  string(e.Type),
+			Body:  it.Payload,
+		})
+	})
+	sink.Close()
+}
+
+// Topic -- fills {bucket} and {type} in the configured topic
+func Topic(pattern string, e events.Event) string {
+	return strings.NewReplacer("{bucket}", e.Bucket, "{type}", string(e.Type)).Replace(pattern)
+}
@@ -0,0 +1,121 @@
+package sinks
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"kluisz-object-storage/config"
+	"kluisz-object-storage/events"
+	"kluisz-object-storage/queue"
+)
+
+// fakeSink -- fails the first failures sends, then records the messages
+type fakeSink struct {
+	mu       sync.Mutex
+	failures int
+	attempts int
+	sent     []Message
+	closed   bool
+}
+
+func (s *fakeSink) Send(ctx context.Context, m Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attempts++
+	if s.attempts <= s.failures {
+		return errors.New("broker unavailable")
+	}
+	s.sent = append(s.sent, m)
+	return nil
+}
+
+func (s *fakeSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	return nil
+}
+
+func (s *fakeSink) state() (sent []Message, attempts int, closed bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Message(nil), s.sent...), s.attempts, s.closed
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestDeliver(t *testing.T) {
+	outbox, err := queue.Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := events.Event{ID: "ev-1", Type: events.ObjectCreated, Bucket: "photos", Key: "2024/a.jpg"}
+	if _, err := outbox.Push(e.Bucket+"/"+e.Key, e); err != nil {
+		t.Fatal(err)
+	}
+
+	sink := &fakeSink{failures: 1}
+	cfg := config.SinkConfig{Name: "test", Topic: "storage.{bucket}.{type}", Workers: 1, MaxAttempts: 5, Backoff: time.Millisecond}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		deliver(ctx, cfg, sink, outbox, zap.NewNop())
+		close(done)
+	}()
+
+	// the first send fails, the event stays queued and is retried
+	waitFor(t, "the retried delivery", func() bool {
+		sent, _, _ := sink.state()
+		return len(sent) == 1
+	})
+	sent, attempts, _ := sink.state()
+	if attempts != 2 {
+		t.Errorf("delivered after %d attempts, want 2", attempts)
+	}
+	m := sent[0]
+	if m.Topic != "storage.photos.ObjectCreated" || m.Key != "photos/2024/a.jpg" || m.ID != "ev-1" || m.Type != "ObjectCreated" {
+		t.Errorf("unexpected message %+v", m)
+	}
+	waitFor(t, "the outbox to empty", func() bool {
+		pending, _ := outbox.Stats()
+		return pending == 0
+	})
+
+	// shutting down stops delivery and closes the broker connection
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("deliver did not return after cancel")
+	}
+	if _, _, closed := sink.state(); !closed {
+		t.Error("sink was not closed at shutdown")
+	}
+}
+
+func TestTopic(t *testing.T) {
+	e := events.Event{Type: events.ObjectRemoved, Bucket: "logs"}
+	tests := []struct{ pattern, want string }{
+		{"storage.{bucket}.{type}", "storage.logs.ObjectRemoved"},
+		{"events", "events"},
+		{"{bucket}", "logs"},
+	}
+	for _, tt := range tests {
+		if got := Topic(tt.pattern, e); got != tt.want {
+			t.Errorf("Topic(%q) = %q, want %q", tt.pattern, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package storage
+
+import (
+	"fmt"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"kluisz-object-storage/config"
+)
+
+// ClientFor -- S3 client for the backend that bucket is routed to
+func ClientFor(bucket string) (*minio.Client, error) {
+	return NewBackendClient(config.Get().Route(bucket))
+}
+
+// NewBackendClient -- S3 client for the named backend
+func NewBackendClient(name string) (*minio.Client, error) {
+	cfg, ok := config.Get().Backend(name)
+	if !ok {
+		return nil, fmt.Errorf("unknown backend %q", name)
+	}
+	transport, err := minio.DefaultTransport(cfg.UseSSL)
+	if err != nil {
+		return nil, err
+	}
+	return minio.New(cfg.Endpoint, &minio.Options{
+		Creds:  credentials.New(&secretCreds{backend: name}),
+		Secure: cfg.UseSSL,
+		Region: cfg.Region,
+		Transport: &resilient{
+			next:     &instrumented{next: transport, endpoint: cfg.Endpoint},
+			breaker:  BreakerFor(cfg.Endpoint),
+			endpoint: cfg.Endpoint,
+		},
+		// retries happen in the resilient transport, where they know the breaker
+		MaxRetries: 1,
+	})
+}
@@ -0,0 +1,56 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"kluisz-object-storage/config"
+	"kluisz-object-storage/secrets"
+)
+
+// secretCreds -- a backend's configured S3 keys, which may be secret references; they
+// expire with the secrets cache so rotated keys reach long-lived clients too
+type secretCreds struct {
+	credentials.Expiry
+	backend string
+}
+
+func (s *secretCreds) Retrieve() (credentials.Value, error) {
+	return s.RetrieveWithCredContext(nil)
+}
+
+func (s *secretCreds) RetrieveWithCredContext(_ *credentials.CredContext) (credentials.Value, error) {
+	cfg, ok := config.Get().Backend(s.backend)
+	if !ok {
+		return credentials.Value{}, fmt.Errorf("unknown backend %q", s.backend)
+	}
+	ctx := context.Background()
+	access, accessExpires, err := secrets.Lookup(ctx, cfg.AccessKey)
+	if err != nil {
+		return credentials.Value{}, err
+	}
+	secret, secretExpires, err := secrets.Lookup(ctx, cfg.SecretKey)
+	if err != nil {
+		return credentials.Value{}, err
+	}
+	expires := earliest(accessExpires, secretExpires)
+	if expires.IsZero() {
+		// plain keys never change
+		expires = time.Now().Add(24 * time.Hour)
+	}
+	s.SetExpiration(expires, 0)
+	return credentials.Value{
+		AccessKeyID:     access,
+		SecretAccessKey: secret,
+		SignerType:      credentials.SignatureV4,
+	}, nil
+}
+
+func earliest(a, b time.Time) time.Time {
+	if a.IsZero() || (!b.IsZero() && b.Before(a)) {
+		return b
+	}
+	return a
+}
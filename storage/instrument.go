@@ -0,0 +1,169 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"kluisz-object-storage/metrics"
+	"kluisz-object-storage/tracing"
+)
+
+// instrumented -- records latency and S3 error codes of every backend call, and
+// traces it as a child of the request span found in the call's context
+type instrumented struct {
+	next     http.RoundTripper
+	endpoint string
+}
+
+func (t *instrumented) RoundTrip(req *http.Request) (*http.Response, error) {
+	op := operation(req, req.URL.Host == t.endpoint)
+	ctx, span := tracing.Tracer().Start(req.Context(), "S3 "+op,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("rpc.system", "s3"),
+			attribute.String("rpc.method", op),
+			attribute.String("http.request.method", req.Method),
+			attribute.String("server.address", req.URL.Host),
+		))
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req.WithContext(ctx))
+	metrics.BackendDuration.WithLabelValues(op).Observe(time.Since(start).Seconds())
+	if err != nil {
+		metrics.BackendErrors.WithLabelValues(op, "NetworkError").Inc()
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		span.End()
+		return resp, err
+	}
+	span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
+	if resp.StatusCode >= 300 && resp.StatusCode != http.StatusNotModified {
+		code := errorCode(resp)
+		metrics.BackendErrors.WithLabelValues(op, code).Inc()
+		span.SetAttributes(attribute.String("aws.error.code", code))
+		span.SetStatus(codes.Error, code)
+	}
+	// the span covers reading the body too, for downloads that is most of the time
+	resp.Body = &spanBody{ReadCloser: resp.Body, span: span}
+	return resp, nil
+}
+
+type spanBody struct {
+	io.ReadCloser
+	span trace.Span
+	once sync.Once
+}
+
+func (b *spanBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(func() { b.span.End() })
+	return err
+}
+
+// errorCode -- the S3 error code from the XML body, which is small and put back for
+// the client to parse; HEAD responses have no body and only give the status
+func errorCode(resp *http.Response) string {
+	if resp.Body != nil && resp.ContentLength < 64<<10 {
+		body, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+		resp.Body.Close()
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+		var e struct {
+			Code string `xml:"Code"`
+		}
+		if err == nil && xml.Unmarshal(body, &e) == nil && e.Code != "" {
+			return e.Code
+		}
+	}
+	return strings.ReplaceAll(http.StatusText(resp.StatusCode), " ", "")
+}
+
+// subresources -- query parameters that select an operation, checked in order
+var subresources = []string{
+	"uploadId", "uploads", "delete", "location", "versioning", "versions", "lifecycle",
+	"policy", "cors", "encryption", "object-lock", "retention", "legal-hold", "tagging",
+	"notification", "replication", "acl",
+}
+
+// operation -- a bounded name for the S3 call, like PutObject or GetBucketLifecycle.
+// Path-style requests carry the bucket in the path, virtual-host ones in the host.
+func operation(req *http.Request, pathStyle bool) string {
+	parts := strings.SplitN(strings.TrimPrefix(req.URL.Path, "/"), "/", 2)
+	level := "Service"
+	switch {
+	case pathStyle && len(parts) == 2 && parts[1] != "", !pathStyle && parts[0] != "":
+		level = "Object"
+	case pathStyle && parts[0] != "", !pathStyle:
+		level = "Bucket"
+	}
+
+	q := req.URL.Query()
+	for _, sub := range subresources {
+		if !q.Has(sub) {
+			continue
+		}
+		switch {
+		case sub == "uploadId" && req.Method == http.MethodPut:
+			return "UploadPart"
+		case sub == "uploadId" && req.Method == http.MethodPost:
+			return "CompleteMultipartUpload"
+		case sub == "uploadId" && req.Method == http.MethodDelete:
+			return "AbortMultipartUpload"
+		case sub == "uploadId":
+			return "ListParts"
+		case sub == "uploads" && req.Method == http.MethodPost:
+			return "CreateMultipartUpload"
+		case sub == "uploads":
+			return "ListMultipartUploads"
+		case sub == "delete":
+			return "DeleteObjects"
+		case sub == "versions":
+			return "ListObjectVersions"
+		}
+		return verb(req.Method) + level + title(sub)
+	}
+
+	if level == "Service" {
+		return "ListBuckets"
+	}
+	if level == "Bucket" && req.Method == http.MethodGet {
+		return "ListObjects"
+	}
+	if level == "Object" && req.Method == http.MethodPut && req.Header.Get("X-Amz-Copy-Source") != "" {
+		return "CopyObject"
+	}
+	return verb(req.Method) + level
+}
+
+func verb(method string) string {
+	switch method {
+	case http.MethodGet:
+		return "Get"
+	case http.MethodPut:
+		return "Put"
+	case http.MethodDelete:
+		return "Delete"
+	case http.MethodHead:
+		return "Head"
+	case http.MethodPost:
+		return "Post"
+	}
+	return "Other"
+}
+
+// title -- "object-lock" becomes "ObjectLock", "legal-hold" becomes "LegalHold"
+func title(sub string) string {
+	var b strings.Builder
+	for _, w := range strings.Split(sub, "-") {
+		if w != "" {
+			b.WriteString(strings.ToUpper(w[:1]) + w[1:])
+		}
+	}
+	return b.String()
+}
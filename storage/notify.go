@@ -0,0 +1,60 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+
+	"kluisz-object-storage/events"
+)
+
+// ListenObjectEvents -- follows the backend's bucket notifications (a MinIO extension)
+// and reports object creations and removals as gateway events
+func ListenObjectEvents(ctx context.Context, bucket string, emit func(events.Event)) error {
+	client, err := ClientFor(bucket)
+	if err != nil {
+		return err
+	}
+	infos := client.ListenBucketNotification(ctx, bucket, "", "", []string{
+		"s3:ObjectCreated:*",
+		"s3:ObjectRemoved:*",
+	})
+	for info := range infos {
+		if info.Err != nil {
+			return info.Err
+		}
+		for _, r := range info.Records {
+			var typ events.Type
+			switch {
+			case strings.HasPrefix(r.EventName, "s3:ObjectCreated:"):
+				typ = events.ObjectCreated
+			case strings.HasPrefix(r.EventName, "s3:ObjectRemoved:"):
+				typ = events.ObjectRemoved
+			default:
+				continue
+			}
+			// keys arrive URL-encoded, as in S3 notifications
+			key, err := url.QueryUnescape(r.S3.Object.Key)
+			if err != nil {
+				key = r.S3.Object.Key
+			}
+			t, _ := time.Parse(time.RFC3339Nano, r.EventTime)
+			emit(events.Event{
+				Type:        typ,
+				Time:        t.UTC(),
+				Bucket:      r.S3.Bucket.Name,
+				Key:         key,
+				Size:        r.S3.Object.Size,
+				ETag:        r.S3.Object.ETag,
+				VersionID:   r.S3.Object.VersionID,
+				ContentType: r.S3.Object.ContentType,
+			})
+		}
+	}
+	if ctx.Err() == nil {
+		return errors.New("notification stream closed by the backend")
+	}
+	return nil
+}
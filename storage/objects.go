@@ -0,0 +1,117 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/minio/minio-go/v7"
+	"kluisz-object-storage/config"
+	"kluisz-object-storage/envelope"
+)
+
+// Read -- reads a whole object, decrypted when the gateway sealed it; the size in info
+// is the plaintext size
+func Read(ctx context.Context, bucket, key string, opts minio.GetObjectOptions) (io.ReadCloser, minio.ObjectInfo, error) {
+	client, err := ClientFor(bucket)
+	if err != nil {
+		return nil, minio.ObjectInfo{}, err
+	}
+	obj, err := client.GetObject(ctx, bucket, key, opts)
+	if err != nil {
+		return nil, minio.ObjectInfo{}, err
+	}
+	info, err := obj.Stat()
+	if err != nil || !envelope.IsSealed(info.UserMetadata) {
+		if err != nil {
+			obj.Close()
+		}
+		return obj, info, err
+	}
+	if envelope.Key == nil {
+		obj.Close()
+		return nil, info, errors.New("object is encrypted by the gateway but no master key is configured")
+	}
+	sealed, err := envelope.Key.Open(info.UserMetadata)
+	if err != nil {
+		obj.Close()
+		return nil, info, err
+	}
+	info.Size = sealed.Size
+	return struct {
+		io.Reader
+		io.Closer
+	}{sealed.NewReader(obj, 0, sealed.Size-1), obj}, info, nil
+}
+
+// Upload -- stores r as an object with Put, sealed when envelope encryption is enabled
+func Upload(ctx context.Context, bucket, key string, r io.Reader, size int64, opts minio.PutObjectOptions) error {
+	client, err := ClientFor(bucket)
+	if err != nil {
+		return err
+	}
+	if envelope.Enabled() {
+		r, size, opts.UserMetadata, err = envelope.Key.Seal(r, size, config.Get().Envelope.ChunkSize)
+		if err != nil {
+			return err
+		}
+	}
+	_, err = Put(ctx, client, bucket, key, r, size, opts)
+	return err
+}
+
+// largest object S3 copies in a single CopyObject, larger ones are copied in parts
+const maxCopySize = 5 << 30
+
+// Copy -- copies an object, or one version of it, between buckets; server side when
+// both are on one backend and streamed through the gateway otherwise. Metadata is
+// kept, so envelope encrypted objects stay readable.
+func Copy(ctx context.Context, srcBucket, srcKey, srcVersion, dstBucket, dstKey string) error {
+	dst, err := ClientFor(dstBucket)
+	if err != nil {
+		return err
+	}
+	cfg := config.Get()
+	if cfg.Route(srcBucket) == cfg.Route(dstBucket) {
+		info, err := dst.StatObject(ctx, srcBucket, srcKey, minio.StatObjectOptions{VersionID: srcVersion})
+		if err != nil {
+			return err
+		}
+		if info.Size <= maxCopySize {
+			_, err := dst.CopyObject(ctx, minio.CopyDestOptions{Bucket: dstBucket, Object: dstKey},
+				minio.CopySrcOptions{Bucket: srcBucket, Object: srcKey, VersionID: srcVersion})
+			return err
+		}
+		// a multipart copy starts a new upload, which does not take the source's
+		// metadata and tags along by itself
+		opts := minio.PutObjectOptions{ContentType: info.ContentType, UserMetadata: info.UserMetadata}
+		if info.UserTagCount > 0 {
+			t, err := dst.GetObjectTagging(ctx, srcBucket, srcKey, minio.GetObjectTaggingOptions{VersionID: srcVersion})
+			if err != nil {
+				return err
+			}
+			opts.UserTags = t.ToMap()
+		}
+		return copyParts(ctx, dst, info, srcBucket, srcVersion, dstBucket, dstKey, opts)
+	}
+
+	src, err := ClientFor(srcBucket)
+	if err != nil {
+		return err
+	}
+	obj, err := src.GetObject(ctx, srcBucket, srcKey, minio.GetObjectOptions{VersionID: srcVersion})
+	if err != nil {
+		return err
+	}
+	defer obj.Close()
+	info, err := obj.Stat()
+	if err != nil {
+		return err
+	}
+	opts := minio.PutObjectOptions{
+		ContentType:  info.ContentType,
+		UserMetadata: info.UserMetadata,
+	}
+	_, err = Put(ctx, dst, dstBucket, dstKey, obj, info.Size, opts)
+	return err
+}
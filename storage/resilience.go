@@ -0,0 +1,225 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"kluisz-object-storage/config"
+	"kluisz-object-storage/metrics"
+)
+
+// ErrCircuitOpen -- the backend failed repeatedly and calls are refused until the
+// cooldown has passed
+var ErrCircuitOpen = errors.New("storage backend unavailable, circuit breaker open")
+
+// Breaker states, also the value of the gateway_backend_circuit_state gauge
+const (
+	Closed = iota
+	HalfOpen
+	Open
+)
+
+var stateNames = []string{"closed", "half-open", "open"}
+
+// Breaker -- counts consecutive failures of one backend. Once open it rejects calls
+// for the cooldown, then lets a single probe through: success closes it again,
+// failure reopens it. The threshold and cooldown are read from the current
+// configuration, so a reload applies to existing breakers.
+type Breaker struct {
+	name string
+
+	mu       sync.Mutex
+	state    int
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+var (
+	breakersMu sync.Mutex
+	breakers   = make(map[string]*Breaker)
+)
+
+// BreakerFor -- the breaker shared by every client of the backend at endpoint
+func BreakerFor(endpoint string) *Breaker {
+	breakersMu.Lock()
+	defer breakersMu.Unlock()
+	b, ok := breakers[endpoint]
+	if !ok {
+		b = &Breaker{name: endpoint}
+		breakers[endpoint] = b
+		metrics.BreakerState.WithLabelValues(endpoint).Set(Closed)
+	}
+	return b
+}
+
+// State -- closed, half-open or open
+func (b *Breaker) State() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return stateNames[b.state]
+}
+
+// allow -- whether a call may go to the backend now
+func (b *Breaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case Open:
+		if time.Since(b.openedAt) < config.Get().Resilience.BreakerCooldown {
+			return false
+		}
+		b.set(HalfOpen)
+		b.probing = true
+		return true
+	case HalfOpen:
+		if b.probing {
+			return false
+		}
+		b.probing = true
+		return true
+	}
+	return true
+}
+
+// release -- ends a call without an answer from the backend; a probe that never got
+// one leaves the breaker half-open for the next call to probe again
+func (b *Breaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+func (b *Breaker) record(failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+	if !failed {
+		b.failures = 0
+		if b.state != Closed {
+			b.set(Closed)
+		}
+		return
+	}
+	b.failures++
+	if b.state == HalfOpen || b.failures >= config.Get().Resilience.BreakerThreshold {
+		b.openedAt = time.Now()
+		if b.state != Open {
+			b.set(Open)
+		}
+	}
+}
+
+// set -- b.mu must be held
+func (b *Breaker) set(state int) {
+	zap.L().Warn("Backend circuit breaker changed state",
+		zap.String("backend", b.name),
+		zap.String("from", stateNames[b.state]),
+		zap.String("to", stateNames[state]),
+		zap.Int("failures", b.failures))
+	b.state = state
+	metrics.BreakerState.WithLabelValues(b.name).Set(float64(state))
+}
+
+// resilient -- retries failed backend calls that are safe to repeat, with exponential
+// backoff and jitter, and fast-fails through the backend's circuit breaker
+type resilient struct {
+	next     http.RoundTripper
+	breaker  *Breaker
+	endpoint string
+}
+
+func (t *resilient) RoundTrip(req *http.Request) (*http.Response, error) {
+	cfg := config.Get().Resilience
+	op := operation(req, req.URL.Host == t.endpoint)
+	for attempt := 0; ; attempt++ {
+		if !t.breaker.allow() {
+			metrics.BreakerRejections.WithLabelValues(t.breaker.name).Inc()
+			return nil, ErrCircuitOpen
+		}
+
+		try := req
+		if attempt > 0 {
+			try = req.Clone(req.Context())
+			if req.GetBody != nil {
+				body, err := req.GetBody()
+				if err != nil {
+					return nil, err
+				}
+				try.Body = body
+			}
+		}
+		resp, err := t.next.RoundTrip(try)
+		if err != nil && req.Context().Err() != nil {
+			// the caller gave up, which says nothing about the backend
+			t.breaker.release()
+			return resp, err
+		}
+
+		failed := backendFailure(req.Context(), resp, err)
+		t.breaker.record(failed)
+		if !failed || attempt >= cfg.Retries || !replayable(req, resp, err) {
+			return resp, err
+		}
+
+		delay := retryDelay(attempt, cfg.RetryBackoff, cfg.RetryMaxBackoff)
+		metrics.BackendRetries.WithLabelValues(op).Inc()
+		zap.L().Debug("Retrying backend call", zap.String("operation", op), zap.Int("attempt", attempt+1), zap.Duration("delay", delay), zap.Error(err))
+		if resp != nil {
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+		}
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// backendFailure -- the backend is unhealthy: it could not be reached or answered
+// with a server error. Client errors and our own cancellations do not count.
+func backendFailure(ctx context.Context, resp *http.Response, err error) bool {
+	if err != nil {
+		return ctx.Err() == nil
+	}
+	switch resp.StatusCode {
+	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// replayable -- reads, lists and deletes are idempotent and always retried. Writes
+// are only retried when their body can be sent again and the backend never acted on
+// them: the connection could not be made, or it answered 503 Slow Down.
+func replayable(req *http.Request, resp *http.Response, err error) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodDelete:
+		return true
+	}
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+	if err != nil {
+		var opErr *net.OpError
+		return errors.As(err, &opErr) && opErr.Op == "dial"
+	}
+	return resp.StatusCode == http.StatusServiceUnavailable
+}
+
+// retryDelay -- full jitter over an exponentially growing window
+func retryDelay(attempt int, base, limit time.Duration) time.Duration {
+	d := base << attempt
+	if d <= 0 || d > limit {
+		d = limit
+	}
+	return time.Duration(rand.Int63n(int64(d)) + 1)
+}
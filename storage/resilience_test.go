@@ -0,0 +1,115 @@
+package storage
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"kluisz-object-storage/config"
+)
+
+// testBackend -- answers every request with status after delay, counting requests
+func testBackend(t *testing.T, status *atomic.Int32, delay time.Duration) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+			return
+		}
+		w.WriteHeader(int(status.Load()))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+func useResilience(cfg config.ResilienceConfig) {
+	config.Use(config.Config{Resilience: cfg})
+}
+
+func get(t *testing.T, rt http.RoundTripper, ctx context.Context, target string) (*http.Response, error) {
+	t.Helper()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target+"/bucket/key", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := rt.RoundTrip(req)
+	if err == nil {
+		resp.Body.Close()
+	}
+	return resp, err
+}
+
+func TestRetries(t *testing.T) {
+	tests := []struct {
+		name    string
+		retries int
+		want    int32
+	}{
+		{"off", 0, 1},
+		{"two", 2, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useResilience(config.ResilienceConfig{Retries: tt.retries, RetryBackoff: time.Millisecond,
+				RetryMaxBackoff: time.Millisecond, BreakerThreshold: 100, BreakerCooldown: time.Minute})
+			var status atomic.Int32
+			status.Store(http.StatusServiceUnavailable)
+			srv, calls := testBackend(t, &status, 0)
+			host := srv.Listener.Addr().String()
+			rt := &resilient{next: http.DefaultTransport, breaker: &Breaker{name: host}, endpoint: host}
+
+			resp, err := get(t, rt, context.Background(), srv.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != http.StatusServiceUnavailable {
+				t.Errorf("got status %d", resp.StatusCode)
+			}
+			if got := calls.Load(); got != tt.want {
+				t.Errorf("backend got %d requests, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBreakerProbe(t *testing.T) {
+	useResilience(config.ResilienceConfig{Retries: 0, BreakerThreshold: 1, BreakerCooldown: time.Millisecond})
+	var status atomic.Int32
+	status.Store(http.StatusInternalServerError)
+	srv, _ := testBackend(t, &status, 50*time.Millisecond)
+	u, _ := url.Parse(srv.URL)
+	b := &Breaker{name: u.Host}
+	rt := &resilient{next: http.DefaultTransport, breaker: b, endpoint: u.Host}
+
+	get(t, rt, context.Background(), srv.URL)
+	if got := b.State(); got != "open" {
+		t.Fatalf("after a failure the breaker is %s, want open", got)
+	}
+
+	// the probe is cancelled by its caller before the backend answers
+	time.Sleep(5 * time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := get(t, rt, ctx, srv.URL); err == nil {
+		t.Fatal("cancelled probe succeeded")
+	}
+	if got := b.State(); got != "half-open" {
+		t.Fatalf("after a cancelled probe the breaker is %s, want half-open", got)
+	}
+
+	// the next call probes again and a real answer decides
+	status.Store(http.StatusOK)
+	if _, err := get(t, rt, context.Background(), srv.URL); err != nil {
+		t.Fatal(err)
+	}
+	if got := b.State(); got != "closed" {
+		t.Errorf("after a successful probe the breaker is %s, want closed", got)
+	}
+}
@@ -0,0 +1,145 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/s3utils"
+)
+
+// uploads -- object uploads in flight, waited for at shutdown
+var uploads sync.WaitGroup
+
+// minio-go's part size when PutObjectOptions.PartSize is not set, objects up to it
+// are sent in a single PUT
+const defaultPartSize = 16 << 20
+
+// Put -- stores r like client.PutObject. Objects that need a multipart upload are sent
+// part by part here so the gateway knows its upload ID: when the upload fails, also
+// because ctx ended as the client went away, the upload timed out or the server shut
+// down, exactly that upload is aborted with a fresh context. minio-go would abort with
+// the cancelled ctx and leave the parts on the backend.
+func Put(ctx context.Context, client *minio.Client, bucket, key string, r io.Reader, size int64, opts minio.PutObjectOptions) (minio.UploadInfo, error) {
+	uploads.Add(1)
+	defer uploads.Done()
+	partSize := int64(opts.PartSize)
+	if partSize == 0 {
+		partSize = defaultPartSize
+	}
+	if opts.DisableMultipart || (size >= 0 && size <= partSize) {
+		return client.PutObject(ctx, bucket, key, r, size, opts)
+	}
+	_, partSize, _, err := minio.OptimalPartInfo(size, opts.PartSize)
+	if err != nil {
+		return minio.UploadInfo{}, err
+	}
+
+	core := minio.Core{Client: client}
+	id, err := core.NewMultipartUpload(ctx, bucket, key, opts)
+	if err != nil {
+		return minio.UploadInfo{}, err
+	}
+	buf := make([]byte, partSize)
+	var parts []minio.CompletePart
+	var total int64
+	for n := 1; ; n++ {
+		read, err := io.ReadFull(r, buf)
+		if err == io.EOF && n > 1 {
+			break
+		}
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return minio.UploadInfo{}, abort(core, bucket, key, id, err)
+		}
+		part, err := core.PutObjectPart(ctx, bucket, key, id, n, bytes.NewReader(buf[:read]), int64(read),
+			minio.PutObjectPartOptions{SSE: opts.ServerSideEncryption, DisableContentSha256: opts.DisableContentSha256})
+		if err != nil {
+			return minio.UploadInfo{}, abort(core, bucket, key, id, err)
+		}
+		parts = append(parts, minio.CompletePart{PartNumber: n, ETag: part.ETag})
+		total += int64(read)
+		if read < len(buf) {
+			break
+		}
+	}
+	if size >= 0 && total != size {
+		return minio.UploadInfo{}, abort(core, bucket, key, id, io.ErrUnexpectedEOF)
+	}
+	info, err := core.CompleteMultipartUpload(ctx, bucket, key, id, parts,
+		minio.PutObjectOptions{ServerSideEncryption: opts.ServerSideEncryption})
+	if err != nil {
+		return minio.UploadInfo{}, abort(core, bucket, key, id, err)
+	}
+	info.Size = total
+	return info, nil
+}
+
+// copyParts -- server side copy of an object too large for a single CopyObject, in
+// parts of a multipart upload that is aborted when the copy fails; opts carries the
+// destination's metadata
+func copyParts(ctx context.Context, client *minio.Client, src minio.ObjectInfo, srcBucket, srcVersion, dstBucket, dstKey string, opts minio.PutObjectOptions) error {
+	uploads.Add(1)
+	defer uploads.Done()
+	_, partSize, _, err := minio.OptimalPartInfo(src.Size, 0)
+	if err != nil {
+		return err
+	}
+	source := s3utils.EncodePath(srcBucket + "/" + src.Key)
+	if srcVersion != "" {
+		source += "?versionId=" + srcVersion
+	}
+	headers := map[string]string{
+		"x-amz-copy-source": source,
+		// fails the copy when the source changes half way
+		"x-amz-copy-source-if-match": src.ETag,
+	}
+
+	core := minio.Core{Client: client}
+	id, err := core.NewMultipartUpload(ctx, dstBucket, dstKey, opts)
+	if err != nil {
+		return err
+	}
+	var parts []minio.CompletePart
+	for n, start := 1, int64(0); start < src.Size; n, start = n+1, start+partSize {
+		length := min(partSize, src.Size-start)
+		part, err := core.CopyObjectPart(ctx, srcBucket, src.Key, dstBucket, dstKey, id, n, start, length, headers)
+		if err != nil {
+			return abort(core, dstBucket, dstKey, id, err)
+		}
+		parts = append(parts, part)
+	}
+	if _, err := core.CompleteMultipartUpload(ctx, dstBucket, dstKey, id, parts, minio.PutObjectOptions{}); err != nil {
+		return abort(core, dstBucket, dstKey, id, err)
+	}
+	return nil
+}
+
+// abort -- aborts the multipart upload id after it failed with err, with a fresh
+// context since the upload's own may be cancelled
+func abort(core minio.Core, bucket, key, id string, err error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if abortErr := core.AbortMultipartUpload(ctx, bucket, key, id); abortErr != nil {
+		return errors.Join(err, abortErr)
+	}
+	return err
+}
+
+// WaitUploads -- waits for in-flight uploads and their aborts, or until ctx is done
+func WaitUploads(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		uploads.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,120 @@
+package syncer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"kluisz-object-storage/config"
+	"kluisz-object-storage/envelope"
+	"kluisz-object-storage/models"
+	"kluisz-object-storage/secrets"
+)
+
+const usage = `usage: kluisz-object-storage sync [flags] <source> <destination>
+
+The source is a local directory or s3://bucket/prefix, the destination s3://bucket/prefix.
+Backends, credentials and envelope encryption come from the gateway's configuration.
+
+`
+
+// Command -- the sync subcommand, returns the exit status: 0 when everything was
+// synced, 1 when some objects failed, 2 for usage and configuration errors
+func Command(args []string) int {
+	fs := flag.NewFlagSet("sync", flag.ContinueOnError)
+	configFile := fs.String("config", "", "config file, default "+config.DefaultFile+" or $"+config.EnvPrefix+"CONFIG")
+	del := fs.Bool("delete", false, "delete destination keys that are not in the source")
+	dryRun := fs.Bool("dry-run", false, "only print what would be done")
+	checksum := fs.Bool("checksum", false, "compare MD5 checksums with ETags instead of modification times")
+	workers := fs.Int("workers", 0, "parallel transfers, default sync.workers")
+	asJSON := fs.Bool("json", false, "print the report as JSON")
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), usage)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return 2
+	}
+
+	var loadArgs []string
+	if *configFile != "" {
+		loadArgs = []string{"--config", *configFile}
+	}
+	cfg, _, err := config.Load(loadArgs)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		return 2
+	}
+	config.Use(cfg)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := secrets.Start(ctx); err != nil {
+		fmt.Fprintf(os.Stderr, "Error resolving secrets: %v\n", err)
+		return 2
+	}
+	if err := envelope.Load(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading envelope master key: %v\n", err)
+		return 2
+	}
+
+	opts := Options{Delete: *del, DryRun: *dryRun, Checksum: *checksum, Workers: *workers}
+	if opts.Source, err = ParseLocation(fs.Arg(0)); err == nil {
+		opts.Destination, err = ParseLocation(fs.Arg(1))
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	if !*asJSON {
+		opts.OnAction = func(a models.SyncAction) {
+			prefix := ""
+			if *dryRun {
+				prefix = "(dry run) "
+			}
+			fmt.Printf("%s%s %s (%s)\n", prefix, a.Action, a.Key, a.Reason)
+		}
+	}
+	s, err := New(opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+
+	report, err := s.Run(ctx)
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(report)
+	} else {
+		for _, e := range report.Errors {
+			fmt.Fprintf(os.Stderr, "failed: %s %s: %s\n", e.Action, e.Key, e.Error)
+		}
+		if report.DryRun {
+			fmt.Print("dry run: ")
+		}
+		fmt.Printf("scanned %d, transferred %d (%d bytes), deleted %d, unchanged %d, failed %d in %s\n",
+			report.Scanned, report.Transferred, report.Bytes, report.Deleted, report.Skipped, report.Failed,
+			report.Finished.Sub(report.Started).Round(time.Millisecond))
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if report.Failed > 0 {
+		return 1
+	}
+	return 0
+}
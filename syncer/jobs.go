@@ -0,0 +1,107 @@
+package syncer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"kluisz-object-storage/config"
+	"kluisz-object-storage/jobs"
+	"kluisz-object-storage/models"
+)
+
+// ErrNotAllowed -- a job's source directory is not under sync.allowedDirs
+var ErrNotAllowed = errors.New("not under a directory in sync.allowedDirs")
+
+// RegisterJobs -- makes syncs available as "sync" jobs, with a models.SyncRequest as
+// parameters and the report as result
+func RegisterJobs() {
+	jobs.Register("sync", jobs.Kind{
+		Check: func(params json.RawMessage) error {
+			var req models.SyncRequest
+			if err := json.Unmarshal(params, &req); err != nil {
+				return err
+			}
+			if max := config.Get().Jobs.MaxConcurrency; req.Workers > max {
+				return fmt.Errorf("workers can be at most %d, jobs.maxConcurrency", max)
+			}
+			_, err := jobOptions(params)
+			return err
+		},
+		Run: func(ctx context.Context, job models.Job, p *jobs.Progress) (any, error) {
+			opts, err := jobOptions(job.Params)
+			if err != nil {
+				return nil, err
+			}
+			opts.OnAction = func(models.SyncAction) { p.AddTotal(1) }
+			opts.OnFinish = func(a models.SyncAction, err error) {
+				if err != nil {
+					p.Fail(a.Key, err)
+					return
+				}
+				p.Done(a.Size)
+			}
+			s, err := New(opts)
+			if err != nil {
+				return nil, err
+			}
+			report, err := s.Run(ctx)
+			return report, err
+		},
+	})
+}
+
+func jobOptions(params json.RawMessage) (Options, error) {
+	var req models.SyncRequest
+	if err := json.Unmarshal(params, &req); err != nil {
+		return Options{}, err
+	}
+	cfg := config.Get()
+	workers := req.Workers
+	if workers <= 0 {
+		workers = cfg.Sync.Workers
+	}
+	// sync.workers may be above the limit, which may also have been lowered since the
+	// job was submitted
+	workers = min(workers, cfg.Jobs.MaxConcurrency)
+	opts := Options{Delete: req.Delete, DryRun: req.DryRun, Checksum: req.Checksum, Workers: workers}
+	var err error
+	if opts.Source, err = ParseLocation(req.Source); err != nil {
+		return opts, err
+	}
+	if opts.Destination, err = ParseLocation(req.Destination); err != nil {
+		return opts, err
+	}
+	if opts.Destination.Bucket == "" {
+		return opts, errors.New("the destination must be a bucket, s3://bucket/prefix")
+	}
+	if err := checkOverlap(opts); err != nil {
+		return opts, err
+	}
+	if opts.Source.Dir != "" && !Allowed(opts.Source.Dir) {
+		return opts, fmt.Errorf("%s: %w", opts.Source.Dir, ErrNotAllowed)
+	}
+	return opts, nil
+}
+
+// Allowed -- dir, with symlinks resolved, is inside one of sync.allowedDirs
+func Allowed(dir string) bool {
+	real, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return false
+	}
+	for _, allowed := range config.Get().Sync.AllowedDirs {
+		base, err := filepath.EvalSymlinks(allowed)
+		if err != nil {
+			continue
+		}
+		rel, err := filepath.Rel(base, real)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,39 @@
+package syncer
+
+import (
+	"encoding/json"
+	"testing"
+
+	"kluisz-object-storage/config"
+)
+
+func TestJobOptionsWorkers(t *testing.T) {
+	tests := []struct {
+		name           string
+		requested      int
+		syncWorkers    int
+		maxConcurrency int
+		want           int
+	}{
+		{"requested", 4, 8, 64, 4},
+		{"requested above the limit", 100, 8, 64, 64},
+		{"omitted", 0, 8, 64, 8},
+		{"omitted, sync.workers above the limit", 0, 128, 64, 64},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config.Use(config.Config{
+				Sync: config.SyncConfig{Workers: tt.syncWorkers},
+				Jobs: config.JobsConfig{MaxConcurrency: tt.maxConcurrency},
+			})
+			params, _ := json.Marshal(map[string]any{"source": "s3://photos", "destination": "s3://backup", "workers": tt.requested})
+			opts, err := jobOptions(params)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if opts.Workers != tt.want {
+				t.Errorf("got %d workers, want %d", opts.Workers, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,410 @@
+// Package syncer makes a bucket prefix match a local directory or another bucket, like
+// rsync: only new and changed files are transferred, extraneous keys are optionally
+// deleted, and a dry run reports what would be done.
+package syncer
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"kluisz-object-storage/config"
+	"kluisz-object-storage/envelope"
+	"kluisz-object-storage/events"
+	"kluisz-object-storage/models"
+	"kluisz-object-storage/storage"
+)
+
+// Location -- a local directory, or a prefix in a bucket
+type Location struct {
+	Dir    string
+	Bucket string
+	Prefix string
+}
+
+// ParseLocation -- "s3://bucket/prefix" or a directory path
+func ParseLocation(s string) (Location, error) {
+	if rest, ok := strings.CutPrefix(s, "s3://"); ok {
+		bucket, prefix, _ := strings.Cut(rest, "/")
+		if bucket == "" {
+			return Location{}, fmt.Errorf("%q: missing bucket name", s)
+		}
+		return Location{Bucket: bucket, Prefix: prefix}, nil
+	}
+	if s == "" {
+		return Location{}, errors.New("empty location")
+	}
+	return Location{Dir: filepath.Clean(s)}, nil
+}
+
+func (l Location) String() string {
+	if l.Dir != "" {
+		return l.Dir
+	}
+	return "s3://" + l.Bucket + "/" + l.Prefix
+}
+
+type Options struct {
+	Source      Location
+	Destination Location
+	// remove destination keys that have no source counterpart
+	Delete bool
+	DryRun bool
+	// compare MD5 checksums with ETags instead of modification times
+	Checksum bool
+	Workers  int
+	// called for every action as it is decided, for live output
+	OnAction func(models.SyncAction)
+	// called for every action once it is done or failed
+	OnFinish func(models.SyncAction, error)
+}
+
+// most actions listed in a report, the counters cover all of them
+const maxActions = 1000
+
+// Sync -- one run, started by New; Report may be called while it is in progress
+type Sync struct {
+	opts   Options
+	mu     sync.Mutex
+	report models.SyncReport
+}
+
+func New(opts Options) (*Sync, error) {
+	if opts.Destination.Bucket == "" {
+		return nil, errors.New("the destination must be a bucket, s3://bucket/prefix")
+	}
+	if err := checkOverlap(opts); err != nil {
+		return nil, err
+	}
+	if opts.Workers <= 0 {
+		opts.Workers = config.Get().Sync.Workers
+	}
+	return &Sync{opts: opts, report: models.SyncReport{
+		Source:      opts.Source.String(),
+		Destination: opts.Destination.String(),
+		DryRun:      opts.DryRun,
+		Started:     time.Now().UTC(),
+		Actions:     []models.SyncAction{},
+		Errors:      []models.SyncError{},
+	}}, nil
+}
+
+// checkOverlap -- rejects a destination inside the source prefix of the same bucket,
+// the copies would be listed and copied again, and with Delete a source inside the
+// destination, its objects have no counterpart under the source and would be removed
+func checkOverlap(opts Options) error {
+	src, dst := opts.Source, opts.Destination
+	if src.Bucket == "" || src.Bucket != dst.Bucket {
+		return nil
+	}
+	if strings.HasPrefix(dst.Prefix, src.Prefix) {
+		return errors.New("the destination is inside the source prefix")
+	}
+	if opts.Delete && strings.HasPrefix(src.Prefix, dst.Prefix) {
+		return errors.New("the source is inside the destination prefix, deleting extraneous keys would remove it")
+	}
+	return nil
+}
+
+// Report -- a copy of the progress so far
+func (s *Sync) Report() models.SyncReport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r := s.report
+	r.Actions = append(make([]models.SyncAction, 0, len(r.Actions)), r.Actions...)
+	r.Errors = append(make([]models.SyncError, 0, len(r.Errors)), r.Errors...)
+	return r
+}
+
+// entry -- a source file or object, key relative to the source root
+type entry struct {
+	rel     string
+	size    int64
+	modTime time.Time
+	etag    string
+}
+
+// Run -- compares source and destination and transfers the differences. Nothing is
+// deleted when the source could not be listed completely.
+func (s *Sync) Run(ctx context.Context) (models.SyncReport, error) {
+	err := s.run(ctx)
+	s.mu.Lock()
+	finished := time.Now().UTC()
+	s.report.Finished = &finished
+	s.mu.Unlock()
+	return s.Report(), err
+}
+
+func (s *Sync) run(ctx context.Context) error {
+	dst := s.opts.Destination
+	dstClient, err := storage.ClientFor(dst.Bucket)
+	if err != nil {
+		return err
+	}
+	existing := make(map[string]minio.ObjectInfo)
+	for obj := range dstClient.ListObjects(ctx, dst.Bucket, minio.ListObjectsOptions{Prefix: dst.Prefix, Recursive: true}) {
+		if obj.Err != nil {
+			return fmt.Errorf("listing destination: %w", obj.Err)
+		}
+		existing[strings.TrimPrefix(obj.Key, dst.Prefix)] = obj
+	}
+
+	actions := make(chan models.SyncAction)
+	var wg sync.WaitGroup
+	for i := 0; i < s.opts.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for a := range actions {
+				err := s.apply(ctx, dstClient, a)
+				if err == nil {
+					s.publish(a)
+				}
+				s.finish(a, err)
+			}
+		}()
+	}
+
+	seen := make(map[string]bool)
+	err = s.walk(ctx, func(e entry) error {
+		seen[e.rel] = true
+		a, ok := s.compare(ctx, dstClient, e, existing)
+		s.count(func(r *models.SyncReport) { r.Scanned++ })
+		if !ok {
+			s.count(func(r *models.SyncReport) { r.Skipped++ })
+			return nil
+		}
+		return s.dispatch(ctx, actions, a)
+	})
+	if err == nil && s.opts.Delete {
+		for rel, obj := range existing {
+			if seen[rel] {
+				continue
+			}
+			a := models.SyncAction{Action: models.SyncDelete, Key: dst.Prefix + rel, Size: obj.Size, Reason: "extraneous"}
+			if err = s.dispatch(ctx, actions, a); err != nil {
+				break
+			}
+		}
+	}
+	close(actions)
+	wg.Wait()
+	return err
+}
+
+// dispatch -- records a decided action and, unless dry running, hands it to a worker
+func (s *Sync) dispatch(ctx context.Context, actions chan<- models.SyncAction, a models.SyncAction) error {
+	if s.opts.OnAction != nil {
+		s.opts.OnAction(a)
+	}
+	if s.opts.DryRun {
+		s.finish(a, nil)
+		return nil
+	}
+	select {
+	case actions <- a:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *Sync) walk(ctx context.Context, fn func(entry) error) error {
+	src := s.opts.Source
+	if src.Dir != "" {
+		return filepath.WalkDir(src.Dir, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			// symlinks and other special files are not synced
+			if !d.Type().IsRegular() {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			rel, err := filepath.Rel(src.Dir, p)
+			if err != nil {
+				return err
+			}
+			return fn(entry{rel: filepath.ToSlash(rel), size: info.Size(), modTime: info.ModTime()})
+		})
+	}
+
+	client, err := storage.ClientFor(src.Bucket)
+	if err != nil {
+		return err
+	}
+	for obj := range client.ListObjects(ctx, src.Bucket, minio.ListObjectsOptions{Prefix: src.Prefix, Recursive: true}) {
+		if obj.Err != nil {
+			return fmt.Errorf("listing source: %w", obj.Err)
+		}
+		e := entry{rel: strings.TrimPrefix(obj.Key, src.Prefix), size: obj.Size, modTime: obj.LastModified, etag: obj.ETag}
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// compare -- the action bringing the destination up to date with e, if any
+func (s *Sync) compare(ctx context.Context, client *minio.Client, e entry, existing map[string]minio.ObjectInfo) (models.SyncAction, bool) {
+	a := models.SyncAction{Action: models.SyncCopy, Key: s.opts.Destination.Prefix + e.rel, Size: e.size}
+	if s.opts.Source.Dir != "" {
+		a.Action = models.SyncUpload
+	}
+	obj, ok := existing[e.rel]
+	if !ok {
+		a.Reason = "missing"
+		return a, true
+	}
+
+	size := obj.Size
+	sealed := false
+	if size != e.size && a.Action == models.SyncUpload && envelope.Enabled() {
+		// uploads are encrypted, the listed size is that of the ciphertext
+		if info, err := client.StatObject(ctx, s.opts.Destination.Bucket, a.Key, minio.StatObjectOptions{}); err == nil {
+			size, sealed = envelope.PlaintextSize(info.UserMetadata)
+		}
+		if !sealed {
+			size = obj.Size
+		}
+	}
+	if size != e.size {
+		a.Reason = "size"
+		return a, true
+	}
+
+	if s.opts.Checksum && !sealed {
+		etag := e.etag
+		if a.Action == models.SyncUpload {
+			sum, err := md5File(filepath.Join(s.opts.Source.Dir, filepath.FromSlash(e.rel)))
+			if err != nil {
+				a.Reason = "checksum"
+				return a, true
+			}
+			etag = sum
+		}
+		// multipart ETags are not content checksums, those fall back to the time
+		if !strings.Contains(etag, "-") && !strings.Contains(obj.ETag, "-") {
+			if etag != obj.ETag {
+				a.Reason = "checksum"
+				return a, true
+			}
+			return a, false
+		}
+	}
+	if e.modTime.After(obj.LastModified) {
+		a.Reason = "newer"
+		return a, true
+	}
+	return a, false
+}
+
+func (s *Sync) apply(ctx context.Context, dstClient *minio.Client, a models.SyncAction) error {
+	dst := s.opts.Destination
+	switch a.Action {
+	case models.SyncDelete:
+		ctx, cancel := context.WithTimeout(ctx, config.Get().Timeouts.Other)
+		defer cancel()
+		return dstClient.RemoveObject(ctx, dst.Bucket, a.Key, minio.RemoveObjectOptions{})
+	case models.SyncUpload:
+		return s.upload(ctx, a)
+	default:
+		return s.copy(ctx, a)
+	}
+}
+
+func (s *Sync) upload(ctx context.Context, a models.SyncAction) error {
+	rel := strings.TrimPrefix(a.Key, s.opts.Destination.Prefix)
+	f, err := os.Open(filepath.Join(s.opts.Source.Dir, filepath.FromSlash(rel)))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, config.Get().Timeouts.Put)
+	defer cancel()
+	return storage.Upload(ctx, s.opts.Destination.Bucket, a.Key, f, info.Size(),
+		minio.PutObjectOptions{ContentType: mime.TypeByExtension(path.Ext(rel))})
+}
+
+func (s *Sync) copy(ctx context.Context, a models.SyncAction) error {
+	src, dst := s.opts.Source, s.opts.Destination
+	ctx, cancel := context.WithTimeout(ctx, config.Get().Timeouts.Put)
+	defer cancel()
+	return storage.Copy(ctx, src.Bucket, src.Prefix+strings.TrimPrefix(a.Key, dst.Prefix), "", dst.Bucket, a.Key)
+}
+
+// publish -- lets webhooks, sinks and replication see the change like any other
+func (s *Sync) publish(a models.SyncAction) {
+	e := events.Event{Type: events.ObjectCreated, Bucket: s.opts.Destination.Bucket, Key: a.Key, Size: a.Size, Source: events.SourceSync}
+	if a.Action == models.SyncDelete {
+		e.Type, e.Size = events.ObjectRemoved, 0
+	}
+	events.Publish(e)
+}
+
+// finish -- counts a done (or, dry running, planned) action
+func (s *Sync) finish(a models.SyncAction, err error) {
+	if s.opts.OnFinish != nil {
+		s.opts.OnFinish(a, err)
+	}
+	s.count(func(r *models.SyncReport) {
+		if err != nil {
+			r.Failed++
+			if len(r.Errors) < maxActions {
+				r.Errors = append(r.Errors, models.SyncError{Key: a.Key, Action: a.Action, Error: err.Error()})
+			}
+			return
+		}
+		switch a.Action {
+		case models.SyncDelete:
+			r.Deleted++
+		default:
+			r.Transferred++
+			r.Bytes += a.Size
+		}
+		if len(r.Actions) < maxActions {
+			r.Actions = append(r.Actions, a)
+		}
+	})
+}
+
+func (s *Sync) count(fn func(*models.SyncReport)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fn(&s.report)
+}
+
+func md5File(name string) (string, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := md5.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,131 @@
+package syncer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"kluisz-object-storage/config"
+	"kluisz-object-storage/models"
+)
+
+func TestParseLocation(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Location
+		wantErr bool
+	}{
+		{"s3://photos", Location{Bucket: "photos"}, false},
+		{"s3://photos/2024/", Location{Bucket: "photos", Prefix: "2024/"}, false},
+		{"s3:///prefix", Location{}, true},
+		{"/srv/exports/", Location{Dir: "/srv/exports"}, false},
+		{"", Location{}, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseLocation(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseLocation(%q) = %+v, %v; want %+v, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestCheckOverlap(t *testing.T) {
+	loc := func(s string) Location {
+		l, err := ParseLocation(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return l
+	}
+	tests := []struct {
+		name    string
+		src     string
+		dst     string
+		delete  bool
+		wantErr bool
+	}{
+		{"other bucket", "s3://a/data/", "s3://b/data/", true, false},
+		{"local source", "/srv/data", "s3://a/data/", true, false},
+		{"sibling prefixes", "s3://a/data/", "s3://a/backup/", true, false},
+		{"same prefix", "s3://a/data/", "s3://a/data/", false, true},
+		{"destination inside source", "s3://a/data/", "s3://a/data/backup/", false, true},
+		{"whole bucket source", "s3://a", "s3://a/backup/", false, true},
+		{"source inside destination", "s3://a/data/in/", "s3://a/data/", false, false},
+		{"source inside destination with delete", "s3://a/data/in/", "s3://a/data/", true, true},
+		{"whole bucket destination with delete", "s3://a/data/", "s3://a", true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkOverlap(Options{Source: loc(tt.src), Destination: loc(tt.dst), Delete: tt.delete})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("got %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCompare(t *testing.T) {
+	config.Use(config.Config{})
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("hello"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	const helloMD5 = "5d41402abc4b2a76b9719d911017c592"
+	old := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	later := old.Add(time.Hour)
+
+	tests := []struct {
+		name     string
+		local    bool
+		checksum bool
+		e        entry
+		obj      *minio.ObjectInfo
+		want     string
+		wantDo   bool
+	}{
+		{"missing", false, false, entry{rel: "a.txt", size: 5}, nil, "missing", true},
+		{"size differs", false, false, entry{rel: "a.txt", size: 6, modTime: old},
+			&minio.ObjectInfo{Size: 5, LastModified: later}, "size", true},
+		{"source newer", false, false, entry{rel: "a.txt", size: 5, modTime: later},
+			&minio.ObjectInfo{Size: 5, LastModified: old}, "newer", true},
+		{"up to date", false, false, entry{rel: "a.txt", size: 5, modTime: old},
+			&minio.ObjectInfo{Size: 5, LastModified: later}, "", false},
+		{"etags differ", false, true, entry{rel: "a.txt", size: 5, modTime: old, etag: "aaa"},
+			&minio.ObjectInfo{Size: 5, LastModified: later, ETag: "bbb"}, "checksum", true},
+		{"etags equal, source newer", false, true, entry{rel: "a.txt", size: 5, modTime: later, etag: "aaa"},
+			&minio.ObjectInfo{Size: 5, LastModified: old, ETag: "aaa"}, "", false},
+		{"multipart etag falls back to the time", false, true, entry{rel: "a.txt", size: 5, modTime: later, etag: "aaa-2"},
+			&minio.ObjectInfo{Size: 5, LastModified: old, ETag: "aaa"}, "newer", true},
+		{"local file checksum equal", true, true, entry{rel: "a.txt", size: 5, modTime: later},
+			&minio.ObjectInfo{Size: 5, LastModified: old, ETag: helloMD5}, "", false},
+		{"local file checksum differs", true, true, entry{rel: "a.txt", size: 5, modTime: old},
+			&minio.ObjectInfo{Size: 5, LastModified: later, ETag: "0123"}, "checksum", true},
+		{"local file gone", true, true, entry{rel: "gone.txt", size: 5, modTime: old},
+			&minio.ObjectInfo{Size: 5, LastModified: later, ETag: helloMD5}, "checksum", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := Options{Destination: Location{Bucket: "backup", Prefix: "copy/"}, Checksum: tt.checksum}
+			wantAction := models.SyncCopy
+			if tt.local {
+				opts.Source, wantAction = Location{Dir: dir}, models.SyncUpload
+			} else {
+				opts.Source = Location{Bucket: "photos"}
+			}
+			existing := map[string]minio.ObjectInfo{}
+			if tt.obj != nil {
+				existing[tt.e.rel] = *tt.obj
+			}
+			a, do := (&Sync{opts: opts}).compare(context.Background(), nil, tt.e, existing)
+			if do != tt.wantDo || a.Reason != tt.want {
+				t.Fatalf("got %v, reason %q; want %v, reason %q", do, a.Reason, tt.wantDo, tt.want)
+			}
+			if do && (a.Action != wantAction || a.Key != "copy/"+tt.e.rel || a.Size != tt.e.size) {
+				t.Errorf("action %+v", a)
+			}
+		})
+	}
+}